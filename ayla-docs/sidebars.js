@@ -30,6 +30,7 @@ const sidebars = {
         "language/variables",
         "language/booleans",
         "language/strings",
+        "language/input",
         "language/lifetimes",
         {
           type: "category",
@@ -64,7 +65,9 @@ const sidebars = {
           items: [
             "language/data-structures/arrays",
             "language/data-structures/slices",
+            "language/data-structures/maps",
             "language/data-structures/enums",
+            "language/data-structures/variants",
             "language/data-structures/structs",
           ],
         },
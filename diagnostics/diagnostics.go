@@ -0,0 +1,234 @@
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/z-sk1/ayla-lang/interpreter"
+	"github.com/z-sk1/ayla-lang/lexer"
+	"github.com/z-sk1/ayla-lang/parser"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+	Hint
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Info:
+		return "info"
+	case Hint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// codes say which part of ayla found the problem
+const (
+	CodeSyntax  = "syntax"
+	CodeRuntime = "runtime"
+)
+
+// Position is 1 based, a line of 0 means the position is unknown
+type Position struct {
+	Line   int
+	Column int
+}
+
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// Related points at another place that helps explain a diagnostic
+type Related struct {
+	Message string
+	File    string
+	Range   Range
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	File     string
+	Range    Range
+	Related  []Related
+}
+
+func (d Diagnostic) HasPosition() bool {
+	return d.Range.Start.Line > 0
+}
+
+// Error renders the diagnostic the same way ayla always has, like "syntax error at 1:5: msg"
+func (d Diagnostic) Error() string {
+	kind := d.Severity.String()
+	if d.Code != "" && d.Severity == Error {
+		kind = d.Code + " error"
+	}
+
+	if !d.HasPosition() {
+		return fmt.Sprintf("%s: %s", kind, d.Message)
+	}
+
+	return fmt.Sprintf("%s at %d:%d: %s", kind, d.Range.Start.Line, d.Range.Start.Column, d.Message)
+}
+
+func at(line, col int) Range {
+	return span(line, col, 0, 0)
+}
+
+// span is a range from one position to another, an end that is missing or before the start collapses onto it
+func span(line, col, endLine, endCol int) Range {
+	if line < 0 {
+		line, col = 0, 0
+	}
+
+	start := Position{Line: line, Column: col}
+	end := Position{Line: endLine, Column: endCol}
+	if endLine < line || (endLine == line && endCol < col) {
+		end = start
+	}
+
+	return Range{Start: start, End: end}
+}
+
+// FromError turns an error from the lexer, parser or interpreter into a diagnostic,
+// anything else keeps its message without a position
+func FromError(file string, err error) Diagnostic {
+	// the lexer and parser keep pointers to their errors, the interpreter keeps values
+	var (
+		lexErr   *lexer.Error
+		parseErr *parser.ParseError
+		runErr   interpreter.RuntimeError
+		runPtr   *interpreter.RuntimeError
+		warning  interpreter.Warning
+		limit    interpreter.LimitError
+	)
+
+	switch {
+	case errors.As(err, &lexErr):
+		return Diagnostic{
+			Severity: Error,
+			Code:     CodeSyntax,
+			Message:  lexErr.Message,
+			File:     file,
+			Range:    at(lexErr.Line, lexErr.Column),
+		}
+
+	case errors.As(err, &parseErr):
+		return fromParseError(file, *parseErr)
+
+	// a program stopped from outside did nothing wrong, so it is only information
+	case errors.As(err, &limit):
+		d := fromRuntimeError(file, limit.RuntimeError)
+		d.Severity = Info
+		return d
+
+	case errors.As(err, &runErr):
+		return fromRuntimeError(file, runErr)
+
+	case errors.As(err, &runPtr):
+		return fromRuntimeError(file, *runPtr)
+
+	case errors.As(err, &warning):
+		severity := Warning
+		if warning.Hint {
+			severity = Hint
+		}
+
+		return Diagnostic{
+			Severity: severity,
+			Code:     warning.Code,
+			Message:  warning.Message,
+			File:     file,
+			Range:    span(warning.Line, warning.Column, warning.EndLine, warning.EndColumn),
+		}
+	}
+
+	return Diagnostic{
+		Severity: Error,
+		Message:  strings.TrimSpace(err.Error()),
+		File:     file,
+	}
+}
+
+func fromParseError(file string, e parser.ParseError) Diagnostic {
+	return Diagnostic{
+		Severity: Error,
+		Code:     CodeSyntax,
+		Message:  fmt.Sprintf("%s (got %s)", e.Message, e.Found()),
+		File:     file,
+		Range:    at(e.Line, e.Column),
+	}
+}
+
+func fromRuntimeError(file string, e interpreter.RuntimeError) Diagnostic {
+	return Diagnostic{
+		Severity: Error,
+		Code:     CodeRuntime,
+		Message:  e.Message,
+		File:     file,
+		Range:    span(e.Line, e.Column, e.EndLine, e.EndColumn),
+	}
+}
+
+// FromErrors converts every error, keeping their order
+func FromErrors(file string, errs []error) []Diagnostic {
+	diags := make([]Diagnostic, 0, len(errs))
+	for _, err := range errs {
+		diags = append(diags, FromError(file, err))
+	}
+
+	return diags
+}
+
+// Clean puts diagnostics in source order and drops exact repeats, the same message with the same
+// code at the same place. ones without a position keep their order and go last
+func Clean(diags []Diagnostic) []Diagnostic {
+	sorted := make([]Diagnostic, len(diags))
+	copy(sorted, diags)
+
+	sort.SliceStable(sorted, func(a, b int) bool {
+		pa, pb := sorted[a].Range.Start, sorted[b].Range.Start
+		if sorted[a].HasPosition() != sorted[b].HasPosition() {
+			return sorted[a].HasPosition()
+		}
+
+		if pa.Line != pb.Line {
+			return pa.Line < pb.Line
+		}
+		return pa.Column < pb.Column
+	})
+
+	type key struct {
+		start   Position
+		code    string
+		message string
+	}
+
+	seen := map[key]bool{}
+	out := sorted[:0]
+
+	for _, d := range sorted {
+		k := key{d.Range.Start, d.Code, d.Message}
+		if seen[k] {
+			continue
+		}
+
+		seen[k] = true
+		out = append(out, d)
+	}
+
+	return out
+}
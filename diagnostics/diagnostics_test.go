@@ -0,0 +1,147 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/interpreter"
+	"github.com/z-sk1/ayla-lang/lexer"
+	"github.com/z-sk1/ayla-lang/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkSource runs src through the same steps as ayla check and gives back what it reports
+func checkSource(name, src string) []Diagnostic {
+	p := parser.New(lexer.New(src))
+	p.File = name
+
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return Clean(FromErrors(name, errs))
+	}
+
+	i := interpreter.New(name)
+
+	for _, step := range []func([]parser.Statement) error{i.RegisterForward, i.ResolveTypes, i.TypeCheck} {
+		if err := step(program.Statements); err != nil {
+			return Clean(FromErrors(name, []error{err}))
+		}
+	}
+
+	var warnings []error
+	for _, w := range i.Lint(program.Statements) {
+		warnings = append(warnings, w)
+	}
+
+	return Clean(FromErrors(name, warnings))
+}
+
+// TestGolden checks every program in testdata against its .golden file, which holds the text
+// the cli prints followed by the diagnostics in the shape an lsp client gets
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no programs in testdata")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name := filepath.Base(file)
+			diags := checkSource(name, string(src))
+
+			var got strings.Builder
+			RenderAll(&got, diags, RenderOptions{Source: string(src)})
+
+			lsp := make([]LSPDiagnostic, 0, len(diags))
+			for _, d := range diags {
+				lsp = append(lsp, ToLSP(d, "file:///"+name))
+			}
+
+			data, err := json.MarshalIndent(lsp, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got.WriteString("---\n")
+			got.Write(data)
+			got.WriteString("\n")
+
+			golden := strings.TrimSuffix(file, ".ayla") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run go test ./diagnostics -update to create it", err)
+			}
+
+			if got.String() != string(want) {
+				t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", golden, got.String(), want)
+			}
+		})
+	}
+}
+
+func TestRenderColorWithoutSource(t *testing.T) {
+	d := Diagnostic{Severity: Warning, Message: "careful", File: "a.ayla", Range: at(2, 3)}
+
+	var out strings.Builder
+	Render(&out, d, RenderOptions{Color: true})
+
+	want := "a.ayla: " + ansiYellow + ansiBold + "warning at 2:3: careful" + ansiReset + "\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+// TestCleanFixtureIsRaw makes sure unordered.ayla keeps giving the parser's errors out of order and
+// with a repeat, so its golden file shows Clean doing its job rather than passing through
+func TestCleanFixtureIsRaw(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "unordered.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	p.ParseProgram()
+
+	raw := FromErrors("unordered.ayla", p.Errors())
+
+	ordered, repeated := true, false
+	for idx := 1; idx < len(raw); idx++ {
+		prev, cur := raw[idx-1], raw[idx]
+		if cur.Range.Start.Line < prev.Range.Start.Line {
+			ordered = false
+		}
+
+		if cur.Range.Start == prev.Range.Start && cur.Message == prev.Message {
+			repeated = true
+		}
+	}
+
+	if ordered || !repeated {
+		t.Fatalf("expected errors out of order and with a repeat, got %v", p.Errors())
+	}
+
+	if cleaned := Clean(raw); len(cleaned) != len(raw)-1 {
+		t.Errorf("expected Clean to drop one repeat, kept %d of %d", len(cleaned), len(raw))
+	}
+}
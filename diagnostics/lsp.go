@@ -0,0 +1,84 @@
+package diagnostics
+
+// the lsp shapes below follow the language server protocol, where lines and characters start at 0
+
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+type LSPLocation struct {
+	URI   string   `json:"uri"`
+	Range LSPRange `json:"range"`
+}
+
+type LSPRelatedInformation struct {
+	Location LSPLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+type LSPDiagnostic struct {
+	Range              LSPRange                `json:"range"`
+	Severity           int                     `json:"severity"`
+	Code               string                  `json:"code,omitempty"`
+	Source             string                  `json:"source"`
+	Message            string                  `json:"message"`
+	RelatedInformation []LSPRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+func toLSPPosition(p Position) LSPPosition {
+	line, col := p.Line-1, p.Column-1
+	if line < 0 {
+		line = 0
+	}
+	if col < 0 {
+		col = 0
+	}
+
+	return LSPPosition{Line: line, Character: col}
+}
+
+func toLSPRange(r Range) LSPRange {
+	return LSPRange{Start: toLSPPosition(r.Start), End: toLSPPosition(r.End)}
+}
+
+// ToLSP converts a diagnostic for a client, uri is used for related info in the same file
+func ToLSP(d Diagnostic, uri string) LSPDiagnostic {
+	// lsp severities are 1 error, 2 warning, 3 information, 4 hint
+	severity := 1
+	switch d.Severity {
+	case Warning:
+		severity = 2
+	case Info:
+		severity = 3
+	case Hint:
+		severity = 4
+	}
+
+	out := LSPDiagnostic{
+		Range:    toLSPRange(d.Range),
+		Severity: severity,
+		Code:     d.Code,
+		Source:   "ayla",
+		Message:  d.Message,
+	}
+
+	for _, r := range d.Related {
+		relURI := uri
+		if r.File != "" && r.File != d.File {
+			relURI = r.File
+		}
+
+		out.RelatedInformation = append(out.RelatedInformation, LSPRelatedInformation{
+			Location: LSPLocation{URI: relURI, Range: toLSPRange(r.Range)},
+			Message:  r.Message,
+		})
+	}
+
+	return out
+}
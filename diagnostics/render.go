@@ -0,0 +1,114 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type RenderOptions struct {
+	// Color highlights the severity with ansi escapes
+	Color bool
+
+	// Source, when set, is used to show the offending line with a caret under the column
+	Source string
+}
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiBold   = "\x1b[1m"
+	ansiReset  = "\x1b[0m"
+)
+
+func severityColor(s Severity) string {
+	switch s {
+	case Warning:
+		return ansiYellow
+	case Info, Hint:
+		return ansiCyan
+	default:
+		return ansiRed
+	}
+}
+
+// Render writes one diagnostic as text, prefixed with its file when it has one
+func Render(w io.Writer, d Diagnostic, opts RenderOptions) {
+	msg := d.Error()
+	if opts.Color {
+		msg = severityColor(d.Severity) + ansiBold + msg + ansiReset
+	}
+
+	if d.File != "" {
+		fmt.Fprintf(w, "%s: %s\n", d.File, msg)
+	} else {
+		fmt.Fprintln(w, msg)
+	}
+
+	if opts.Source != "" && d.HasPosition() {
+		writeCaret(w, d, opts)
+	}
+
+	for _, r := range d.Related {
+		file := r.File
+		if file == "" {
+			file = d.File
+		}
+
+		if r.Range.Start.Line > 0 {
+			fmt.Fprintf(w, "  %s:%d:%d: %s\n", file, r.Range.Start.Line, r.Range.Start.Column, r.Message)
+		} else {
+			fmt.Fprintf(w, "  %s: %s\n", file, r.Message)
+		}
+	}
+}
+
+// RenderAll writes every diagnostic in order
+func RenderAll(w io.Writer, diags []Diagnostic, opts RenderOptions) {
+	for _, d := range diags {
+		Render(w, d, opts)
+	}
+}
+
+func writeCaret(w io.Writer, d Diagnostic, opts RenderOptions) {
+	source := strings.TrimPrefix(opts.Source, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	lineNo := d.Range.Start.Line
+	if lineNo > len(lines) {
+		return
+	}
+
+	line := strings.ReplaceAll(lines[lineNo-1], "\t", "    ")
+	runes := []rune(lines[lineNo-1])
+
+	// columns count runes, tabs are shown as four spaces
+	col := d.Range.Start.Column
+	if col < 1 {
+		col = 1
+	}
+
+	pad := 0
+	for idx := 0; idx < col-1 && idx < len(runes); idx++ {
+		if runes[idx] == '\t' {
+			pad += 4
+		} else {
+			pad++
+		}
+	}
+
+	width := 1
+	if d.Range.End.Line == d.Range.Start.Line && d.Range.End.Column > col {
+		width = d.Range.End.Column - col
+	}
+
+	caret := strings.Repeat("^", width)
+	if opts.Color {
+		caret = severityColor(d.Severity) + caret + ansiReset
+	}
+
+	gutter := fmt.Sprintf("%d | ", lineNo)
+	fmt.Fprintf(w, "%s%s\n", gutter, line)
+	fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", len(gutter)+pad), caret)
+}
@@ -0,0 +1,177 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMixedArithmetic runs every operator over each pairing of int and float, an int meeting a
+// float is promoted to float whichever side it is on
+func TestMixedArithmetic(t *testing.T) {
+	tests := []struct {
+		left, op, right string
+		want            string
+	}{
+		// int with int stays int
+		{"7", "+", "2", "9"},
+		{"7", "-", "2", "5"},
+		{"7", "*", "2", "14"},
+		{"7", "/", "2", "3"},
+		{"7", "==", "2", "no"},
+		{"7", "<", "2", "no"},
+		{"7", ">", "2", "yes"},
+
+		// int with float
+		{"3", "+", "2.5", "5.5"},
+		{"3", "-", "2.5", "0.5"},
+		{"3", "*", "2.5", "7.5"},
+		{"3", "/", "2.5", "1.2"},
+		{"3", "==", "3.0", "yes"},
+		{"3", "<", "2.5", "no"},
+		{"3", ">", "2.5", "yes"},
+
+		// float with int
+		{"2.5", "+", "1", "3.5"},
+		{"2.5", "-", "1", "1.5"},
+		{"2.5", "*", "2", "5"},
+		{"2.5", "/", "2", "1.25"},
+		{"2.0", "==", "2", "yes"},
+		{"2.5", "<", "3", "yes"},
+		{"2.5", ">", "3", "no"},
+
+		// float with float
+		{"2.5", "+", "0.5", "3"},
+		{"2.5", "-", "0.5", "2"},
+		{"2.5", "*", "0.5", "1.25"},
+		{"2.5", "/", "0.5", "5"},
+		{"2.5", "==", "2.5", "yes"},
+		{"2.5", "<", "0.5", "no"},
+		{"2.5", ">", "0.5", "yes"},
+	}
+
+	for _, tt := range tests {
+		expr := tt.left + " " + tt.op + " " + tt.right
+
+		typ := "int"
+		if strings.Contains(tt.left+tt.right, ".") {
+			typ = "float"
+		}
+		if strings.ContainsAny(tt.op, "=<>") {
+			typ = "bool"
+		}
+
+		// through variables too, so it isn't only literals being folded together
+		src := "putln(" + expr + ")\nsay l = " + tt.left + "\nsay r = " + tt.right + "\nputln(l " + tt.op + " r)\nputln(typeof(" + expr + "))\n"
+
+		out, err := run(t, src, "")
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+
+		if want := tt.want + "\n" + tt.want + "\n" + typ + "\n"; out != want {
+			t.Errorf("%s: printed %q, want %q", expr, out, want)
+		}
+	}
+}
+
+// TestFloatLeftIntRight keeps the float on the left and promotes the int on the right,
+// including through compound assignment
+func TestFloatLeftIntRight(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say b = 2.5\nsay n = 1\nputln(b - n, b + n, b * n, b / n)\n", want: "1.5 3.5 2.5 2.5\n"},
+		{src: "say b = 2.5\nb -= 1\nputln(b, typeof(b))\n", want: "1.5 float\n"},
+		{src: "say b = 2.5\nb /= 2\nputln(b, typeof(b))\n", want: "1.25 float\n"},
+		{src: "say b = 0.5\nputln(b < 1, b > 0, b == 0)\n", want: "yes yes no\n"},
+	})
+}
+
+func TestPower(t *testing.T) {
+	runScripts(t, []scriptTest{
+		// groups from the right and binds tighter than *
+		{src: "putln(2 ** 3 ** 2, (2 ** 3) ** 2, 2 * 3 ** 2)\n", want: "512 64 18\n"},
+		{src: "putln(2 ** 10, 10 ** 0, 0 ** 0, typeof(2 ** 3))\n", want: "1024 1 1 int\n"},
+		// a negative power gives a float
+		{src: "putln(2 ** -1, 2.0 ** -2, typeof(2 ** -1))\n", want: "0.5 0.25 float\n"},
+		{src: "putln(9.0 ** 0.5, 4 ** 0.5)\n", want: "3 2\n"},
+		// the minus belongs to the number
+		{src: "putln(-2 ** 2, -2 ** 3)\n", want: "4 -8\n"},
+		{src: "say n = 3\nn **= 2\nputln(n, typeof(n))\n", want: "9 int\n"},
+		{src: "say f = 2.0\nf **= -1\nputln(f)\n", want: "0.5\n"},
+		{src: "say m = 2\nm **= -1\n", wantErr: "cannot assign float to m (declared int)"},
+		{src: "putln(\"a\" ** 2)\n", wantErr: "type mismatch: 'string' ** 'int'"},
+	})
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say n = 10\nn += 5\nn -= 3\nn *= 2\nn /= 4\nputln(n)\n", want: "6\n"},
+		{src: "say f = 1.5\nf += 1\nf *= 2\nputln(f)\n", want: "5\n"},
+		{src: "say s = \"ab\"\ns += \"c\"\nputln(s)\n", want: "abc\n"},
+		// index and field targets are read and written back in place
+		{src: "say xs = []int{1, 2}\nxs[0] += 10\nxs[-1] *= 3\nputln(xs)\n", want: "[11, 6]\n"},
+		{src: "type P struct {\n    x int\n}\nsay p = P{x: 1}\np.x *= 7\nputln(p.x)\n", want: "7\n"},
+		{src: "keep k = 1\nk += 1\n", wantErr: "runtime error at 2:1: cannot assign to const: k"},
+		{src: "nope += 1\n", wantErr: "runtime error at 1:1: undefined variable: nope"},
+		{src: "say n = 1\nn += \"x\"\n", wantErr: "type mismatch: 'int' + 'string'"},
+		{src: "say n = 3\nn /= 0\n", wantErr: "division by zero"},
+	})
+}
+
+func TestNilComparison(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(nil == nil, nil != nil)\n", want: "yes no\n"},
+		{src: "putln(nil != 5, 5 == nil, \"a\" != nil, nil == 2.5)\n", want: "yes no yes no\n"},
+		{src: "say p *int = nil\nsay t thing = nil\nputln(p == nil, t == nil)\n", want: "yes yes\n"},
+		{src: "say n = 1\nsay p = &n\nputln(p == nil, p != nil)\n", want: "no yes\n"},
+		{src: "putln(nil < 1)\n", wantErr: "runtime error at 1:7: invalid operator '<' with nil, only == and != can compare against nil"},
+		{src: "putln(1 >= nil)\n", wantErr: "invalid operator '>=' with nil"},
+		{src: "putln(nil + 1)\n", wantErr: "invalid operator '+' with nil"},
+	})
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say i = 1\ni++\ni++\ni--\nputln(i)\n", want: "2\n"},
+		{src: "say f = 1.5\nf++\nputln(f)\nf--\nf--\nputln(f)\n", want: "2.5\n0.5\n"},
+		{src: "for j := 0; j < 3; j++ {\n    put(j)\n}\nputln()\n", want: "012\n"},
+		{src: "for j := 3; j > 0; j-- {\n    put(j)\n}\nputln()\n", want: "321\n"},
+		// a named numeric type keeps its name
+		{src: "type Score int\nsay s Score = 4\ns++\nputln(s, typeof(s))\n", want: "5 Score\n"},
+		{src: "say xs = []int{5}\nxs[0]++\nputln(xs)\n", want: "[6]\n"},
+		{src: "keep k = 1\nk++\n", wantErr: "runtime error at 2:1: cannot assign to const: k"},
+		{src: "say s = \"a\"\ns++\n", wantErr: "invalid operation: ++ on non-numeric type string"},
+		{src: "say b = yes\nb--\n", wantErr: "invalid operation: -- on non-numeric type bool"},
+		{src: "nope--\n", wantErr: "undefined variable: nope"},
+	})
+}
+
+func TestIntegerLiteralBases(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(0xff + 1, 0XFF, 0xDead_Beef)\n", want: "256 255 3735928559\n"},
+		{src: "putln(0b101, 0B11, 0o17, 0O7)\n", want: "5 3 15 7\n"},
+		{src: "putln(-0x10, typeof(0x10), typeof(0b1))\n", want: "-16 int int\n"},
+		{src: "putln(0x7fffffffffffffff)\n", want: "9223372036854775807\n"},
+		{src: "explode(0xff + 1)\n", wantErr: "256"},
+	})
+}
+
+func TestFloatExponents(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say x = 1.5e3\nputln(x, typeof(x))\n", want: "1500 float\n"},
+		{src: "putln(1e3, 1E+2, 2.5e-3, typeof(1e3))\n", want: "1000 100 0.0025 float\n"},
+		{src: "putln(sput(2.5e-3), 2.5e-3 == 0.0025, 1e3 == 1000)\n", want: "0.0025 yes yes\n"},
+		{src: "say e = 3\nputln(1 + e, 2*e)\n", want: "4 6\n"},
+	})
+}
+
+func TestDigitSeparators(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say budget = 1_000_000_000\nputln(budget, typeof(budget))\n", want: "1000000000 int\n"},
+		{src: "say pi = 3.141_592\nputln(pi, typeof(pi))\n", want: "3.141592 float\n"},
+		{src: "putln(0xff_ff, 0b1_0, 1_0.5_0, 1e1_0)\n", want: "65535 2 10.5 10000000000\n"},
+
+		// a leading _ makes it a name, not a number
+		{src: "putln(_100)\n", wantErr: "undefined variable: _100"},
+	})
+}
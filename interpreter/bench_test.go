@@ -0,0 +1,123 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+// each benchmark runs one whole program through the same steps as ayla run, with its output
+// captured and checked. baselines are from go test -bench . ./interpreter on a single core linux vm,
+// a change that makes one of them clearly slower should say why in review
+
+// benchProgram parses src once, then runs it on a fresh interpreter every iteration
+func benchProgram(b *testing.B, src, want string) {
+	stmts := parse(b, src)
+
+	for b.Loop() {
+		var out strings.Builder
+
+		i := New("bench.ayla")
+		i.Stdout = &out
+
+		if err := runStatements(i, stmts); err != nil {
+			b.Fatal(err)
+		}
+
+		if out.String() != want {
+			b.Fatalf("printed %q, want %q", out.String(), want)
+		}
+	}
+}
+
+// baseline: ~198ms/op
+func BenchmarkArithmeticLoop(b *testing.B) {
+	benchProgram(b, `
+say total = 0
+
+for i := 0; i < 100000; i++ {
+    total = total + i * 2 % 7
+}
+
+putln(total)
+`, "299998\n")
+}
+
+// baseline: ~16ms/op
+func BenchmarkRecursiveFib(b *testing.B) {
+	benchProgram(b, `
+fun fib(n int) (int) {
+    ayla n < 2 {
+        give n
+    }
+
+    give fib(n - 1) + fib(n - 2)
+}
+
+putln(fib(18))
+`, "2584\n")
+}
+
+// baseline: ~25ms/op
+func BenchmarkStringConcat(b *testing.B) {
+	benchProgram(b, `
+say s = ""
+
+for i := 0; i < 10000; i++ {
+    s = s + "x"
+}
+
+putln(len(s))
+`, "10000\n")
+}
+
+// baseline: ~99ms/op
+func BenchmarkSlicePushIndex(b *testing.B) {
+	benchProgram(b, `
+say xs = []int{}
+
+for i := 0; i < 20000; i++ {
+    push(xs, i)
+}
+
+say sum = 0
+for i := 0; i < len(xs); i++ {
+    sum = sum + xs[i]
+}
+
+putln(sum)
+`, "199990000\n")
+}
+
+// baseline: ~118ms/op
+func BenchmarkFunctionCalls(b *testing.B) {
+	benchProgram(b, `
+fun add(a int, b int) (int) {
+    give a + b
+}
+
+fun inc(x int) (int) {
+    give add(x, 1)
+}
+
+say n = 0
+for i := 0; i < 20000; i++ {
+    n = inc(n)
+}
+
+putln(n)
+`, "20000\n")
+}
+
+// baseline: ~95ms/op
+func BenchmarkInterpolation(b *testing.B) {
+	benchProgram(b, `
+say last = ""
+
+for i := 0; i < 20000; i++ {
+    say name = "item"
+    last = "${name} ${i}: ${i * 2}"
+}
+
+putln(last)
+`, "item 19999: 39998\n")
+}
@@ -1,11 +1,12 @@
 package interpreter
 
 import (
-	"bufio"
 	"fmt"
 	"io"
-	"os"
+	"math"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/z-sk1/ayla-lang/parser"
 )
@@ -67,6 +68,14 @@ func initBuiltinTypes(TypeEnv map[string]TypeValue) {
 		},
 	}
 
+	TypeEnv["variant"] = TypeValue{
+		TypeInfo: &TypeInfo{
+			Name:         "variant",
+			Kind:         TypeVariant,
+			IsComparable: true,
+		},
+	}
+
 	var emptyInterface = &TypeInfo{
 		Name:    "interface{}",
 		Kind:    TypeInterface,
@@ -99,12 +108,24 @@ func initBuiltinTypes(TypeEnv map[string]TypeValue) {
 	}
 }
 
+// durationUnits is what seconds and minutes convert from, durations are passed around as whole milliseconds
+var durationUnits = []struct {
+	name string
+	unit time.Duration
+}{
+	{"seconds", time.Second},
+	{"minutes", time.Minute},
+}
+
 func (i *Interpreter) registerBuiltins() {
 	env := i.Env
 
 	env.builtins["ord"] = &BuiltinFunc{
-		Name:  "ord",
-		Arity: 1,
+		Name:          "ord",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, "ord")
 			if err != nil {
@@ -121,8 +142,11 @@ func (i *Interpreter) registerBuiltins() {
 	}
 
 	env.builtins["chr"] = &BuiltinFunc{
-		Name:  "chr",
-		Arity: 1,
+		Name:          "chr",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			v, err := ArgInt(node, args, 0, "chr")
 			if err != nil {
@@ -133,15 +157,176 @@ func (i *Interpreter) registerBuiltins() {
 		},
 	}
 
+	env.builtins["upper"] = WrapString1("upper", strings.ToUpper)
+	env.builtins["lower"] = WrapString1("lower", strings.ToLower)
+	env.builtins["trim"] = WrapString1("trim", strings.TrimSpace)
+	env.builtins["split"] = WrapString2RSlice("split", strings.Split)
+	env.builtins["join"] = WrapSliceStringRString("join", strings.Join)
+	env.builtins["contains"] = WrapString2RBool("contains", strings.Contains)
+	env.builtins["startsWith"] = WrapString2RBool("startsWith", strings.HasPrefix)
+	env.builtins["endsWith"] = WrapString2RBool("endsWith", strings.HasSuffix)
+	env.builtins["replace"] = WrapString3("replace", strings.ReplaceAll)
+
+	env.builtins["safediv"] = &BuiltinFunc{
+		Name:          "safediv",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			if len(args) != 2 && len(args) != 3 {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("safediv: expected 2 or 3 arguments, got %d", len(args)))
+			}
+
+			var fallback Value = NilValue{}
+			if len(args) == 3 {
+				fallback = args[2]
+			}
+
+			a := UnwrapFully(args[0])
+			b := UnwrapFully(args[1])
+
+			// two ints stay an int like '/', anything mixed with a float becomes a float
+			if x, ok := a.(IntValue); ok {
+				if y, ok := b.(IntValue); ok {
+					if y.V == 0 {
+						return fallback, nil
+					}
+					return IntValue{V: x.V / y.V}, nil
+				}
+			}
+
+			x, err := ArgFloat(node, args, 0, "safediv")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			y, err := ArgFloat(node, args, 1, "safediv")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			if y == 0 {
+				return fallback, nil
+			}
+
+			return FloatValue{V: x / y}, nil
+		},
+	}
+
+	env.builtins["abs"] = &BuiltinFunc{
+		Name:          "abs",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			switch v := UnwrapFully(args[0]).(type) {
+			case IntValue:
+				// the smallest int has no positive counterpart, negating it gives itself back
+				if v.V == math.MinInt {
+					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("abs: %d has no positive int, it is the smallest int", v.V))
+				}
+				if v.V < 0 {
+					return IntValue{V: -v.V}, nil
+				}
+				return IntValue{V: v.V}, nil
+			case FloatValue:
+				return FloatValue{V: math.Abs(v.V)}, nil
+			}
+
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("abs: expected a number, got %s", i.TypeInfoFromValue(args[0]).Name))
+		},
+	}
+
+	env.builtins["sqrt"] = &BuiltinFunc{
+		Name:          "sqrt",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			f, err := ArgFloat(node, args, 0, "sqrt")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			if f < 0 {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("sqrt: cannot take the square root of negative number %v", f))
+			}
+
+			return FloatValue{V: math.Sqrt(f)}, nil
+		},
+	}
+
+	env.builtins["floor"] = WrapFloat1RInt("floor", math.Floor)
+	env.builtins["ceil"] = WrapFloat1RInt("ceil", math.Ceil)
+	env.builtins["round"] = WrapFloat1RInt("round", math.Round)
+
+	// fractions and negatives round to the nearest millisecond, halves away from zero like round
+	for _, u := range durationUnits {
+		perMilli := float64(u.unit / time.Millisecond)
+		env.builtins[u.name] = WrapFloat1RInt(u.name, func(n float64) float64 {
+			return math.Round(n * perMilli)
+		})
+	}
+
+	env.builtins["durationstr"] = &BuiltinFunc{
+		Name:          "durationstr",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			ms, err := ArgInt(node, args, 0, "durationstr")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			limit := math.MaxInt64 / int64(time.Millisecond)
+			if int64(ms) > limit || int64(ms) < -limit {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("durationstr: %d milliseconds is too long to format", ms))
+			}
+
+			d := time.Duration(ms) * time.Millisecond
+			return StringValue{V: d.String()}, nil
+		},
+	}
+
+	env.builtins["min"] = &BuiltinFunc{
+		Name:          "min",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			return extreme(i, node, "min", args, false)
+		},
+	}
+
+	env.builtins["max"] = &BuiltinFunc{
+		Name:          "max",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			return extreme(i, node, "max", args, true)
+		},
+	}
+
 	env.builtins["len"] = &BuiltinFunc{
-		Name:  "len",
-		Arity: 1,
+		Name:          "len",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
-			v := args[0]
+			v := UnwrapFully(args[0])
 
 			switch v.Type() {
 			case STRING:
-				return IntValue{V: len(v.(StringValue).V)}, nil
+				return IntValue{V: utf8.RuneCountInString(v.(StringValue).V)}, nil
 			case ARR:
 				return IntValue{V: len(v.(ArrayValue).Elements)}, nil
 			case MAP:
@@ -153,10 +338,13 @@ func (i *Interpreter) registerBuiltins() {
 	}
 
 	env.builtins["cap"] = &BuiltinFunc{
-		Name:  "cap",
-		Arity: 1,
+		Name:          "cap",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
-			v := args[0]
+			v := UnwrapFully(args[0])
 
 			switch v.Type() {
 			case ARR:
@@ -167,6 +355,42 @@ func (i *Interpreter) registerBuiltins() {
 		},
 	}
 
+	env.builtins["at"] = &BuiltinFunc{
+		Name:          "at",
+		Arity:         3,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			idx, err := ArgInt(node, args, 1, "at")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			v := UnwrapFully(args[0])
+
+			switch v := v.(type) {
+			case StringValue:
+				r := []rune(v.V)
+				idx, inBounds := resolveIndex(idx, len(r))
+				if !inBounds {
+					return args[2], nil
+				}
+
+				return StringValue{V: string(r[idx])}, nil
+			case ArrayValue:
+				idx, inBounds := resolveIndex(idx, len(v.Elements))
+				if !inBounds {
+					return args[2], nil
+				}
+
+				return copyValue(v.Elements[idx]), nil
+			default:
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("at: type %s not supported", i.TypeInfoFromValue(v).Name))
+			}
+		},
+	}
+
 	env.builtins["close"] = &BuiltinFunc{
 		Name:  "close",
 		Arity: 1,
@@ -188,8 +412,11 @@ func (i *Interpreter) registerBuiltins() {
 	}
 
 	env.builtins["make"] = &BuiltinFunc{
-		Name:  "make",
-		Arity: -1,
+		Name:          "make",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			if len(args) < 1 {
 				return NilValue{}, NewRuntimeError(node, "make: expected at least one argument")
@@ -285,8 +512,11 @@ func (i *Interpreter) registerBuiltins() {
 	}
 
 	env.builtins["append"] = &BuiltinFunc{
-		Name:  "append",
-		Arity: -1,
+		Name:          "append",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			slice, err := ArgArray(node, args, 0, "append", "T")
 			if err != nil {
@@ -301,13 +531,203 @@ func (i *Interpreter) registerBuiltins() {
 					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("append: arg %d expected '%s' but got '%s'", idx, elemType.Name, ArgType.Name))
 				}
 
-				slice.Elements = append(slice.Elements, arg)
+				val, err := i.assignWithType(node, arg, elemType)
+				if err != nil {
+					return NilValue{}, err
+				}
+
+				slice.Elements = append(slice.Elements, val)
 			}
 
 			return slice, nil
 		},
 	}
 
+	env.builtins["push"] = &BuiltinFunc{
+		Name:  "push",
+		Arity: 2,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			arr, err := ArgArray(node, args, 0, "push", "T")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			if arr.Fixed {
+				return NilValue{}, NewRuntimeError(node, "push: cannot push to a fixed size array")
+			}
+
+			argType := i.TypeInfoFromValue(args[1])
+			if !TypesAssignable(argType, arr.ElemType) {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("push: expected '%s' but got '%s'", arr.ElemType.Name, argType.Name))
+			}
+
+			// the same conversion a declaration does, so 2 pushed onto a []float is 2.0
+			val, err := i.assignWithType(node, args[1], arr.ElemType)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			arr.Elements = append(arr.Elements, val)
+
+			if err := i.storeArg(node, 0, arr); err != nil {
+				return NilValue{}, err
+			}
+
+			return arr, nil
+		},
+	}
+
+	env.builtins["pop"] = &BuiltinFunc{
+		Name:  "pop",
+		Arity: 1,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			arr, err := ArgArray(node, args, 0, "pop", "T")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			if arr.Fixed {
+				return NilValue{}, NewRuntimeError(node, "pop: cannot pop from a fixed size array")
+			}
+
+			if len(arr.Elements) == 0 {
+				return NilValue{}, NewRuntimeError(node, "pop: array is empty")
+			}
+
+			last := arr.Elements[len(arr.Elements)-1]
+			arr.Elements = arr.Elements[:len(arr.Elements)-1]
+
+			if err := i.storeArg(node, 0, arr); err != nil {
+				return NilValue{}, err
+			}
+
+			return last, nil
+		},
+	}
+
+	env.builtins["tofloats"] = &BuiltinFunc{
+		Name:          "tofloats",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			arr, err := ArgArray(node, args, 0, "tofloats", "T")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			elements := make([]Value, len(arr.Elements))
+
+			for idx, elem := range arr.Elements {
+				f, ok := toFloat(UnwrapFully(elem))
+				if !ok {
+					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("tofloats: element %d is not a number", idx))
+				}
+
+				elements[idx] = FloatValue{V: f}
+			}
+
+			return ArrayValue{
+				Elements: elements,
+				ElemType: i.TypeEnv["float"].TypeInfo,
+				Capacity: len(elements),
+			}, nil
+		},
+	}
+
+	env.builtins["toints"] = &BuiltinFunc{
+		Name:          "toints",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			arr, err := ArgArray(node, args, 0, "toints", "T")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			elements := make([]Value, len(arr.Elements))
+
+			for idx, elem := range arr.Elements {
+				switch v := UnwrapFully(elem).(type) {
+				case IntValue:
+					elements[idx] = IntValue{V: v.V}
+				case FloatValue:
+					if math.IsNaN(v.V) || v.V < math.MinInt || v.V >= math.MaxInt {
+						return NilValue{}, NewRuntimeError(node, fmt.Sprintf("toints: element %d, %v, does not fit in an int", idx, v.V))
+					}
+					elements[idx] = IntValue{V: int(v.V)}
+				default:
+					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("toints: element %d is not a number", idx))
+				}
+			}
+
+			return ArrayValue{
+				Elements: elements,
+				ElemType: i.TypeEnv["int"].TypeInfo,
+				Capacity: len(elements),
+			}, nil
+		},
+	}
+
+	// accumulate returns every intermediate result of folding arr with f,
+	// the initial value itself is not included so the result is as long as arr
+	env.builtins["accumulate"] = &BuiltinFunc{
+		Name:          "accumulate",
+		Arity:         3,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			arr, err := ArgArray(node, args, 0, "accumulate", "T")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			acc := args[2]
+			elements := make([]Value, len(arr.Elements))
+
+			for idx, elem := range arr.Elements {
+				acc, err = i.callValue(args[1], []Value{acc, elem}, node)
+				if err != nil {
+					return NilValue{}, err
+				}
+
+				elements[idx] = acc
+			}
+
+			return ArrayValue{
+				Elements: elements,
+				ElemType: UnwrapAlias(i.TypeInfoFromValue(args[2])),
+				Capacity: len(elements),
+			}, nil
+		},
+	}
+
+	env.builtins["compose"] = &BuiltinFunc{
+		Name:          "compose",
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			return chainFuncs(i, node, "compose", args[1], args[0])
+		},
+	}
+
+	env.builtins["pipe"] = &BuiltinFunc{
+		Name:          "pipe",
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			return chainFuncs(i, node, "pipe", args[0], args[1])
+		},
+	}
+
 	env.builtins["delete"] = &BuiltinFunc{
 		Name:  "delete",
 		Arity: 2,
@@ -338,14 +758,18 @@ func (i *Interpreter) registerBuiltins() {
 			}
 
 			delete(val.(MapValue).Entries, MapKey(key))
+			delete(val.(MapValue).Keys, MapKey(key))
 			i.Env.Set(ident.Value, val)
 			return NilValue{}, nil
 		},
 	}
 
 	env.builtins["typeof"] = &BuiltinFunc{
-		Name:  "typeof",
-		Arity: 1,
+		Name:          "typeof",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			v := args[0]
 
@@ -364,15 +788,159 @@ func (i *Interpreter) registerBuiltins() {
 		},
 	}
 
-	env.builtins["put"] = &BuiltinFunc{
-		Name:  "put",
+	env.builtins["type"] = &BuiltinFunc{
+		Name:          "type",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			// the kind of value, typeof gives the full type name
+			t := UnwrapFully(args[0]).Type()
+			if t == ARR {
+				return StringValue{V: "array"}, nil
+			}
+
+			return StringValue{V: string(t)}, nil
+		},
+	}
+
+	env.builtins["functions"] = &BuiltinFunc{
+		Name:          "functions",
+		Arity:         0,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			names := i.Env.FuncNames()
+
+			elems := make([]Value, len(names))
+			for idx, name := range names {
+				elems[idx] = StringValue{V: name}
+			}
+
+			return ArrayValue{
+				Elements: elems,
+				ElemType: i.TypeEnv["string"].TypeInfo,
+				Capacity: len(elems),
+			}, nil
+		},
+	}
+
+	env.builtins["call"] = &BuiltinFunc{
+		Name:  "call",
 		Arity: -1,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			if len(args) == 0 {
-				fmt.Print()
-				return NilValue{}, nil
+				return NilValue{}, NewRuntimeError(node, "call: expected the name of a host function")
+			}
+
+			name, err := ArgString(node, args, 0, "call")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			return i.callHost(node, name, args[1:])
+		},
+	}
+
+	env.builtins["memo"] = &BuiltinFunc{
+		Name:          "memo",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			fn := UnwrapFully(args[0])
+
+			arity := -1
+			var typ *TypeInfo
+
+			switch f := fn.(type) {
+			case *Func:
+				if len(f.Params) == 0 || !f.Params[len(f.Params)-1].Variadic {
+					arity = len(f.Params)
+				}
+				typ = f.TypeName
+			case *BuiltinFunc:
+				arity = f.Arity
+				typ = f.TypeName
+			default:
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("memo: expected a function, got %s", i.TypeInfoFromValue(fn).Name))
+			}
+
+			// results are kept per memoized function, keyed by the arguments they were called with
+			cache := newMemoCache(i.MaxMemoEntries)
+
+			return &BuiltinFunc{
+				Name:     "memo",
+				Arity:    arity,
+				TypeName: typ,
+				Fn: func(i *Interpreter, call *parser.FuncCall, args []Value) (Value, error) {
+					key, err := memoKey(i, call, args)
+					if err != nil {
+						return NilValue{}, err
+					}
+
+					if v, ok := cache.get(key); ok {
+						return v, nil
+					}
+
+					v, err := i.callValue(fn, args, call)
+					if err != nil {
+						return NilValue{}, err
+					}
+
+					cache.put(key, v)
+					return v, nil
+				},
+			}, nil
+		},
+	}
+
+	env.builtins["variant"] = &BuiltinFunc{
+		Name:          "variant",
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			tag, err := ArgString(node, args, 0, "variant")
+			if err != nil {
+				return NilValue{}, err
 			}
 
+			if tag == "" {
+				return NilValue{}, NewRuntimeError(node, "variant tag cannot be empty")
+			}
+
+			return VariantValue{Tag: tag, Payload: args[1]}, nil
+		},
+	}
+
+	env.builtins["tagof"] = &BuiltinFunc{
+		Name:          "tagof",
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			v, ok := UnwrapFully(args[0]).(VariantValue)
+			if !ok {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("tagof expects a variant, got %s", UnwrapFully(args[0]).Type()))
+			}
+
+			return StringValue{V: v.Tag}, nil
+		},
+	}
+
+	env.builtins["put"] = &BuiltinFunc{
+		Name:  "put",
+		Arity: -1,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			// built up first so output from another start block can't land in the middle of it
+			var out strings.Builder
+
 			for _, v := range args {
 				ti := UnwrapAlias(i.TypeInfoFromValue(v))
 
@@ -386,17 +954,19 @@ func (i *Interpreter) registerBuiltins() {
 
 						res, err := i.callFunction(method, []Value{receiver}, node)
 						if err != nil {
+							io.WriteString(i.output(), out.String())
 							return NilValue{}, err
 						}
 
-						fmt.Print(res.String())
+						fmt.Fprint(&out, res.String())
 						continue
 					}
 				}
 
-				fmt.Print(v.String())
+				fmt.Fprint(&out, i.format(v))
 			}
 
+			io.WriteString(i.output(), out.String())
 			return NilValue{}, nil
 		},
 	}
@@ -405,9 +975,12 @@ func (i *Interpreter) registerBuiltins() {
 		Name:  "putln",
 		Arity: -1,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			// built up first so a line from another start block can't land in the middle of it
+			var out strings.Builder
+
 			for idx, v := range args {
 				if idx > 0 {
-					fmt.Print(" ")
+					fmt.Fprint(&out, " ")
 				}
 
 				ti := UnwrapAlias(i.TypeInfoFromValue(v))
@@ -422,18 +995,20 @@ func (i *Interpreter) registerBuiltins() {
 
 						res, err := i.callFunction(method, []Value{receiver}, node)
 						if err != nil {
+							io.WriteString(i.output(), out.String())
 							return NilValue{}, err
 						}
 
-						fmt.Print(res.String())
+						fmt.Fprint(&out, res.String())
 						continue
 					}
 				}
 
-				fmt.Print(v.String())
+				fmt.Fprint(&out, i.format(v))
 			}
 
-			fmt.Println()
+			out.WriteString("\n")
+			io.WriteString(i.output(), out.String())
 			return NilValue{}, nil
 		},
 	}
@@ -451,43 +1026,84 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, err
 			}
 
-			goArgs := []any{}
-			for _, v := range args[1:] {
-				goArgs = append(goArgs, aylaValueToGoValue(v))
-			}
+			goArgs := i.formatArgs(args[1:])
 
-			fmt.Printf(format, goArgs...)
+			fmt.Fprintf(i.output(), format, goArgs...)
 			return NilValue{}, nil
 		},
 	}
 
 	env.builtins["sput"] = &BuiltinFunc{
-		Name:  "sput",
-		Arity: -1,
+		Name:          "sput",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			vals := make([]any, len(args))
-			for i, a := range args {
-				vals[i] = a.String()
+			for idx, a := range args {
+				vals[idx] = i.format(a)
 			}
 			return StringValue{V: fmt.Sprint(vals...)}, nil
 		},
 	}
 
 	env.builtins["sputln"] = &BuiltinFunc{
-		Name:  "sputln",
-		Arity: -1,
+		Name:          "sputln",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			vals := make([]any, len(args))
-			for i, a := range args {
-				vals[i] = a.String()
+			for idx, a := range args {
+				vals[idx] = i.format(a)
 			}
 			return StringValue{V: fmt.Sprintln(vals...)}, nil
 		},
 	}
 
+	env.builtins["format"] = &BuiltinFunc{
+		Name:          "format",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			if len(args) == 0 {
+				return NilValue{}, NewRuntimeError(node, "format: expected at least one argument")
+			}
+
+			template, err := ArgString(node, args, 0, "format")
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			values := args[1:]
+			pieces := strings.Split(template, "{}")
+
+			if len(pieces)-1 != len(values) {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("format: %d placeholders but %d values", len(pieces)-1, len(values)))
+			}
+
+			var out strings.Builder
+			for idx, piece := range pieces {
+				out.WriteString(piece)
+				if idx < len(values) {
+					out.WriteString(i.format(values[idx]))
+				}
+			}
+
+			return StringValue{V: out.String()}, nil
+		},
+	}
+
 	env.builtins["sputf"] = &BuiltinFunc{
-		Name:  "sputf",
-		Arity: -1,
+		Name:          "sputf",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			if len(args) == 0 {
 				return NilValue{}, NewRuntimeError(node, "sputf: expected at least one argument")
@@ -498,18 +1114,18 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, err
 			}
 
-			goArgs := []any{}
-			for _, v := range args[1:] {
-				goArgs = append(goArgs, aylaValueToGoValue(v))
-			}
+			goArgs := i.formatArgs(args[1:])
 
 			return StringValue{V: fmt.Sprintf(format, goArgs...)}, nil
 		},
 	}
 
 	env.builtins["errorf"] = &BuiltinFunc{
-		Name:  "sputf",
-		Arity: -1,
+		Name:          "errorf",
+		Arity:         -1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			if len(args) == 0 {
 				return NilValue{}, NewRuntimeError(node, "errorf: expected at least one argument")
@@ -520,10 +1136,7 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, err
 			}
 
-			goArgs := []any{}
-			for _, v := range args[1:] {
-				goArgs = append(goArgs, aylaValueToGoValue(v))
-			}
+			goArgs := i.formatArgs(args[1:])
 
 			return Error{Message: fmt.Errorf(format, goArgs...).Error()}, nil
 		},
@@ -531,22 +1144,27 @@ func (i *Interpreter) registerBuiltins() {
 
 	env.builtins["explode"] = &BuiltinFunc{
 		Name:  "explode",
-		Arity: 1,
+		Arity: -1,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
-			var msg string
+			if len(args) == 0 {
+				return NilValue{}, NewRuntimeError(node, "explode: expected at least one argument")
+			}
 
-			switch v := args[0].(type) {
-			case StringValue:
-				msg = v.V
-			case InterfaceValue:
-				if TypesAssignable(v.TypeInfo, i.TypeEnv["error"].TypeInfo) {
-					msg = v.Value.(InterfaceValue).Value.(Error).Error()
+			// like put, every argument goes on one line separated by spaces
+			parts := make([]string, len(args))
+			for idx, arg := range args {
+				// an error or string held in an interface is still printed as its message
+				switch v := UnwrapFully(arg).(type) {
+				case StringValue:
+					parts[idx] = v.V
+				case Error:
+					parts[idx] = v.Message
+				default:
+					parts[idx] = i.format(arg)
 				}
-			default:
-				msg = v.String()
 			}
 
-			return NilValue{}, NewRuntimeError(node, msg)
+			return NilValue{}, NewRuntimeError(node, strings.Join(parts, " "))
 		},
 	}
 
@@ -563,10 +1181,7 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, err
 			}
 
-			goArgs := []any{}
-			for _, v := range args[1:] {
-				goArgs = append(goArgs, aylaValueToGoValue(v))
-			}
+			goArgs := i.formatArgs(args[1:])
 
 			msg := fmt.Sprintf(format, goArgs...)
 
@@ -578,21 +1193,17 @@ func (i *Interpreter) registerBuiltins() {
 		Name:  "scanln",
 		Arity: -1,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
-			reader := bufio.NewReader(os.Stdin)
-			line, err := reader.ReadString('\n')
+			line, err := i.input().ReadString('\n')
 			if err != nil && err != io.EOF {
 				return NilValue{}, err
 			}
 
-			fields := strings.Fields(line)
-
-			if len(fields) < len(args) {
-				return NilValue{}, NewRuntimeError(node, "scanln: not enough input values")
-			}
+			line = strings.TrimRight(line, "\r\n")
 
-			for idx := 0; idx < len(args) && idx < len(fields); idx++ {
-				arg := args[idx]
+			targets := make([]Assignable, len(args))
+			vals := make([]Value, len(args))
 
+			for idx, arg := range args {
 				// unwrap pointer to get an assignable
 				ass, ok := resolveAssignableArg(arg)
 				if !ok {
@@ -603,8 +1214,29 @@ func (i *Interpreter) registerBuiltins() {
 				if err != nil {
 					return NilValue{}, NewRuntimeError(node, err.Error())
 				}
-				input := fields[idx]
-				err = i.assignInput(node, ass, val, input, "scanln")
+
+				targets[idx], vals[idx] = ass, val
+			}
+
+			// a string at the end takes the rest of the line, spaces and all
+			rest := false
+			if len(vals) > 0 {
+				last := vals[len(vals)-1]
+				if named, ok := last.(NamedValue); ok {
+					last = named.Value
+				}
+
+				_, rest = last.(StringValue)
+			}
+
+			fields := splitInput(line, len(args), rest)
+
+			if len(fields) < len(args) {
+				return NilValue{}, NewRuntimeError(node, "scanln: not enough input values")
+			}
+
+			for idx, ass := range targets {
+				err = i.assignInput(node, ass, vals[idx], fields[idx], "scanln")
 				if err != nil {
 					return NilValue{}, err
 				}
@@ -619,7 +1251,7 @@ func (i *Interpreter) registerBuiltins() {
 		Arity: -1,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 
-			reader := bufio.NewReader(os.Stdin)
+			reader := i.input()
 
 			for _, arg := range args {
 				ass, ok := resolveAssignableArg(arg)
@@ -665,10 +1297,10 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, err
 			}
 
-			reader := bufio.NewReader(os.Stdin)
+			reader := i.input()
 
 			var scanArgs []any
-			var setters []func()
+			var setters []func() error
 
 			for _, arg := range args[1:] {
 				ass, ok := resolveAssignableArg(arg)
@@ -686,29 +1318,29 @@ func (i *Interpreter) registerBuiltins() {
 				case IntValue:
 					var v int
 					scanArgs = append(scanArgs, &v)
-					setters = append(setters, func() {
-						ass.Set(i, IntValue{V: v})
+					setters = append(setters, func() error {
+						return ass.Set(i, IntValue{V: v})
 					})
 
 				case FloatValue:
 					var v float64
 					scanArgs = append(scanArgs, &v)
-					setters = append(setters, func() {
-						ass.Set(i, FloatValue{V: v})
+					setters = append(setters, func() error {
+						return ass.Set(i, FloatValue{V: v})
 					})
 
 				case BoolValue:
 					var v bool
 					scanArgs = append(scanArgs, &v)
-					setters = append(setters, func() {
-						ass.Set(i, BoolValue{V: v})
+					setters = append(setters, func() error {
+						return ass.Set(i, BoolValue{V: v})
 					})
 
 				case StringValue:
 					var v string
 					scanArgs = append(scanArgs, &v)
-					setters = append(setters, func() {
-						ass.Set(i, StringValue{V: v})
+					setters = append(setters, func() error {
+						return ass.Set(i, StringValue{V: v})
 					})
 
 				default:
@@ -722,13 +1354,31 @@ func (i *Interpreter) registerBuiltins() {
 			}
 
 			for _, set := range setters {
-				set()
+				if err := set(); err != nil {
+					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("scanf: %s", err.Error()))
+				}
 			}
 
 			return NilValue{}, nil
 		},
 	}
 
+	env.builtins["readchar"] = &BuiltinFunc{
+		Name:  "readchar",
+		Arity: 0,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			ch, err := i.readChar()
+			if err == io.EOF {
+				return NilValue{}, nil
+			}
+			if err != nil {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("readchar: %s", err.Error()))
+			}
+
+			return StringValue{V: string(ch)}, nil
+		},
+	}
+
 	env.builtins["scankey"] = &BuiltinFunc{
 		Name:  "scankey",
 		Arity: 1,
@@ -743,7 +1393,7 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, NewRuntimeError(node, err.Error())
 			}
 
-			ch, err := readKey()
+			ch, err := i.readChar()
 			if err != nil {
 				return NilValue{}, NewRuntimeError(node, err.Error())
 			}
@@ -761,7 +1411,10 @@ func (i *Interpreter) registerBuiltins() {
 				return NilValue{}, NewRuntimeError(node, "scankey: unsupported type")
 			}
 
-			ass.Set(i, newVal)
+			if err := ass.Set(i, newVal); err != nil {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("scankey: %s", err.Error()))
+			}
+
 			return NilValue{}, nil
 		},
 	}
@@ -0,0 +1,447 @@
+package interpreter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStringsCountCharacters(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`putln(len("héllo"))`, "5\n"},
+		{`s := "héllo"` + "\n" + `putln(s[len(s) - 1])`, "o\n"},
+		{`for i, c := range "añb" {` + "\n" + `    put(i, c, " ")` + "\n}\n", "0a 1ñ 2b "},
+		{`putln(len("世界"), len(""))`, "2 0\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := run(t, tt.src, "")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+
+		if out != tt.want {
+			t.Errorf("%s: printed %q, want %q", tt.src, out, tt.want)
+		}
+	}
+}
+
+func TestMemoFromConcurrentStarts(t *testing.T) {
+	src := `fun square(n int) (int) {
+    give n * n
+}
+
+say fast = memo(square)
+
+for w := range 4 {
+    start {
+        for n := range 1000 {
+            ayla fast(n) != n * n {
+                putln("wrong result for", n)
+            }
+        }
+    }
+}
+`
+
+	out, err := run(t, src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out != "" {
+		t.Errorf("expected every goroutine to get the right results, got %q", out)
+	}
+}
+
+func TestMemoDropsLeastRecentlyUsed(t *testing.T) {
+	src := `say calls = 0
+
+fun square(n int) (int) {
+    calls++
+    give n * n
+}
+
+say fast = memo(square)
+
+fast(1)
+fast(2)
+fast(1)
+fast(3)
+fast(1)
+putln(calls)
+fast(2)
+putln(calls)
+`
+
+	var out strings.Builder
+
+	i := New("test.ayla")
+	i.Stdout = &out
+	i.MaxMemoEntries = 2
+
+	if err := runIn(t, i, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "3\n4\n" {
+		t.Errorf("expected 2 to be dropped once 3 was cached, got %q", out.String())
+	}
+}
+
+func TestFormatVerbsFollowBoolStyle(t *testing.T) {
+	src := `putf("%v %5v %t|", yes, no, yes)
+putln(sputf("%v %s %d %.1f", []bool{yes}, no, 4, 2.5))
+putln(errorf("failed: %v", no))
+explodef("stopped: %v", yes)
+`
+
+	tests := []struct {
+		style BoolStyle
+		out   string
+		err   string
+	}{
+		{BoolYesNo, "yes    no true|[yes] no 4 2.5\nfailed: no\n", "stopped: yes"},
+		{BoolTrueFalse, "true false true|[true] false 4 2.5\nfailed: false\n", "stopped: true"},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+
+		i := New("test.ayla")
+		i.Stdout = &out
+		i.BoolStyle = tt.style
+
+		err := runIn(t, i, src)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("style %d: expected explodef to fail with %q, got %v", tt.style, tt.err, err)
+		}
+
+		if out.String() != tt.out {
+			t.Errorf("style %d: got %q, want %q", tt.style, out.String(), tt.out)
+		}
+	}
+}
+
+func TestPushAndPop(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "xs := []int{1}\npush(xs, 2)\nputln(xs, len(xs))\n", want: "[1, 2] 2\n"},
+		{src: "z := []float{1.5}\npush(z, 2)\nputln(z[1] / 4)\n", want: "0.5\n"},
+		{src: "say z []float = []float{1.5}\nsay n = 2\npush(z, n)\nputln(z[1] / 4)\n", want: "0.5\n"},
+		{src: "z := []float{1.5}\nw := append(z, 3)\nputln(w[1] / 4)\n", want: "0.75\n"},
+		{src: "xs := []string{\"a\"}\npush(xs, 1)\n", wantErr: "push: expected 'string' but got 'int'"},
+		{src: "xs := [2]int{1, 2}\npush(xs, 3)\n", wantErr: "push: cannot push to a fixed size array"},
+		{src: "xs := []int{1, 2, 3}\nputln(pop(xs), xs)\n", want: "3 [1, 2]\n"},
+		{src: "xs := []int{7}\npop(xs)\nputln(len(xs))\n", want: "0\n"},
+		{src: "xs := []int{}\npop(xs)\n", wantErr: "pop: array is empty"},
+	})
+}
+
+func TestExplode(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "explode(\"out of\", \"fuel\")\n", wantErr: "runtime error at 1:1: out of fuel"},
+		{src: "say e error = errorf(\"bad %d\", 3)\nexplode(e)\n", wantErr: "runtime error at 2:1: bad 3"},
+		{src: "explode(errorf(\"raw\"), 2, \"s\")\n", wantErr: "runtime error at 1:1: raw 2 s"},
+		{src: "say t thing = 5\nexplode(t)\n", wantErr: "runtime error at 2:1: 5"},
+		{src: "say t thing = \"held\"\nexplode(t, []int{1})\n", wantErr: "runtime error at 2:1: held [1]"},
+		{src: "explode()\n", wantErr: "explode: expected at least one argument"},
+	})
+}
+
+func TestMathBuiltins(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(max(3, 7, 2), min(3, 7, 2))\n", want: "7 2\n"},
+		{src: "putln(max(1, 2.5), min(2.5, 1))\n", want: "2.5 1\n"},
+		{src: "putln(abs(-4.5), abs(-3), abs(3))\n", want: "4.5 3 3\n"},
+		{src: "say m = -9223372036854775807 - 1\nputln(abs(m + 1))\n", want: "9223372036854775807\n"},
+		{src: "say m = -9223372036854775807 - 1\nabs(m)\n", wantErr: "abs: -9223372036854775808 has no positive int"},
+		{src: "max()\n", wantErr: "max: expected at least one argument"},
+		{src: "min()\n", wantErr: "min: expected at least one argument"},
+		{src: "max(1, \"a\")\n", wantErr: "max: argument 2 must be a number, got string"},
+		{src: "abs(\"a\")\n", wantErr: "abs: expected a number, got string"},
+	})
+}
+
+func TestDurations(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"seconds(1)", "1000"},
+		{"seconds(1.5)", "1500"},
+		{"seconds(-2)", "-2000"},
+		{"seconds(0.0005)", "1"},
+		{"seconds(-0.0005)", "-1"},
+		{"seconds(0.0004)", "0"},
+		{"minutes(1)", "60000"},
+		{"minutes(1.5)", "90000"},
+		{"minutes(-0.5)", "-30000"},
+		{"durationstr(0)", "0s"},
+		{"durationstr(250)", "250ms"},
+		{"durationstr(92000)", "1m32s"},
+		{"durationstr(-1500)", "-1.5s"},
+		{"durationstr(minutes(61))", "1h1m0s"},
+		{"durationstr(seconds(90) + 250)", "1m30.25s"},
+	}
+
+	for _, tt := range tests {
+		out, err := run(t, "putln("+tt.expr+")\n", "")
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+
+		if out != tt.want+"\n" {
+			t.Errorf("%s: printed %q, want %q", tt.expr, out, tt.want+"\n")
+		}
+	}
+
+	runScripts(t, []scriptTest{
+		{src: "seconds(\"1\")\n", wantErr: "seconds: argument 1 must be"},
+		{src: "minutes(1e300)\n", wantErr: "minutes: 1e+300 does not fit in an int"},
+		{src: "durationstr(1.5)\n", wantErr: "durationstr: argument 1 must be"},
+		{src: "durationstr(9223372036854775807)\n", wantErr: "durationstr: 9223372036854775807 milliseconds is too long to format"},
+	})
+}
+
+func TestFunctions(t *testing.T) {
+	const funcs = "fun zeta() {}\nfun alpha() {}\nfun mid(n int) (int) {\n    give n\n}\n"
+
+	runScripts(t, []scriptTest{
+		{src: "putln(functions())\n", want: "[]\n"},
+		{src: funcs + "putln(functions())\n", want: "[alpha, mid, zeta]\n"},
+		// a variable holding a function is called like one, so it is listed too
+		{src: funcs + "say beta = fun() {}\nputln(functions())\n", want: "[alpha, beta, mid, zeta]\n"},
+		// it is listed while it holds one
+		{src: "say f thing = fun() {}\nputln(functions())\nf = 1\nputln(functions())\n", want: "[f]\n[]\n"},
+		// inside a function, its own locals and everything outside
+		{src: funcs + "fun outer() {\n    say inner = fun() {}\n    putln(functions())\n}\nouter()\n", want: "[alpha, inner, mid, outer, zeta]\n"},
+		// a name shadowed by something that isn't a function is left out
+		{src: funcs + "fun shadow() {\n    say alpha = 1\n    putln(functions())\n}\nshadow()\n", want: "[mid, shadow, zeta]\n"},
+		{src: "putln(len(functions()))\n", want: "0\n"},
+	})
+}
+
+func TestCaseAndTrim(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(upper(\"abc é\"), lower(\"ABC É\"))\n", want: "ABC É abc é\n"},
+		{src: "putln(upper(\"\"), lower(\"123\"))\n", want: " 123\n"},
+		{src: "putln(\"[\" + trim(\"  hi there \\n\\t\") + \"]\")\n", want: "[hi there]\n"},
+		{src: "putln(\"[\" + trim(\" \\r\\n \") + \"]\")\n", want: "[]\n"},
+		{src: "type Name string\nsay n Name = \"ayla\"\nputln(upper(n))\n", want: "AYLA\n"},
+		{src: "upper(5)\n", wantErr: "upper: argument 1 must be a string"},
+		{src: "lower([]int{1})\n", wantErr: "lower: argument 1 must be a string"},
+		{src: "trim(yes)\n", wantErr: "trim: argument 1 must be a string"},
+		{src: "upper(\"a\", \"b\")\n", wantErr: "expected 1 args, got 2"},
+	})
+}
+
+func TestSafediv(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(safediv(7, 2), typeof(safediv(7, 2)))\n", want: "3 int\n"},
+		{src: "putln(safediv(7, 0), safediv(7, 0.0))\n", want: "nil nil\n"},
+		{src: "putln(safediv(7, 0, -1), safediv(1.5, 0, \"none\"))\n", want: "-1 none\n"},
+		{src: "putln(safediv(7.0, 2), safediv(7, 2.0), typeof(safediv(7, 2.0)))\n", want: "3.5 3.5 float\n"},
+		{src: "putln(safediv(-9, 2), safediv(1, 4.0))\n", want: "-4 0.25\n"},
+		{src: "safediv(\"7\", 2)\n", wantErr: "safediv: argument 1 must be a number"},
+		{src: "safediv(7, yes)\n", wantErr: "safediv: argument 2 must be a number"},
+		{src: "safediv(7)\n", wantErr: "safediv: expected 2 or 3 arguments, got 1"},
+	})
+}
+
+func TestBoolStyle(t *testing.T) {
+	src := `say ready = yes
+putln(ready, no, []bool{yes, no})
+putln(string(no) + "!")
+putln("ready: ${ready}, done: ${!ready}")
+putln(map[string]bool{"ok": yes})
+`
+
+	tests := []struct {
+		style BoolStyle
+		want  string
+	}{
+		{BoolYesNo, "yes no [yes, no]\nno!\nready: yes, done: no\nmap{ok: yes}\n"},
+		{BoolTrueFalse, "true false [true, false]\nfalse!\nready: true, done: false\nmap{ok: true}\n"},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+
+		i := New("test.ayla")
+		i.Stdout = &out
+		i.BoolStyle = tt.style
+
+		if err := runIn(t, i, src); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.String() != tt.want {
+			t.Errorf("style %d: got %q, want %q", tt.style, out.String(), tt.want)
+		}
+	}
+}
+
+// TestBuiltinNames checks the list ayla doc --keywords prints is the builtins a program can call
+func TestBuiltinNames(t *testing.T) {
+	names := BuiltinNames()
+	if !slices.IsSorted(names) {
+		t.Error("builtin names are not sorted")
+	}
+
+	i := New("test.ayla")
+	if len(names) != len(i.Env.builtins) {
+		t.Errorf("BuiltinNames has %d names, an interpreter has %d builtins", len(names), len(i.Env.builtins))
+	}
+
+	for _, name := range names {
+		if _, ok := i.Env.builtins[name]; !ok {
+			t.Errorf("%s is listed but can't be called", name)
+		}
+	}
+}
+
+func TestToFloatsAndToInts(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "say xs = []thing{1, 2.5, 3}\nputln(tofloats(xs), toints(xs))\n", want: "[1, 2.5, 3] [1, 2, 3]\n"},
+		{src: "putln(typeof(tofloats([]int{1, 2})), typeof(toints([]float{1.5})))\n", want: "[]float []int\n"},
+		// toints truncates toward zero
+		{src: "putln(toints([]float{-1.7, 2.9}))\n", want: "[-1, 2]\n"},
+		{src: "putln(tofloats([]int{}), toints([]float{}))\n", want: "[] []\n"},
+		// the result is a new array
+		{src: "say xs = []int{1}\nsay ys = tofloats(xs)\nys[0] = 9.5\nputln(xs, ys)\n", want: "[1] [9.5]\n"},
+		{src: "toints([]thing{1, \"a\"})\n", wantErr: "toints: element 1 is not a number"},
+		{src: "tofloats([]thing{yes})\n", wantErr: "tofloats: element 0 is not a number"},
+		{src: "tofloats(5)\n", wantErr: "tofloats: argument 1 must be a []T"},
+		{src: "toints([]float{1e300})\n", wantErr: "toints: element 0, 1e+300, does not fit in an int"},
+	})
+}
+
+func TestAccumulate(t *testing.T) {
+	const add = "fun add(a int, b int) (int) {\n    give a + b\n}\n"
+
+	runScripts(t, []scriptTest{
+		// the initial value is left out, so the result is as long as the input
+		{src: add + "putln(accumulate([]int{1, 2, 3}, add, 0))\n", want: "[1, 3, 6]\n"},
+		{src: add + "putln(accumulate([]int{1, 2, 3}, add, 10))\n", want: "[11, 13, 16]\n"},
+		{src: add + "putln(accumulate([]int{}, add, 0))\n", want: "[]\n"},
+		{src: "putln(accumulate([]string{\"a\", \"b\"}, fun(acc string, s string) (string) { acc + s }, \">\"))\n", want: "[>a, >ab]\n"},
+		{src: "accumulate([]int{1, 2}, fun(a int, b int) (int) { explode(\"callback failed\") }, 0)\n", wantErr: "callback failed"},
+		{src: "accumulate(5, 1, 0)\n", wantErr: "accumulate: argument 1 must be a []T"},
+		{src: "accumulate([]int{1}, 1, 0)\n", wantErr: "expected 'function' but got 'int'"},
+	})
+}
+
+func TestComposeAndPipe(t *testing.T) {
+	const fns = "fun inc(x int) (int) {\n    give x + 1\n}\nfun double(x int) (int) {\n    give x * 2\n}\nfun show(x int) (string) {\n    give sput(x)\n}\n"
+
+	runScripts(t, []scriptTest{
+		// compose runs right to left, pipe left to right
+		{src: fns + "putln(compose(inc, double)(3), inc(double(3)))\n", want: "7 7\n"},
+		{src: fns + "putln(pipe(inc, double)(3), double(inc(3)))\n", want: "8 8\n"},
+		{src: fns + "putln(compose(inc, compose(double, inc))(3), pipe(pipe(inc, double), inc)(3))\n", want: "9 9\n"},
+
+		// the chain takes the first function's parameters and gives back the last one's results
+		{src: fns + "putln(typeof(compose(show, inc)), typeof(pipe(inc, show)))\n", want: "fun(int) (string) fun(int) (string)\n"},
+		{src: fns + "say f fun(int) (string) = pipe(inc, show)\nputln(f(1))\n", want: "2\n"},
+
+		{src: fns + "fun boom(x int) (int) {\n    explode(\"boom\")\n}\ncompose(inc, boom)(1)\n", wantErr: "boom"},
+		{src: fns + "compose(inc, double)(1, 2)\n", wantErr: "expected 1 args, got 2"},
+		{src: fns + "compose(inc, 1)\n", wantErr: "compose: expected 'function' but got 'int'"},
+		{src: fns + "pipe(\"inc\", double)\n", wantErr: "pipe: expected 'function' but got 'string'"},
+	})
+}
+
+func TestSplit(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "explode(len(split(\"a,b,c\", \",\")))\n", wantErr: "runtime error at 1:1: 3"},
+		{src: "putln(split(\"a,b,c\", \",\"), typeof(split(\"a\", \",\")))\n", want: "[a, b, c] []string\n"},
+		{src: "putln(split(\"a--b\", \"--\"), split(\"abc\", \"x\"))\n", want: "[a, b] [abc]\n"},
+
+		// empty parts are kept, and an empty string is still one part
+		{src: "putln(split(\"a,,b\", \",\"), len(split(\",a,\", \",\")), len(split(\"\", \",\")))\n", want: "[a, , b] 3 1\n"},
+
+		// an empty separator splits into characters
+		{src: "putln(split(\"héllo\", \"\"), len(split(\"\", \"\")))\n", want: "[h, é, l, l, o] 0\n"},
+
+		{src: "type Csv string\nputln(split(Csv(\"a,b\"), \",\"))\n", want: "[a, b]\n"},
+		{src: "split(1, \",\")\n", wantErr: "split: argument 1 must be a string"},
+		{src: "split(\"a\", 1)\n", wantErr: "split: argument 2 must be a string"},
+	})
+}
+
+func TestJoin(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(join([]string{\"a\", \"b\", \"c\"}, \", \"), join([]string{\"a\", \"b\"}, \"\"))\n", want: "a, b, c ab\n"},
+		{src: "putln(join([]string{}, \",\") == \"\", join([]string{\"x\"}, \"-\"))\n", want: "yes x\n"},
+		{src: "putln(join(split(\"a,b\", \",\"), \";\"))\n", want: "a;b\n"},
+		{src: "type Name string\nputln(join([]Name{Name(\"a\"), Name(\"b\")}, \"+\"))\n", want: "a+b\n"},
+		{src: "join([]int{1, 2}, \",\")\n", wantErr: "join: element 0 of the first argument must be a string, got int"},
+		{src: "xs := []thing{\"a\", 1}\njoin(xs, \",\")\n", wantErr: "join: element 1 of the first argument must be a string, got int"},
+		{src: "join(\"ab\", \",\")\n", wantErr: "join: argument 1 must be a []string"},
+		{src: "join([]string{\"a\"}, 1)\n", wantErr: "join: argument 2 must be a string"},
+	})
+}
+
+func TestStringPredicates(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(contains(\"hello\", \"ell\"), contains(\"hello\", \"xyz\"), contains(\"a\", \"\"))\n", want: "yes no yes\n"},
+		{src: "putln(startsWith(\"hello\", \"he\"), startsWith(\"hello\", \"lo\"), startsWith(\"\", \"a\"))\n", want: "yes no no\n"},
+		{src: "putln(endsWith(\"hello\", \"lo\"), endsWith(\"hello\", \"he\"), endsWith(\"\", \"\"))\n", want: "yes no yes\n"},
+		{src: "putln(typeof(contains(\"a\", \"a\")))\n", want: "bool\n"},
+		{src: "type Path string\nputln(endsWith(Path(\"a.ayla\"), \".ayla\"))\n", want: "yes\n"},
+		{src: "putln(\"ok\")\ncontains(1, \"a\")\n", wantErr: "runtime error at 2:1: contains: argument 1 must be a string"},
+		{src: "startsWith(\"a\", 1)\n", wantErr: "startsWith: argument 2 must be a string"},
+		{src: "endsWith(yes, \"a\")\n", wantErr: "endsWith: argument 1 must be a string"},
+	})
+}
+
+func TestReplace(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(replace(\"a-b-c\", \"-\", \"+\"), replace(\"aaa\", \"a\", \"bb\"), replace(\"abc\", \"b\", \"\"))\n", want: "a+b+c bbbbbb ac\n"},
+		{src: "putln(replace(\"abc\", \"x\", \"y\"), replace(\"ab\", \"\", \"-\"))\n", want: "abc -a-b-\n"},
+
+		// the original string is left alone
+		{src: "s := \"a b\"\nt := replace(s, \" \", \"_\")\nputln(s, t)\n", want: "a b a_b\n"},
+
+		{src: "replace(1, \"a\", \"b\")\n", wantErr: "replace: argument 1 must be a string"},
+		{src: "replace(\"a\", 1, \"b\")\n", wantErr: "replace: argument 2 must be a string"},
+		{src: "replace(\"a\", \"a\", 1)\n", wantErr: "replace: argument 3 must be a string"},
+		{src: "replace(\"a\", \"b\")\n", wantErr: "expected 3 args, got 2"},
+	})
+}
+
+func TestRoundingAndRoots(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln(sqrt(16), sqrt(2.25), sqrt(0), typeof(sqrt(4)))\n", want: "4 1.5 0 float\n"},
+		{src: "putln(floor(2.7), floor(-2.5), floor(3), typeof(floor(2.5)))\n", want: "2 -3 3 int\n"},
+		{src: "putln(ceil(2.1), ceil(-2.5), ceil(3), typeof(ceil(2.5)))\n", want: "3 -2 3 int\n"},
+
+		// halves round away from zero
+		{src: "putln(round(2.5), round(-2.5), round(2.4), round(7), typeof(round(1.5)))\n", want: "3 -3 2 7 int\n"},
+
+		{src: "sqrt(-1)\n", wantErr: "sqrt: cannot take the square root of negative number -1"},
+		{src: "sqrt(-0.5)\n", wantErr: "sqrt: cannot take the square root of negative number -0.5"},
+		{src: "ceil(1e300)\n", wantErr: "ceil: 1e+300 does not fit in an int"},
+		{src: "sqrt(\"4\")\n", wantErr: "sqrt: argument 1 must be a number"},
+		{src: "floor(\"a\")\n", wantErr: "floor: argument 1 must be a number"},
+		{src: "round(yes)\n", wantErr: "round: argument 1 must be a number"},
+	})
+}
+
+func TestFormatPlaceholders(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "x := 1\ny := \"two\"\nputln(format(\"x = {}, y = {}\", x, y))\n", want: "x = 1, y = two\n"},
+		{src: "putln(format(\"none\"), format(\"{}{}\", 1.5, yes), format(\"{}\", []int{1}))\n", want: "none 1.5yes [1]\n"},
+		{src: "putln(typeof(format(\"{}\", 1)), format(\"{{}}\", 1))\n", want: "string {1}\n"},
+		{src: "format(\"{} {}\", 1)\n", wantErr: "format: 2 placeholders but 1 values"},
+		{src: "format(\"{}\", 1, 2)\n", wantErr: "format: 1 placeholders but 2 values"},
+		{src: "format(\"{x}\", 1)\n", wantErr: "format: 0 placeholders but 1 values"},
+		{src: "format(1)\n", wantErr: "format: argument 1 must be a string"},
+		{src: "format()\n", wantErr: "format: expected at least one argument"},
+	})
+}
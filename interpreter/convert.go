@@ -0,0 +1,360 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// FromGo turns a go value into an ayla value so a host can hand it to a script.
+// slices and arrays become arrays and maps with string keys become maps, nested as deep as they go,
+// name is used in errors to say where the unsupported value was, like "args[3].tags"
+func (i *Interpreter) FromGo(name string, v any) (Value, error) {
+	if v == nil {
+		return NilValue{}, nil
+	}
+
+	return i.fromGo(name, reflect.ValueOf(v))
+}
+
+// MustFromGo is FromGo that panics on error, handy when the value is known to be supported
+func (i *Interpreter) MustFromGo(name string, v any) Value {
+	val, err := i.FromGo(name, v)
+	if err != nil {
+		panic(err)
+	}
+
+	return val
+}
+
+func (i *Interpreter) fromGo(path string, rv reflect.Value) (Value, error) {
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.IsNil() {
+			return NilValue{}, nil
+		}
+
+		return i.fromGo(path, rv.Elem())
+
+	case reflect.Bool:
+		return BoolValue{V: rv.Bool()}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntValue{V: int(rv.Int())}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt {
+			return NilValue{}, fmt.Errorf("%s: %d overflows int", path, u)
+		}
+
+		return IntValue{V: int(u)}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return FloatValue{V: rv.Float()}, nil
+
+	case reflect.String:
+		return StringValue{V: rv.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		elemType, err := i.typeFromGo(path+"[]", rv.Type().Elem())
+		if err != nil {
+			return NilValue{}, err
+		}
+
+		elems := make([]Value, rv.Len())
+		for idx := range elems {
+			el, err := i.fromGo(fmt.Sprintf("%s[%d]", path, idx), rv.Index(idx))
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			elems[idx] = el
+		}
+
+		return ArrayValue{
+			Elements: elems,
+			ElemType: elemType,
+			Capacity: len(elems),
+			Fixed:    rv.Kind() == reflect.Array,
+		}, nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return NilValue{}, fmt.Errorf("%s: unsupported type %s, map keys must be strings", path, rv.Type())
+		}
+
+		valueType, err := i.typeFromGo(path+"[]", rv.Type().Elem())
+		if err != nil {
+			return NilValue{}, err
+		}
+
+		m := MapValue{
+			KeyType:   i.TypeEnv["string"].TypeInfo,
+			ValueType: valueType,
+			Entries:   map[string]Value{},
+			Keys:      map[string]Value{},
+		}
+
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+
+			val, err := i.fromGo(memberPath(path, k), iter.Value())
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			key := StringValue{V: k}
+			m.Entries[MapKey(key)] = val
+			m.Keys[MapKey(key)] = key
+		}
+
+		return m, nil
+	}
+
+	return NilValue{}, fmt.Errorf("%s: unsupported type %s", path, rv.Type())
+}
+
+// typeFromGo finds the ayla element type for a go type, interfaces become thing
+func (i *Interpreter) typeFromGo(path string, t reflect.Type) (*TypeInfo, error) {
+	switch t.Kind() {
+	case reflect.Interface:
+		return i.TypeEnv["thing"].TypeInfo, nil
+	case reflect.Bool:
+		return i.TypeEnv["bool"].TypeInfo, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return i.TypeEnv["int"].TypeInfo, nil
+	case reflect.Float32, reflect.Float64:
+		return i.TypeEnv["float"].TypeInfo, nil
+	case reflect.String:
+		return i.TypeEnv["string"].TypeInfo, nil
+
+	case reflect.Slice:
+		elem, err := i.typeFromGo(path+"[]", t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &TypeInfo{Name: "[]" + elem.Name, Kind: TypeArray, Elem: elem}, nil
+
+	case reflect.Array:
+		elem, err := i.typeFromGo(path+"[]", t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &TypeInfo{
+			Name: fmt.Sprintf("[%d]%s", t.Len(), elem.Name),
+			Kind: TypeFixedArray,
+			Elem: elem,
+			Size: t.Len(),
+		}, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: unsupported type %s, map keys must be strings", path, t)
+		}
+
+		val, err := i.typeFromGo(path+"[]", t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &TypeInfo{
+			Name:  "map[string]" + val.Name,
+			Kind:  TypeMap,
+			Key:   i.TypeEnv["string"].TypeInfo,
+			Value: val,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported type %s", path, t)
+}
+
+// ToGo turns an ayla value back into plain go values, arrays become slices and maps become
+// map[string]T, typed by their element types so []int comes back as []int and thing as any,
+// ayla has no nil arrays so a nil go slice comes back empty
+func (i *Interpreter) ToGo(name string, v Value) (any, error) {
+	rv, err := i.toGo(name, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	return rv.Interface(), nil
+}
+
+var goAnyType = reflect.TypeOf((*any)(nil)).Elem()
+
+func (i *Interpreter) toGo(path string, v Value) (reflect.Value, error) {
+	switch val := UnwrapFully(v).(type) {
+	case NilValue:
+		return reflect.Value{}, nil
+	case BoolValue:
+		return reflect.ValueOf(val.V), nil
+	case IntValue:
+		return reflect.ValueOf(val.V), nil
+	case FloatValue:
+		return reflect.ValueOf(val.V), nil
+	case StringValue:
+		return reflect.ValueOf(val.V), nil
+
+	case ArrayValue:
+		elemType, err := goTypeOf(path+"[]", val.ElemType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		var out reflect.Value
+		if val.Fixed {
+			out = reflect.New(reflect.ArrayOf(val.Capacity, elemType)).Elem()
+		} else {
+			out = reflect.MakeSlice(reflect.SliceOf(elemType), len(val.Elements), len(val.Elements))
+		}
+
+		for idx, el := range val.Elements {
+			elPath := fmt.Sprintf("%s[%d]", path, idx)
+
+			rv, err := i.toGo(elPath, el)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			if err := setGo(elPath, out.Index(idx), rv); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+
+		return out, nil
+
+	case MapValue:
+		if UnwrapAlias(val.KeyType).Kind != TypeString {
+			return reflect.Value{}, fmt.Errorf("%s: unsupported type %s, map keys must be strings", path, i.TypeInfoFromValue(val).Name)
+		}
+
+		valueType, err := goTypeOf(path+"[]", val.ValueType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		out := reflect.MakeMapWithSize(reflect.MapOf(reflect.TypeOf(""), valueType), len(val.Entries))
+
+		for k, entry := range val.Entries {
+			key := UnwrapFully(val.Keys[k]).(StringValue).V
+			entryPath := memberPath(path, key)
+
+			rv, err := i.toGo(entryPath, entry)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			slot := reflect.New(valueType).Elem()
+			if err := setGo(entryPath, slot, rv); err != nil {
+				return reflect.Value{}, err
+			}
+
+			out.SetMapIndex(reflect.ValueOf(key), slot)
+		}
+
+		return out, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("%s: unsupported type %s", path, i.TypeInfoFromValue(v).Name)
+}
+
+// goTypeOf picks the go type an ayla element type converts to
+func goTypeOf(path string, ti *TypeInfo) (reflect.Type, error) {
+	ti = UnwrapAlias(ti)
+	if ti == nil {
+		return goAnyType, nil
+	}
+
+	switch ti.Kind {
+	case TypeInt:
+		return reflect.TypeOf(0), nil
+	case TypeFloat:
+		return reflect.TypeOf(0.0), nil
+	case TypeString:
+		return reflect.TypeOf(""), nil
+	case TypeBool:
+		return reflect.TypeOf(false), nil
+
+	case TypeNamed:
+		if ti.Underlying != nil {
+			return goTypeOf(path, ti.Underlying)
+		}
+
+	case TypeArray, TypeFixedArray:
+		elem, err := goTypeOf(path+"[]", ti.Elem)
+		if err != nil {
+			return nil, err
+		}
+
+		if ti.Kind == TypeFixedArray {
+			return reflect.ArrayOf(ti.Size, elem), nil
+		}
+
+		return reflect.SliceOf(elem), nil
+
+	case TypeMap:
+		if UnwrapAlias(ti.Key).Kind != TypeString {
+			return nil, fmt.Errorf("%s: unsupported type %s, map keys must be strings", path, ti.Name)
+		}
+
+		val, err := goTypeOf(path+"[]", ti.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.MapOf(reflect.TypeOf(""), val), nil
+	}
+
+	// interfaces, structs and the rest are checked value by value
+	return goAnyType, nil
+}
+
+func setGo(path string, dst, src reflect.Value) error {
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if !src.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("%s: cannot use %s as %s", path, src.Type(), dst.Type())
+	}
+
+	dst.Set(src)
+	return nil
+}
+
+// memberPath adds a map key to an error path, as .key when it reads like a name and ["key"] otherwise
+func memberPath(path, key string) string {
+	if isPathName(key) {
+		return path + "." + key
+	}
+
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+func isPathName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for idx, r := range s {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		digit := r >= '0' && r <= '9'
+
+		if !letter && (idx == 0 || !digit) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// randomGoType builds a go type FromGo supports, nesting slices, arrays and maps up to depth deep
+func randomGoType(rng *rand.Rand, depth int) reflect.Type {
+	leaves := []reflect.Type{
+		reflect.TypeOf(0),
+		reflect.TypeOf(0.0),
+		reflect.TypeOf(""),
+		reflect.TypeOf(false),
+		goAnyType,
+	}
+
+	if depth == 0 || rng.IntN(3) == 0 {
+		return leaves[rng.IntN(len(leaves))]
+	}
+
+	elem := randomGoType(rng, depth-1)
+
+	switch rng.IntN(3) {
+	case 0:
+		return reflect.SliceOf(elem)
+	case 1:
+		return reflect.ArrayOf(rng.IntN(4), elem)
+	default:
+		return reflect.MapOf(reflect.TypeOf(""), elem)
+	}
+}
+
+// randomGoValue fills in a value of type t, values under any get a random type of their own
+func randomGoValue(rng *rand.Rand, t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Int:
+		v.SetInt(rng.Int64N(2000) - 1000)
+	case reflect.Float64:
+		v.SetFloat(rng.NormFloat64() * 100)
+	case reflect.String:
+		v.SetString(string([]rune("ab é世\n\"")[:rng.IntN(7)]))
+	case reflect.Bool:
+		v.SetBool(rng.IntN(2) == 0)
+
+	case reflect.Interface:
+		// a nil thing comes back as a nil any
+		if rng.IntN(5) > 0 {
+			inner := randomGoType(rng, max(depth-1, 0))
+			v.Set(randomGoValue(rng, inner, depth-1))
+		}
+
+	case reflect.Slice:
+		n := rng.IntN(4)
+		v.Set(reflect.MakeSlice(t, n, n))
+		for idx := range n {
+			v.Index(idx).Set(randomGoValue(rng, t.Elem(), depth-1))
+		}
+
+	case reflect.Array:
+		for idx := range t.Len() {
+			v.Index(idx).Set(randomGoValue(rng, t.Elem(), depth-1))
+		}
+
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		for idx := range rng.IntN(4) {
+			key := fmt.Sprintf("k%d", idx)
+			v.SetMapIndex(reflect.ValueOf(key), randomGoValue(rng, t.Elem(), depth-1))
+		}
+	}
+
+	return v
+}
+
+func TestFromGoToGoRoundTrip(t *testing.T) {
+	i := New("test.ayla")
+	rng := rand.New(rand.NewPCG(7, 19))
+
+	for n := 0; n < 2000; n++ {
+		typ := randomGoType(rng, 4)
+		want := randomGoValue(rng, typ, 4).Interface()
+
+		v, err := i.FromGo("v", want)
+		if err != nil {
+			t.Fatalf("FromGo(%#v): %v", want, err)
+		}
+
+		got, err := i.ToGo("v", v)
+		if err != nil {
+			t.Fatalf("ToGo of %#v: %v", want, err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("round trip of %T changed it\ngot:  %#v\nwant: %#v", want, got, want)
+		}
+	}
+}
+
+func TestFromGoOrderAndPaths(t *testing.T) {
+	i := New("test.ayla")
+
+	v := i.MustFromGo("args", []string{"c", "a", "b"})
+	if got := i.format(v); got != "[c, a, b]" {
+		t.Errorf("expected the elements in order, got %s", got)
+	}
+
+	_, err := i.FromGo("args", []map[string]any{{}, {}, {}, {"tags": make(chan int)}})
+	if err == nil || err.Error() != "args[3].tags: unsupported type chan int" {
+		t.Errorf("expected the error to name where the channel was, got %v", err)
+	}
+
+	_, err = i.FromGo("args", map[string][]func(){"on click": nil})
+	if err == nil || err.Error() != "args[][]: unsupported type func()" {
+		t.Errorf("expected the error to name the element type, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiscardCanBeDeclaredAndAssigned(t *testing.T) {
+	src := `fun pick(_ int, b int, _ string) (int) {
+    give b
+}
+
+say _ = 1
+_ = pick(1, 2, "x")
+
+for _, v := range []int{7} {
+    putln(pick(0, v, ""))
+}
+`
+
+	out, err := run(t, src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out != "7\n" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestDiscardCannotBeRead(t *testing.T) {
+	tests := []string{
+		"putln(_)",
+		"say x = _ + 1",
+		"say nums = []int{1}\nnums[_] = 2",
+		"fun f(_ int) (int) {\n    give _\n}",
+	}
+
+	for _, src := range tests {
+		_, err := run(t, src, "")
+		if err == nil || !strings.Contains(err.Error(), "cannot use '_' as a value") {
+			t.Errorf("%q: expected reading _ to fail, got %v", src, err)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package interpreter
 
 import (
+	"bufio"
+	"container/list"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/z-sk1/ayla-lang/parser"
 	"golang.org/x/term"
@@ -42,9 +48,15 @@ func New(path string) *Interpreter {
 	wd, _ := os.Getwd()
 
 	i := &Interpreter{
-		Env:          env,
-		pointerCache: make(map[*TypeInfo]*TypeInfo),
-		currentDir:   dir,
+		Env:              env,
+		pointerCache:     make(map[*TypeInfo]*TypeInfo),
+		currentDir:       dir,
+		MaxCallDepth:     DefaultMaxCallDepth,
+		MaxPrintElements: DefaultMaxPrintElements,
+		MaxPrintBytes:    DefaultMaxPrintBytes,
+		MaxMemoEntries:   DefaultMaxMemoEntries,
+		steps:            new(atomic.Int64),
+		outMu:            new(sync.Mutex),
 	}
 
 	libDir, err := SetupAylaDirs()
@@ -72,16 +84,89 @@ func New(path string) *Interpreter {
 	return i
 }
 
+// BuiltinNames returns the sorted names of every global builtin function
+func BuiltinNames() []string {
+	i := &Interpreter{
+		Env: &Environment{builtins: make(map[string]*BuiltinFunc)},
+	}
+	i.registerBuiltins()
+
+	names := make([]string, 0, len(i.Env.builtins))
+	for name := range i.Env.builtins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
 func (i *Interpreter) Clone() *Interpreter {
 	return &Interpreter{
-		Env:          i.Env.Clone(),
-		TypeEnv:      i.TypeEnv,
-		pointerCache: i.pointerCache,
-		modulePaths:  i.modulePaths,
-		currentDir:   i.currentDir,
-		projectRoot:  i.projectRoot,
-		Wg:           i.Wg,
+		Env:              i.Env.Clone(),
+		TypeEnv:          i.TypeEnv,
+		pointerCache:     i.pointerCache,
+		modulePaths:      i.modulePaths,
+		currentDir:       i.currentDir,
+		projectRoot:      i.projectRoot,
+		MaxCallDepth:     i.MaxCallDepth,
+		MaxPrintElements: i.MaxPrintElements,
+		MaxPrintBytes:    i.MaxPrintBytes,
+		MaxMemoEntries:   i.MaxMemoEntries,
+		steps:            i.steps,
+		BoolStyle:        i.BoolStyle,
+		MaxSteps:         i.MaxSteps,
+		Stop:             i.Stop,
+		AllowHost:        i.AllowHost,
+		host:             i.host,
+		Stdin:            i.Stdin,
+		Stdout:           i.Stdout,
+		outMu:            i.outMu,
+		stdin:            i.input(),
+		Wg:               i.Wg,
+	}
+}
+
+// format is how the program sees v as text, with bools in the chosen style
+func (i *Interpreter) format(v Value) string {
+	return formatLimited(v, i.BoolStyle, i.MaxPrintElements, i.MaxPrintBytes)
+}
+
+// checkLimits counts a step and ends the program if it ran out of steps or was stopped
+func (i *Interpreter) checkLimits(node parser.Node) error {
+	if i.MaxSteps > 0 {
+		if i.steps.Add(1) > int64(i.MaxSteps) {
+			return LimitError{NewRuntimeError(node, fmt.Sprintf("stopped after %d steps", i.MaxSteps))}
+		}
+	}
+
+	if i.Stop != nil {
+		select {
+		case <-i.Stop:
+			return LimitError{NewRuntimeError(node, "stopped")}
+		default:
+		}
+	}
+
+	return nil
+}
+
+// resolveIndex makes a negative index count back from the end, so -1 is the last element,
+// inBounds is false when the index is still outside 0 to length-1
+func resolveIndex(idx, length int) (resolved int, inBounds bool) {
+	if idx < 0 {
+		idx += length
 	}
+
+	return idx, idx >= 0 && idx < length
+}
+
+// indexOutOfBounds says which index was asked for, and what a negative one turned into
+func indexOutOfBounds(idx, length int) string {
+	if idx < 0 {
+		return fmt.Sprintf("index %d out of bounds for length %d, it resolves to %d", idx, length, idx+length)
+	}
+
+	return fmt.Sprintf("index %d out of bounds for length %d", idx, length)
 }
 
 func NewWithEnv(env *Environment, path string) *Interpreter {
@@ -92,9 +177,15 @@ func NewWithEnv(env *Environment, path string) *Interpreter {
 	wd, _ := os.Getwd()
 
 	i := &Interpreter{
-		Env:          env,
-		pointerCache: make(map[*TypeInfo]*TypeInfo),
-		currentDir:   dir,
+		Env:              env,
+		pointerCache:     make(map[*TypeInfo]*TypeInfo),
+		currentDir:       dir,
+		MaxCallDepth:     DefaultMaxCallDepth,
+		MaxPrintElements: DefaultMaxPrintElements,
+		MaxPrintBytes:    DefaultMaxPrintBytes,
+		MaxMemoEntries:   DefaultMaxMemoEntries,
+		steps:            new(atomic.Int64),
+		outMu:            new(sync.Mutex),
 	}
 
 	libDir, err := SetupAylaDirs()
@@ -160,7 +251,8 @@ func NewRuntimeError(node parser.Node, msg string) RuntimeError {
 	}
 
 	line, col := node.Pos()
-	return RuntimeError{Message: msg, Line: line, Column: col}
+	endLine, endCol := node.End()
+	return RuntimeError{Message: msg, Line: line, Column: col, EndLine: endLine, EndColumn: endCol}
 }
 
 func (e *Environment) Get(name string) (Value, bool, bool) {
@@ -179,6 +271,32 @@ func (e *Environment) Get(name string) (Value, bool, bool) {
 	return nil, false, false
 }
 
+// FuncNames returns the sorted names in scope that hold an ayla function, a say variable
+// holding one counts since it is called the same way, a name shadowed by something that is
+// not a function is left out
+func (e *Environment) FuncNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		for name, v := range env.store {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if _, ok := UnwrapFully(v.Value).(*Func); ok {
+				names = append(names, name)
+			}
+		}
+		env.mu.RUnlock()
+	}
+
+	sort.Strings(names)
+	return names
+}
+
 func (e *Environment) GetLocal(name string) (Value, bool, bool) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
@@ -320,6 +438,32 @@ func aylaValueToGoValue(v Value) any {
 	}
 }
 
+// formatArgs turns the values after a format string into arguments for fmt
+func (i *Interpreter) formatArgs(args []Value) []any {
+	out := make([]any, len(args))
+	for idx, v := range args {
+		out[idx] = formatArg{i: i, v: v}
+	}
+
+	return out
+}
+
+// formatArg writes a value for %v and %s the way printing does, bools in the interpreter's style,
+// other verbs like %d or %.2f get the plain go value
+type formatArg struct {
+	i *Interpreter
+	v Value
+}
+
+func (a formatArg) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), a.i.format(a.v))
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), aylaValueToGoValue(UnwrapFully(a.v)))
+	}
+}
+
 func toFloat(v Value) (float64, bool) {
 	switch x := v.(type) {
 	case FloatValue:
@@ -671,7 +815,62 @@ func capacityFromType(ti *TypeInfo, elems []Value) int {
 	return len(elems)
 }
 
-func readKey() (rune, error) {
+// input is the buffered reader shared by the input builtins
+func (i *Interpreter) input() *bufio.Reader {
+	if i.stdin == nil {
+		var src io.Reader = os.Stdin
+		if i.Stdin != nil {
+			src = i.Stdin
+		}
+
+		i.stdin = bufio.NewReader(src)
+	}
+
+	return i.stdin
+}
+
+// output is where the printing builtins write, every write holds the lock shared with clones
+func (i *Interpreter) output() io.Writer {
+	var w io.Writer = os.Stdout
+	if i.Stdout != nil {
+		w = i.Stdout
+	}
+
+	if i.outMu == nil {
+		return w
+	}
+
+	return lockedWriter{mu: i.outMu, w: w}
+}
+
+// lockedWriter lets goroutines share one writer, like a strings.Builder a host passed as Stdout
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
+
+// readChar reads one character, straight from the keyboard without waiting
+// for enter when stdin is a terminal
+func (i *Interpreter) readChar() (rune, error) {
+	in := i.input()
+
+	// what an earlier scanln left in the buffer comes first, the keyboard is only asked when it is empty
+	if i.Stdin == nil && in.Buffered() == 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+		return readKey(in)
+	}
+
+	r, _, err := in.ReadRune()
+	return r, err
+}
+
+func readKey(in *bufio.Reader) (rune, error) {
 	fd := int(os.Stdin.Fd())
 
 	oldState, err := term.MakeRaw(fd)
@@ -680,45 +879,82 @@ func readKey() (rune, error) {
 	}
 	defer term.Restore(fd, oldState)
 
-	var buf [1]byte
-	_, err = os.Stdin.Read(buf[:])
+	r, _, err := in.ReadRune()
 	if err != nil {
 		return 0, err
 	}
 
-	if buf == [1]byte{'\r'} {
-		buf = [1]byte{'\n'}
+	if r == '\r' {
+		r = '\n'
 	}
 
-	return rune(buf[0]), err
+	return r, nil
 }
 
+// splitInput splits a line on spaces into n fields at most, when rest is set the last field
+// is everything left over with its spaces kept, only trimmed at the ends
+func splitInput(line string, n int, rest bool) []string {
+	if !rest {
+		return strings.Fields(line)
+	}
+
+	var fields []string
+	for len(fields) < n-1 {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields
+		}
+
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+
+	return append(fields, strings.TrimSpace(line))
+}
+
+// assignInput parses input as the type of val, which is what the target holds now, and stores it.
+// a named type like type Age int reads its input as the type it is made of
 func (i *Interpreter) assignInput(node parser.Node, ass Assignable, val Value, input string, name string) error {
+	named, isNamed := val.(NamedValue)
+	if isNamed {
+		val = named.Value
+	}
+
+	var parsed Value
+
 	switch val.(type) {
 
 	case IntValue:
 		n, err := strconv.Atoi(input)
 		if err != nil {
-			return NewRuntimeError(node, fmt.Sprintf("%s: invalid int input", name))
+			return NewRuntimeError(node, fmt.Sprintf("%s: invalid int input %q", name, input))
 		}
-		ass.Set(i, IntValue{V: n})
+		parsed = IntValue{V: n}
 
 	case FloatValue:
 		f, err := strconv.ParseFloat(input, 64)
 		if err != nil {
-			return NewRuntimeError(node, fmt.Sprintf("%s: invalid float input", name))
+			return NewRuntimeError(node, fmt.Sprintf("%s: invalid float input %q", name, input))
 		}
-		ass.Set(i, FloatValue{V: f})
+		parsed = FloatValue{V: f}
 
 	case BoolValue:
-		b, err := strconv.ParseBool(input)
-		if err != nil {
-			return NewRuntimeError(node, fmt.Sprintf("%s: invalid bool input", name))
+		switch input {
+		case "yes", "true":
+			parsed = BoolValue{V: true}
+		case "no", "false":
+			parsed = BoolValue{V: false}
+		default:
+			return NewRuntimeError(node, fmt.Sprintf("%s: invalid bool input %q, expected yes or no", name, input))
 		}
-		ass.Set(i, BoolValue{V: b})
 
 	case StringValue:
-		ass.Set(i, StringValue{V: input})
+		parsed = StringValue{V: input}
 
 	default:
 		err := inferAndAssign(ass, input, i)
@@ -729,6 +965,14 @@ func (i *Interpreter) assignInput(node parser.Node, ass Assignable, val Value, i
 		return nil
 	}
 
+	if isNamed {
+		parsed = NamedValue{TypeName: named.TypeName, Value: parsed}
+	}
+
+	if err := ass.Set(i, parsed); err != nil {
+		return NewRuntimeError(node, fmt.Sprintf("%s: %s", name, err.Error()))
+	}
+
 	return nil
 }
 
@@ -802,6 +1046,212 @@ func compareOrdered(node parser.Node, a, b Value, op string) (Value, error) {
 	return NilValue{}, NewRuntimeError(node, "enum values are not orderable")
 }
 
+// declaredNames returns the names a statement declares in its own block
+func declaredNames(stmt parser.Statement) []string {
+	switch s := stmt.(type) {
+	case *parser.VarStatement:
+		return []string{s.Name.Value}
+	case *parser.VarStatementNoKeyword:
+		return []string{s.Name.Value}
+	case *parser.ConstStatement:
+		return []string{s.Name.Value}
+	case *parser.MultiVarStatement:
+		return identNames(s.Names)
+	case *parser.MultiVarStatementNoKeyword:
+		return identNames(s.Names)
+	case *parser.MultiConstStatement:
+		return identNames(s.Names)
+	case *parser.VarStatementBlock:
+		return blockNames(s.Decls)
+	case *parser.ConstStatementBlock:
+		return blockNames(s.Decls)
+	case *parser.FuncStatement:
+		return []string{s.Name.Value}
+	}
+
+	return nil
+}
+
+func identNames(idents []*parser.Identifier) []string {
+	names := make([]string, 0, len(idents))
+	for _, id := range idents {
+		names = append(names, id.Value)
+	}
+
+	return names
+}
+
+func blockNames(stmts []parser.Statement) []string {
+	var names []string
+	for _, stmt := range stmts {
+		names = append(names, declaredNames(stmt)...)
+	}
+
+	return names
+}
+
+// ifBranches flattens an ayla / elen ayla / elen chain into its bodies
+func ifBranches(stmt *parser.IfStatement) [][]parser.Statement {
+	branches := [][]parser.Statement{stmt.Consequence}
+
+	if len(stmt.Alternative) == 1 {
+		if elif, ok := stmt.Alternative[0].(*parser.IfStatement); ok {
+			return append(branches, ifBranches(elif)...)
+		}
+	}
+
+	if stmt.Alternative != nil {
+		branches = append(branches, stmt.Alternative)
+	}
+
+	return branches
+}
+
+func withNames(visible map[string]bool, names ...string) map[string]bool {
+	out := make(map[string]bool, len(visible)+len(names))
+	for k := range visible {
+		out[k] = true
+	}
+	for _, n := range names {
+		out[n] = true
+	}
+
+	return out
+}
+
+// checkSiblingAssignments reports assigning to a variable that was only ever
+// declared in another branch of the same ayla chain, which can never be in scope
+func checkSiblingAssignments(stmts []parser.Statement, visible, siblings map[string]bool) error {
+	visible = withNames(visible)
+
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *parser.AssignmentStatement:
+			for _, target := range s.Targets {
+				ident, ok := target.(*parser.Identifier)
+				if !ok || visible[ident.Value] || !siblings[ident.Value] {
+					continue
+				}
+
+				return NewRuntimeError(ident, fmt.Sprintf("cannot assign to undeclared variable: %s, it is only declared in another branch of this ayla, declare it before the ayla instead", ident.Value))
+			}
+
+		case *parser.IfStatement:
+			branches := ifBranches(s)
+
+			for idx, body := range branches {
+				inner := withNames(siblings)
+				for other, otherBody := range branches {
+					if other == idx {
+						continue
+					}
+					for _, name := range blockNames(otherBody) {
+						inner[name] = true
+					}
+				}
+
+				if err := checkSiblingAssignments(body, visible, inner); err != nil {
+					return err
+				}
+			}
+
+		case *parser.ForStatement:
+			scope := visible
+			if s.Init != nil {
+				scope = withNames(visible, declaredNames(s.Init)...)
+			}
+
+			if err := checkSiblingAssignments(s.Body, scope, siblings); err != nil {
+				return err
+			}
+
+		case *parser.ForRangeStatement:
+			var names []string
+			for _, id := range []*parser.Identifier{s.Key, s.Value} {
+				if id != nil {
+					names = append(names, id.Value)
+				}
+			}
+
+			if err := checkSiblingAssignments(s.Body, withNames(visible, names...), siblings); err != nil {
+				return err
+			}
+
+		case *parser.WhileStatement:
+			if err := checkSiblingAssignments(s.Body, visible, siblings); err != nil {
+				return err
+			}
+
+		case *parser.SwitchStatement:
+			for _, c := range s.Cases {
+				if err := checkSiblingAssignments(c.Body, visible, siblings); err != nil {
+					return err
+				}
+			}
+
+			if s.Default != nil {
+				if err := checkSiblingAssignments(s.Default.Body, visible, siblings); err != nil {
+					return err
+				}
+			}
+
+		case *parser.MatchStatement:
+			for _, arm := range s.Arms {
+				scope := visible
+				if arm.Binding != nil {
+					scope = withNames(visible, arm.Binding.Value)
+				}
+
+				if err := checkSiblingAssignments(arm.Body, scope, siblings); err != nil {
+					return err
+				}
+			}
+
+			if s.Default != nil {
+				if err := checkSiblingAssignments(s.Default.Body, visible, siblings); err != nil {
+					return err
+				}
+			}
+
+		case *parser.FuncStatement:
+			params := make([]string, 0, len(s.Params))
+			for _, p := range s.Params {
+				params = append(params, p.Name.Value)
+			}
+
+			if err := checkSiblingAssignments(s.Body, withNames(visible, params...), nil); err != nil {
+				return err
+			}
+
+		case *parser.MethodStatement:
+			params := []string{s.Receiver.Name.Value}
+			for _, p := range s.Params {
+				params = append(params, p.Name.Value)
+			}
+
+			if err := checkSiblingAssignments(s.Body, withNames(visible, params...), nil); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range declaredNames(stmt) {
+			visible[name] = true
+		}
+	}
+
+	return nil
+}
+
+// a trailing expression statement is an implicit return
+func endsWithExpression(body []parser.Statement) bool {
+	if len(body) == 0 {
+		return false
+	}
+
+	_, ok := body[len(body)-1].(*parser.ExpressionStatement)
+	return ok
+}
+
 func (i *Interpreter) checkFuncStatement(fn *parser.FuncStatement) error {
 	hasValueReturn := false
 	hasEmptyReturn := false
@@ -816,6 +1266,10 @@ func (i *Interpreter) checkFuncStatement(fn *parser.FuncStatement) error {
 		}
 	}
 
+	if endsWithExpression(fn.Body) && len(fn.ReturnTypes) > 0 {
+		hasValueReturn = true
+	}
+
 	if hasValueReturn && len(fn.ReturnTypes) == 0 {
 		return NewRuntimeError(fn, "function returns a value but has no return type")
 	}
@@ -845,6 +1299,10 @@ func (i *Interpreter) checkFuncLiteral(fn *parser.FuncLiteral) error {
 		}
 	}
 
+	if endsWithExpression(fn.Body) && len(fn.ReturnTypes) > 0 {
+		hasValueReturn = true
+	}
+
 	if hasValueReturn && len(fn.ReturnTypes) == 0 {
 		return NewRuntimeError(fn, "function returns a value but has no return type")
 	}
@@ -874,6 +1332,10 @@ func (i *Interpreter) checkMethodStatement(fn *parser.MethodStatement) error {
 		}
 	}
 
+	if endsWithExpression(fn.Body) && len(fn.ReturnTypes) > 0 {
+		hasValueReturn = true
+	}
+
 	if hasValueReturn && len(fn.ReturnTypes) == 0 {
 		return NewRuntimeError(fn, "method returns a value but has no return type")
 	}
@@ -902,6 +1364,11 @@ func extractEnumValue(node parser.Node, v EnumValue, expected TypeKind) (Value,
 }
 
 func (i *Interpreter) assignWithType(node parser.Node, v Value, expected *TypeInfo) (Value, error) {
+	return i.assignNamed(node, "", v, expected)
+}
+
+// assignNamed is assignWithType for a variable, so a type mismatch can say which variable it was
+func (i *Interpreter) assignNamed(node parser.Node, name string, v Value, expected *TypeInfo) (Value, error) {
 	if expected == nil {
 		return v, nil
 	}
@@ -951,22 +1418,16 @@ func (i *Interpreter) assignWithType(node parser.Node, v Value, expected *TypeIn
 	}
 
 	if !TypesAssignable(actual, baseExpected) {
+		msg := fmt.Sprintf("type mismatch: expected '%s' but got '%s'", expected.Name, actual.Name)
+		if name != "" {
+			msg = fmt.Sprintf("cannot assign %s to %s (declared %s)", actual.Name, name, expected.Name)
+		}
+
 		if node == nil {
-			return NilValue{}, fmt.Errorf(
-				"type mismatch: expected '%s' but got '%s'",
-				expected.Name,
-				actual.Name,
-			)
+			return NilValue{}, fmt.Errorf("%s", msg)
 		}
 
-		return NilValue{}, NewRuntimeError(
-			node,
-			fmt.Sprintf(
-				"type mismatch: expected '%s' but got '%s'",
-				expected.Name,
-				actual.Name,
-			),
-		)
+		return NilValue{}, NewRuntimeError(node, msg)
 	}
 
 	v = i.promoteValueToType(v, expected)
@@ -975,7 +1436,7 @@ func (i *Interpreter) assignWithType(node parser.Node, v Value, expected *TypeIn
 		return NilValue{}, err
 	}
 
-	if arr, ok := v.(ArrayValue); ok && baseExpected.Kind == TypeArray {
+	if arr, ok := v.(ArrayValue); ok && baseExpected.Kind == TypeArray && hasRange(baseExpected.Elem) {
 		for _, el := range arr.Elements {
 			if err := validateRange(node, el, baseExpected.Elem); err != nil {
 				return NilValue{}, err
@@ -1073,7 +1534,7 @@ func (i *Interpreter) paramWithType(node parser.Node, pname string, v Value, exp
 		return NilValue{}, err
 	}
 
-	if arr, ok := v.(ArrayValue); ok && baseExpected.Kind == TypeArray {
+	if arr, ok := v.(ArrayValue); ok && baseExpected.Kind == TypeArray && hasRange(baseExpected.Elem) {
 		for _, el := range arr.Elements {
 			if err := validateRange(node, el, baseExpected.Elem); err != nil {
 				return NilValue{}, err
@@ -1095,6 +1556,11 @@ func (i *Interpreter) paramWithType(node parser.Node, pname string, v Value, exp
 	return v, nil
 }
 
+// hasRange reports whether values of ti have a min or max to check
+func hasRange(ti *TypeInfo) bool {
+	return ti != nil && (ti.Min != nil || ti.Max != nil)
+}
+
 func validateRange(node parser.Node, v Value, expected *TypeInfo) error {
 	if expected.Min != nil || expected.Max != nil {
 		switch val := v.(type) {
@@ -1171,12 +1637,17 @@ func resolveAssignableArg(arg Value) (Assignable, bool) {
 	return ass, ok
 }
 
-func (i *Interpreter) unpackForAssign(node parser.Node, res EvalResult, targetCount int) ([]Value, error) {
+// unpackForAssign gives the values of expr for targetCount targets. a tuple is spread over several
+// targets, and a call that gives back several values hands a single target its first one, but any
+// other tuple, like (1, 2) or a variable holding one, stays whole in a single target
+func (i *Interpreter) unpackForAssign(node parser.Node, expr parser.Expression, res EvalResult, targetCount int) ([]Value, error) {
 	values := res.Values
 
 	if len(values) == 1 {
 		if tup, ok := values[0].(TupleValue); ok {
-			values = tup.Values
+			if _, isCall := expr.(*parser.FuncCall); isCall || targetCount > 1 {
+				values = tup.Values
+			}
 		}
 	}
 
@@ -1210,6 +1681,10 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 	switch e := expr.(type) {
 
 	case *parser.Identifier:
+		if e.Value == "_" {
+			return DiscardTarget{}, nil
+		}
+
 		v, ok := i.Env.GetVar(e.Value)
 		if !ok {
 			return nil, fmt.Errorf("undefined variable: %s", e.Value)
@@ -1222,7 +1697,7 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 
 	case *parser.MemberExpression:
 
-		objVal, err := i.evalOne(e.Left)
+		objVal, err := i.placeValue(e.Left)
 		if err != nil {
 			return nil, err
 		}
@@ -1235,13 +1710,22 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 			}
 		}
 
+		if mv, ok := objVal.(MapValue); ok && UnwrapAlias(mv.KeyType).Kind == TypeString {
+			return MapIndexTarget{
+				Map:       &mv,
+				Key:       StringValue{V: e.Field.Value},
+				KeyType:   mv.KeyType,
+				ValueType: mv.ValueType,
+			}, nil
+		}
+
 		structVal, ok := objVal.(*StructValue)
 		if !ok {
 			return nil, fmt.Errorf("cannot assign field on non-struct, got %s", i.TypeInfoFromValue(objVal).Name)
 		}
 
 		if _, ok := structVal.Fields[e.Field.Value]; !ok {
-			return nil, fmt.Errorf("unknown struct field: %s", e.Field.Value)
+			return nil, fmt.Errorf("%s", noField(e.Field.Value, structVal.TypeName))
 		}
 
 		structTI := structVal.TypeName
@@ -1251,7 +1735,7 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 
 		fieldType, ok := structTI.Fields[e.Field.Value]
 		if !ok {
-			return nil, fmt.Errorf("unknown struct field: %s", e.Field.Value)
+			return nil, fmt.Errorf("%s", noField(e.Field.Value, structVal.TypeName))
 		}
 
 		return MemberTarget{
@@ -1271,7 +1755,7 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 			}
 			leftVal = v.Value
 		} else {
-			leftVal, err = i.evalOne(e.Left)
+			leftVal, err = i.placeValue(e.Left)
 			if err != nil {
 				return nil, err
 			}
@@ -1321,10 +1805,9 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 				return nil, fmt.Errorf("index must be int")
 			}
 
-			idx := idxVal.V
-
-			if idx < 0 || idx >= len(val.Elements) {
-				return nil, fmt.Errorf("index: %d out of bounds", idx)
+			idx, inBounds := resolveIndex(idxVal.V, len(val.Elements))
+			if !inBounds {
+				return nil, fmt.Errorf("%s", indexOutOfBounds(idxVal.V, len(val.Elements)))
 			}
 
 			return ArrayIndexTarget{
@@ -1354,6 +1837,115 @@ func (i *Interpreter) resolveAssignableTarget(expr parser.Expression) (Assignabl
 	return nil, fmt.Errorf("invalid assignment target")
 }
 
+// placeValue gives back the value stored at expr itself rather than a copy, so assigning into it
+// reaches the original, like pts[0].x = 1 or grid[1][2] = 0. anything else is evaluated as usual
+func (i *Interpreter) placeValue(expr parser.Expression) (Value, error) {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		if v, ok := i.Env.GetVar(e.Value); ok {
+			return v.Value, nil
+		}
+
+	case *parser.IndexExpression, *parser.MemberExpression:
+		target, err := i.resolveAssignableTarget(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return target.Get(i)
+	}
+
+	return i.evalOne(expr)
+}
+
+func noField(field string, ti *TypeInfo) string {
+	kind := "struct"
+	if UnwrapAlias(ti).Kind == TypeMap {
+		kind = "map"
+	}
+
+	return fmt.Sprintf("no field named '%s' in %s '%s'", field, kind, ti.Name)
+}
+
+// chainFuncs returns a one argument function that calls first, then passes
+// the result to second
+func chainFuncs(i *Interpreter, node *parser.FuncCall, name string, first, second Value) (Value, error) {
+	for _, fn := range []Value{first, second} {
+		if UnwrapFully(fn).Type() != FUNCTION {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: expected 'function' but got '%s'", name, UnwrapAlias(i.TypeInfoFromValue(fn)).Name))
+		}
+	}
+
+	return &BuiltinFunc{
+		Name:     name,
+		Arity:    1,
+		TypeName: chainedType(i.TypeInfoFromValue(UnwrapFully(first)), i.TypeInfoFromValue(UnwrapFully(second))),
+		Fn: func(i *Interpreter, call *parser.FuncCall, args []Value) (Value, error) {
+			mid, err := i.callValue(first, args, call)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			return i.callValue(second, []Value{mid}, call)
+		},
+	}, nil
+}
+
+// chainedType is the type of a chain that takes first's parameters and gives back second's
+// results, or nil when either side doesn't know its type
+func chainedType(first, second *TypeInfo) *TypeInfo {
+	if first.Kind != TypeFunc || second.Kind != TypeFunc {
+		return nil
+	}
+
+	paramNames := make([]string, len(first.Params))
+	for idx, ti := range first.Params {
+		paramNames[idx] = ti.Name
+	}
+
+	returnNames := make([]string, len(second.Returns))
+	for idx, ti := range second.Returns {
+		returnNames[idx] = ti.Name
+	}
+
+	return &TypeInfo{
+		Name:    fmt.Sprintf("fun(%s) (%s)", strings.Join(paramNames, ", "), strings.Join(returnNames, ", ")),
+		Kind:    TypeFunc,
+		Params:  first.Params,
+		Returns: second.Returns,
+	}
+}
+
+// storeArg writes val back into the variable, field or index that was passed
+// as argument idx, so builtins like push and pop can mutate in place
+func (i *Interpreter) storeArg(node *parser.FuncCall, idx int, val Value) error {
+	switch node.Args[idx].(type) {
+	case *parser.Identifier, *parser.MemberExpression, *parser.IndexExpression:
+	default:
+		return nil
+	}
+
+	target, err := i.resolveAssignableTarget(node.Args[idx])
+	if err != nil {
+		return NewRuntimeError(node, err.Error())
+	}
+
+	old, err := target.Get(i)
+	if err != nil {
+		return NewRuntimeError(node, err.Error())
+	}
+
+	if named, ok := old.(NamedValue); ok {
+		val = NamedValue{TypeName: named.TypeName, Value: val}
+	}
+
+	if err := target.Set(i, val); err != nil {
+		return NewRuntimeError(node, err.Error())
+	}
+
+	return nil
+}
+
 func copyValue(v Value) Value {
 	switch val := v.(type) {
 
@@ -1384,3 +1976,293 @@ func copyValue(v Value) Value {
 		return v
 	}
 }
+
+// memoKey joins the map keys of every argument, so only values that can be map keys can be cached
+func memoKey(i *Interpreter, node parser.Node, args []Value) (string, error) {
+	var sb strings.Builder
+
+	for idx, arg := range args {
+		switch UnwrapFully(arg).(type) {
+		case IntValue, StringValue, BoolValue, *PointerValue, EnumValue:
+		default:
+			return "", NewRuntimeError(node, fmt.Sprintf("memo: argument %d is a %s, which can't be used as a cache key", idx+1, i.TypeInfoFromValue(arg).Name))
+		}
+
+		key := MapKey(arg)
+		fmt.Fprintf(&sb, "%d:%s;", len(key), key)
+	}
+
+	return sb.String(), nil
+}
+
+// memoCache holds the results of one memoized function, it is shared by every goroutine calling it
+type memoCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type memoEntry struct {
+	key   string
+	value Value
+}
+
+func newMemoCache(max int) *memoCache {
+	return &memoCache{max: max, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *memoCache) get(key string) (Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return el.Value.(*memoEntry).value, true
+}
+
+func (c *memoCache) put(key string, v Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoEntry).value = v
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoEntry{key: key, value: v})
+
+	if c.max > 0 && c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoEntry).key)
+	}
+}
+
+// chooseSignal is what a choose gives back after running a branch, snap only ends the choose
+func chooseSignal(sig ControlSignal, err error) (ControlSignal, error) {
+	if err != nil {
+		return SignalNone{}, err
+	}
+
+	if _, ok := sig.(SignalBreak); ok {
+		return SignalNone{}, nil
+	}
+
+	return sig, nil
+}
+
+// checkLoopControl reports snap outside a loop or choose and next outside a loop,
+// a function body starts over since snap can't reach the loop it was called from
+func checkLoopControl(stmts []parser.Statement, inLoop, inChoose bool) error {
+	var err error
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		if err != nil {
+			return false
+		}
+
+		switch n := n.(type) {
+		case *parser.WhileStatement:
+			err = checkLoopControl(n.Body, true, inChoose)
+			return false
+		case *parser.ForStatement:
+			err = checkLoopControl(n.Body, true, inChoose)
+			return false
+		case *parser.ForRangeStatement:
+			err = checkLoopControl(n.Body, true, inChoose)
+			return false
+
+		case *parser.SwitchStatement:
+			if n.Default != nil {
+				err = checkLoopControl(n.Default.Body, inLoop, true)
+			}
+			for _, c := range n.Cases {
+				if err == nil {
+					err = checkLoopControl(c.Body, inLoop, true)
+				}
+			}
+			return false
+
+		case *parser.FuncStatement:
+			err = checkLoopControl(n.Body, false, false)
+			return false
+		case *parser.FuncLiteral:
+			err = checkLoopControl(n.Body, false, false)
+			return false
+		case *parser.MethodStatement:
+			err = checkLoopControl(n.Body, false, false)
+			return false
+
+		case *parser.BreakStatement:
+			if !inLoop && !inChoose {
+				err = NewRuntimeError(n, "snap is only allowed inside a loop or a choose")
+			}
+		case *parser.ContinueStatement:
+			if !inLoop {
+				err = NewRuntimeError(n, "next is only allowed inside a loop")
+			}
+		}
+
+		return true
+	})
+
+	return err
+}
+
+// checkDiscardReads reports '_' used as a value before the program runs,
+// it can be declared or assigned to but never read
+func checkDiscardReads(stmts []parser.Statement) error {
+	// identifiers that name what is being declared or assigned, everything else is a read
+	names := map[*parser.Identifier]bool{}
+	var read *parser.Identifier
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		if read != nil {
+			return false
+		}
+
+		if id, ok := n.(*parser.Identifier); ok {
+			if id.Value == "_" && !names[id] {
+				read = id
+			}
+			return true
+		}
+
+		for _, id := range nameIdents(n) {
+			names[id] = true
+		}
+
+		return true
+	})
+
+	if read != nil {
+		return NewRuntimeError(read, "cannot use '_' as a value")
+	}
+
+	return nil
+}
+
+// nameIdents gives back the identifiers in n that name something instead of reading it,
+// what n declares or assigns to, field names and type names
+func nameIdents(n parser.Node) []*parser.Identifier {
+	var ids []*parser.Identifier
+
+	params := func(ps []*parser.Param) {
+		for _, p := range ps {
+			ids = append(ids, p.Name)
+		}
+	}
+
+	switch n := n.(type) {
+	case *parser.AssignmentStatement:
+		if n.Op == "=" {
+			for _, target := range n.Targets {
+				if id, ok := target.(*parser.Identifier); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+
+	case *parser.VarStatement:
+		ids = append(ids, n.Name)
+	case *parser.VarStatementNoKeyword:
+		ids = append(ids, n.Name)
+	case *parser.ConstStatement:
+		ids = append(ids, n.Name)
+	case *parser.MultiVarStatement:
+		ids = append(ids, n.Names...)
+	case *parser.MultiVarStatementNoKeyword:
+		ids = append(ids, n.Names...)
+	case *parser.MultiConstStatement:
+		ids = append(ids, n.Names...)
+
+	case *parser.TypeStatement:
+		ids = append(ids, n.Name)
+	case *parser.EnumStatement:
+		ids = append(ids, n.Name)
+		for _, m := range n.Members {
+			if v, ok := m.(*parser.Variant); ok {
+				ids = append(ids, v.Name)
+			}
+		}
+	case *parser.StructType:
+		for _, field := range n.Fields {
+			ids = append(ids, field.Name)
+		}
+	case *parser.IdentType:
+		ids = append(ids, n.Name)
+	case *parser.QualifiedType:
+		ids = append(ids, n.Module, n.Name)
+	case *parser.FuncType:
+		ids = append(ids, n.Name)
+
+	case *parser.FuncStatement:
+		ids = append(ids, n.Name)
+		params(n.Params)
+	case *parser.MethodStatement:
+		ids = append(ids, n.Name)
+		if n.Receiver != nil {
+			ids = append(ids, n.Receiver.Name)
+		}
+		params(n.Params)
+	case *parser.FuncLiteral:
+		params(n.Params)
+
+	case *parser.ForRangeStatement:
+		ids = append(ids, n.Key, n.Value)
+	case *parser.MatchArm:
+		ids = append(ids, n.Tag, n.Binding)
+	case *parser.SelectCaseClause:
+		ids = append(ids, n.AssignName)
+	case *parser.MemberExpression:
+		ids = append(ids, n.Field)
+	}
+
+	return ids
+}
+
+// extreme finds the smallest argument, or the largest when max is set.
+// ints stay ints, but one float among them makes the result a float
+func extreme(i *Interpreter, node parser.Node, name string, args []Value, max bool) (Value, error) {
+	if len(args) == 0 {
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: expected at least one argument", name))
+	}
+
+	allInts := true
+	for idx, arg := range args {
+		v := UnwrapFully(arg)
+		if _, ok := toFloat(v); !ok {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: argument %d must be a number, got %s", name, idx+1, i.TypeInfoFromValue(arg).Name))
+		}
+
+		if _, ok := v.(IntValue); !ok {
+			allInts = false
+		}
+	}
+
+	if allInts {
+		best := UnwrapFully(args[0]).(IntValue).V
+		for _, arg := range args[1:] {
+			if v := UnwrapFully(arg).(IntValue).V; (v > best) == max && v != best {
+				best = v
+			}
+		}
+
+		return IntValue{V: best}, nil
+	}
+
+	best, _ := toFloat(UnwrapFully(args[0]))
+	for _, arg := range args[1:] {
+		if v, _ := toFloat(UnwrapFully(arg)); (v > best) == max && v != best {
+			best = v
+		}
+	}
+
+	return FloatValue{V: best}, nil
+}
@@ -0,0 +1,186 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/z-sk1/ayla-lang/parser"
+)
+
+var goErrorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterHost hands a go function to scripts under a name like "host.sendmail", which they run
+// with call("host.sendmail", to, subject) once AllowHost is set. parameters and the result can be
+// ints, floats, strings, bools or slices of them, and an error as the last result becomes a runtime error
+func (i *Interpreter) RegisterHost(name string, fn any) error {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return fmt.Errorf("host %s: expected a func, got %T", name, fn)
+	}
+
+	t := rv.Type()
+	if t.IsVariadic() {
+		return fmt.Errorf("host %s: variadic funcs are not supported", name)
+	}
+
+	for idx := 0; idx < t.NumIn(); idx++ {
+		if !isHostType(t.In(idx)) {
+			return fmt.Errorf("host %s: unsupported parameter type %s", name, t.In(idx))
+		}
+	}
+
+	outs := t.NumOut()
+	if outs > 0 && t.Out(outs-1) == goErrorType {
+		outs--
+	}
+
+	if outs > 1 {
+		return fmt.Errorf("host %s: expected at most one result besides an error, got %s", name, t)
+	}
+
+	if outs == 1 && !isHostType(t.Out(0)) {
+		return fmt.Errorf("host %s: unsupported result type %s", name, t.Out(0))
+	}
+
+	if i.host == nil {
+		i.host = map[string]reflect.Value{}
+	}
+
+	i.host[name] = rv
+	return nil
+}
+
+func isHostType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && isHostType(t.Elem())
+	}
+
+	return false
+}
+
+// callHost runs a registered host function, checking the arguments against its go signature
+func (i *Interpreter) callHost(node *parser.FuncCall, name string, args []Value) (Value, error) {
+	if !i.AllowHost {
+		return NilValue{}, NewRuntimeError(node, "call: host functions are not allowed, the host has to turn on AllowHost")
+	}
+
+	fn, ok := i.host[name]
+	if !ok {
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: no host function named %q", name))
+	}
+
+	t := fn.Type()
+	if len(args) != t.NumIn() {
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: %s expects %d arguments, got %d, its signature is %s",
+			name, t.NumIn(), len(args), t))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		rv, ok := hostArg(t.In(idx), arg)
+		if !ok {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: argument %d of %s must be %s, got %s, its signature is %s",
+				idx+1, name, t.In(idx), i.TypeInfoFromValue(arg).Name, t))
+		}
+
+		in[idx] = rv
+	}
+
+	out, r := callGo(fn, in)
+	if r != nil {
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: %s panicked: %v", name, r))
+	}
+
+	if len(out) > 0 && t.Out(len(out)-1) == goErrorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: %s: %s", name, err.Error()))
+		}
+
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return NilValue{}, nil
+	}
+
+	v, err := i.FromGo(name, out[0].Interface())
+	if err != nil {
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("call: %s", err.Error()))
+	}
+
+	return v, nil
+}
+
+// callGo calls fn and hands back what it panicked with instead of letting the panic take down the host
+func callGo(fn reflect.Value, in []reflect.Value) (out []reflect.Value, r any) {
+	defer func() {
+		r = recover()
+	}()
+
+	return fn.Call(in), nil
+}
+
+// hostArg converts an ayla value to the go type of a host parameter, ints are accepted for floats
+func hostArg(t reflect.Type, v Value) (reflect.Value, bool) {
+	v = UnwrapFully(v)
+	out := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		iv, ok := v.(IntValue)
+		if !ok || out.OverflowInt(int64(iv.V)) {
+			return out, false
+		}
+
+		out.SetInt(int64(iv.V))
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(v)
+		if !ok {
+			return out, false
+		}
+
+		out.SetFloat(f)
+
+	case reflect.String:
+		s, ok := v.(StringValue)
+		if !ok {
+			return out, false
+		}
+
+		out.SetString(s.V)
+
+	case reflect.Bool:
+		b, ok := v.(BoolValue)
+		if !ok {
+			return out, false
+		}
+
+		out.SetBool(b.V)
+
+	case reflect.Slice:
+		arr, ok := v.(ArrayValue)
+		if !ok {
+			return out, false
+		}
+
+		out = reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements))
+		for idx, el := range arr.Elements {
+			rv, ok := hostArg(t.Elem(), el)
+			if !ok {
+				return out, false
+			}
+
+			out.Index(idx).Set(rv)
+		}
+
+	default:
+		return out, false
+	}
+
+	return out, true
+}
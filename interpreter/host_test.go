@@ -0,0 +1,124 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// hostInterp gives back an interpreter with two host functions registered and AllowHost on
+func hostInterp(t *testing.T, out *strings.Builder) *Interpreter {
+	t.Helper()
+
+	i := New("test.ayla")
+	i.Stdout = out
+	i.AllowHost = true
+
+	err := i.RegisterHost("host.add", func(a, b int) int {
+		return a + b
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.RegisterHost("host.join", func(parts []string, sep string) (string, error) {
+		if len(parts) == 0 {
+			return "", errors.New("nothing to join")
+		}
+
+		return strings.Join(parts, sep), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return i
+}
+
+func TestCallHost(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"ints", "putln(call(\"host.add\", 2, 40))\n", "42\n"},
+		{"slice and error result", "putln(call(\"host.join\", []string{\"a\", \"b\"}, \"-\"))\n", "a-b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := runIn(t, hostInterp(t, &out), tt.src); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("printed %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCallHostErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"too few arguments", "call(\"host.add\", 1)\n", "call: host.add expects 2 arguments, got 1, its signature is func(int, int) int"},
+		{"too many arguments", "call(\"host.join\", []string{}, \"-\", 3)\n", "call: host.join expects 2 arguments, got 3"},
+		{"wrong type", "call(\"host.add\", 1, \"2\")\n", "call: argument 2 of host.add must be int, got string, its signature is func(int, int) int"},
+		{"wrong element type", "call(\"host.join\", []int{1}, \"-\")\n", "call: argument 1 of host.join must be []string"},
+		{"error result", "call(\"host.join\", []string{}, \"-\")\n", "call: host.join: nothing to join"},
+		{"unknown name", "call(\"host.missing\")\n", "call: no host function named \"host.missing\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := runIn(t, hostInterp(t, &out), tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCallHostNeedsAllowHost(t *testing.T) {
+	var out strings.Builder
+
+	i := hostInterp(t, &out)
+	i.AllowHost = false
+
+	err := runIn(t, i, "call(\"host.add\", 1, 2)\n")
+	if err == nil || !strings.Contains(err.Error(), "host functions are not allowed") {
+		t.Errorf("expected call to be refused, got %v", err)
+	}
+}
+
+func TestCallHostPanicIsRuntimeError(t *testing.T) {
+	var out strings.Builder
+
+	i := hostInterp(t, &out)
+	err := i.RegisterHost("host.first", func(xs []int) int {
+		return xs[0]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runIn(t, i, "putln(\"before\")\ncall(\"host.first\", []int{})\nputln(\"after\")\n")
+
+	var rerr RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "call: host.first panicked: runtime error: index out of range") {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+
+	if out.String() != "before\n" {
+		t.Errorf("expected the script to stop at the panic, printed %q", out.String())
+	}
+}
@@ -0,0 +1,63 @@
+package interpreter
+
+import "testing"
+
+// TestIndexMatrix covers read, write and slice on arrays and strings, with indexes that are in
+// range, negative and out of range
+func TestIndexMatrix(t *testing.T) {
+	const arr = "xs := []int{1, 2, 3}\n"
+	const str = "s := \"héllo\"\n"
+
+	runScripts(t, []scriptTest{
+		// read × array
+		{src: arr + "putln(xs[0], xs[2])\n", want: "1 3\n"},
+		{src: arr + "putln(xs[-1], xs[-3])\n", want: "3 1\n"},
+		{src: arr + "putln(xs[3])\n", wantErr: "index 3 out of bounds for length 3"},
+		{src: arr + "putln(xs[-4])\n", wantErr: "index -4 out of bounds for length 3, it resolves to -1"},
+
+		// read × string, by character
+		{src: str + "putln(s[0], s[1])\n", want: "h é\n"},
+		{src: str + "putln(s[-1], s[-5])\n", want: "o h\n"},
+		{src: str + "putln(s[5])\n", wantErr: "index 5 out of bounds for length 5"},
+		{src: str + "putln(s[-6])\n", wantErr: "index -6 out of bounds for length 5, it resolves to -1"},
+
+		// write × array
+		{src: arr + "xs[0] = 7\nputln(xs)\n", want: "[7, 2, 3]\n"},
+		{src: arr + "xs[-1] = 9\nputln(xs)\n", want: "[1, 2, 9]\n"},
+		{src: arr + "xs[3] = 9\n", wantErr: "index 3 out of bounds for length 3"},
+		{src: arr + "xs[-4] = 9\n", wantErr: "index -4 out of bounds for length 3, it resolves to -1"},
+
+		// write × string, strings can't be changed in place
+		{src: str + "s[0] = \"j\"\n", wantErr: "invalid assignment target"},
+		{src: str + "s[-1] = \"j\"\n", wantErr: "invalid assignment target"},
+
+		// slice × array
+		{src: arr + "putln(xs[1:], xs[0:3], xs[:])\n", want: "[2, 3] [1, 2, 3] [1, 2, 3]\n"},
+		{src: arr + "putln(xs[:-1], xs[-2:])\n", want: "[1, 2] [2, 3]\n"},
+		{src: arr + "putln(xs[1:5])\n", wantErr: "slice bounds out of range [1:5] with length 3"},
+		{src: arr + "putln(xs[2:1])\n", wantErr: "slice bounds out of range [2:1] with length 3"},
+		{src: arr + "putln(xs[-5:])\n", wantErr: "slice bounds out of range [-5:3] with length 3, they resolve to [-2:3]"},
+
+		// slice × string
+		{src: str + "putln(s[1:3], s[:])\n", want: "él héllo\n"},
+		{src: str + "putln(s[:-1], s[-3:])\n", want: "héll llo\n"},
+		{src: str + "putln(s[1:9])\n", wantErr: "slice bounds out of range [1:9] with length 5"},
+		{src: str + "putln(s[-9:])\n", wantErr: "slice bounds out of range [-9:5] with length 5, they resolve to [-4:5]"},
+	})
+}
+
+// TestAt covers at, which gives back its default instead of stopping when the index is out of range
+func TestAt(t *testing.T) {
+	const arr = "xs := []int{1, 2, 3}\n"
+	const str = "s := \"héllo\"\n"
+
+	runScripts(t, []scriptTest{
+		{src: arr + "putln(at(xs, 0, -1), at(xs, 2, -1))\n", want: "1 3\n"},
+		{src: arr + "putln(at(xs, 3, -1), at(xs, 99, 0))\n", want: "-1 0\n"},
+		{src: arr + "putln(at(xs, -1, 0), at(xs, -3, 0), at(xs, -4, 0))\n", want: "3 1 0\n"},
+		{src: str + "putln(at(s, 1, \"?\"), at(s, -1, \"?\"), at(s, 5, \"?\"))\n", want: "é o ?\n"},
+		{src: "xs := [][]int{[]int{1}}\nys := at(xs, 0, []int{})\nys[0] = 9\nputln(xs)\n", want: "[[1]]\n"},
+		{src: arr + "at(xs, \"0\", 0)\n", wantErr: "at: argument 2 must be"},
+		{src: "at(5, 0, 0)\n", wantErr: "at: type int not supported"},
+	})
+}
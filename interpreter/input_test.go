@@ -0,0 +1,147 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadChar(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		stdin string
+		want  string
+	}{
+		{"one at a time", "putln(readchar(), readchar())\n", "hé", "h é\n"},
+		{"nothing left", "putln(readchar())\n", "", "nil\n"},
+		{"after scanln", "say name string\nscanln(&name)\nputln(name, readchar())\n", "ayla\nq", "ayla q\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(t, tt.src, tt.stdin)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != tt.want {
+				t.Errorf("printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartReadsSharedInput(t *testing.T) {
+	src := "start {\n    putln(readchar())\n}\n"
+
+	out, err := run(t, src, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out != "x\n" {
+		t.Errorf("printed %q, want %q", out, "x\n")
+	}
+}
+
+func TestScanln(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		stdin string
+		want  string
+	}{
+		{"full line", "say name string\nscanln(&name)\nputln(name)\n", "John Doe\n", "John Doe\n"},
+		{"windows line ending", "say name string\nscanln(&name)\nputln(name + \"!\")\n", "John Doe\r\n", "John Doe!\n"},
+		{"no newline at the end", "say name string\nscanln(&name)\nputln(name)\n", "  John Doe  ", "John Doe\n"},
+		{"rest of the line", "say age int\nsay name string\nscanln(&age, &name)\nputln(age + 1, name)\n", "41 John  Doe\n", "42 John  Doe\n"},
+		{"one line each", "say a string\nsay b string\nscanln(&a)\nscanln(&b)\nputln(b, a)\n", "first line\nsecond line\n", "second line first line\n"},
+		{"split on spaces", "say x int\nsay y int\nscanln(&x, &y)\nputln(x * y)\n", "6 7\n", "42\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(t, tt.src, tt.stdin)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != tt.want {
+				t.Errorf("printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanlnNotEnoughValues(t *testing.T) {
+	_, err := run(t, "say x int\nsay y int\nscanln(&x, &y)\n", "6\n")
+	if err == nil || !strings.Contains(err.Error(), "scanln: not enough input values") {
+		t.Errorf("expected a not enough input values error, got %v", err)
+	}
+}
+
+func TestInteractiveScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "prompt and answer",
+			src:   "put(\"name? \")\nsay name string\nscanln(&name)\nputln(\"hi\", name)\n",
+			stdin: "Ayla Lang\n",
+			want:  "name? hi Ayla Lang\n",
+		},
+		{
+			name:  "scan across lines",
+			src:   "say a int\nsay b float\nsay c bool\nscan(&a, &b, &c)\nputf(\"%d %.1f %v\\n\", a, b, c)\n",
+			stdin: "3\n1.5\nyes\n",
+			want:  "3 1.5 yes\n",
+		},
+		{
+			name:  "scanf",
+			src:   "say w int\nsay h int\nscanf(\"%dx%d\", &w, &h)\nputln(w * h)\n",
+			stdin: "4x5\n",
+			want:  "20\n",
+		},
+		{
+			name:  "loop until zero",
+			src:   "say total = 0\nsay n int\nscanln(&n)\nwhile n != 0 {\n    total += n\n    scanln(&n)\n}\nputln(total)\n",
+			stdin: "1\n2\n3\n0\n",
+			want:  "6\n",
+		},
+		{
+			name:    "explode stops with its message",
+			src:     "say n int\nscanln(&n)\nayla n < 0 {\n    explode(\"negative:\", n)\n}\nputln(n)\n",
+			stdin:   "-2\n",
+			want:    "",
+			wantErr: "negative: -2",
+		},
+		{
+			name:    "input runs out",
+			src:     "say a int\nscan(&a)\n",
+			stdin:   "",
+			wantErr: "scan: unexpected end of input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(t, tt.src, tt.stdin)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != tt.want {
+				t.Errorf("printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package interpreter
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/z-sk1/ayla-lang/lexer"
 	"github.com/z-sk1/ayla-lang/parser"
@@ -32,16 +37,71 @@ type Interpreter struct {
 	currentDir   string
 	projectRoot  string
 
+	PrintResults bool
+
+	// BoolStyle is how printing, interpolation and string() write bools
+	BoolStyle BoolStyle
+
+	// MaxCallDepth caps how deeply ayla functions can recurse, 0 means no limit
+	MaxCallDepth int
+	depth        int
+
+	// MaxPrintElements caps how many array and map elements printing or converting one value to a
+	// string writes, nested ones included, and MaxPrintBytes caps how many bytes. 0 means no limit
+	MaxPrintElements int
+	MaxPrintBytes    int
+
+	// MaxMemoEntries caps how many results each function made by memo keeps, the least recently
+	// used ones are dropped first. 0 means no limit
+	MaxMemoEntries int
+
+	// pure only lets builtins without side effects run, see EvalSelection
+	pure bool
+
+	// AllowHost lets scripts run the go functions given to RegisterHost with call, it is off by default
+	AllowHost bool
+	host      map[string]reflect.Value
+
+	// Stdin is where input builtins read from, nil means os.Stdin
+	Stdin io.Reader
+	stdin *bufio.Reader
+
+	// Stdout is where printing builtins write to, nil means os.Stdout
+	Stdout io.Writer
+	outMu  *sync.Mutex // shared with clones, so goroutines made with start take turns writing
+
+	// MaxSteps ends the program with a LimitError once that many statements and loop iterations
+	// have run, 0 means no limit. goroutines made with start count toward the same total
+	MaxSteps int
+	steps    *atomic.Int64
+
+	// Stop ends the program with a LimitError at the next statement once it is closed,
+	// so a host can cancel a run or give it a deadline
+	Stop <-chan struct{}
+
 	Wg sync.WaitGroup
 }
 
+const DefaultMaxCallDepth = 5000
+
+const DefaultMaxMemoEntries = 10000
+
 var GlobalModules map[string]ModuleValue = map[string]ModuleValue{}
 var NativeModules map[string]NativeLoader = map[string]NativeLoader{}
 
+// LimitError ends a program that was stopped from outside by MaxSteps or Stop, instead of failing by itself
+type LimitError struct {
+	RuntimeError
+}
+
 type RuntimeError struct {
 	Message string
 	Line    int
 	Column  int
+
+	// EndLine and EndColumn are just past the node the error is about
+	EndLine   int
+	EndColumn int
 }
 
 func (e RuntimeError) Error() string {
@@ -60,6 +120,7 @@ var compoundOps = map[token.TokenType]string{
 	token.MUL_ASSIGN:   "*",
 	token.SLASH_ASSIGN: "/",
 	token.MOD_ASSIGN:   "%",
+	token.POW_ASSIGN:   "**",
 
 	token.AND_ASSIGN: "&",
 	token.OR_ASSIGN:  "|",
@@ -153,6 +214,7 @@ func (i *Interpreter) loadModule(name string) (Value, error) {
 
 	l := lexer.New(src)
 	p := parser.New(l)
+	p.File = path
 	program := p.ParseProgram()
 
 	Env := NewEnvironment(i.Env)
@@ -160,20 +222,32 @@ func (i *Interpreter) loadModule(name string) (Value, error) {
 	modInterp := NewWithEnv(Env, path)
 	modInterp.TypeEnv = i.TypeEnv
 	modInterp.currentDir = filepath.Dir(path)
-
-	if err := modInterp.RegisterForward(program); err != nil {
+	modInterp.AllowHost = i.AllowHost
+	modInterp.host = i.host
+	modInterp.MaxPrintElements = i.MaxPrintElements
+	modInterp.MaxPrintBytes = i.MaxPrintBytes
+	modInterp.MaxMemoEntries = i.MaxMemoEntries
+	modInterp.MaxSteps = i.MaxSteps
+	modInterp.steps = i.steps
+	modInterp.Stop = i.Stop
+	modInterp.Stdin = i.Stdin
+	modInterp.stdin = i.input()
+	modInterp.Stdout = i.Stdout
+	modInterp.outMu = i.outMu
+
+	if err := modInterp.RegisterForward(program.Statements); err != nil {
 		return NilValue{}, err
 	}
 
-	if err := modInterp.ResolveTypes(program); err != nil {
+	if err := modInterp.ResolveTypes(program.Statements); err != nil {
 		return NilValue{}, err
 	}
 
-	if err := modInterp.TypeCheck(program); err != nil {
+	if err := modInterp.TypeCheck(program.Statements); err != nil {
 		return NilValue{}, err
 	}
 
-	_, err = modInterp.EvalStatements(program)
+	_, err = modInterp.EvalStatements(program.Statements)
 	if err != nil {
 		return NilValue{}, err
 	}
@@ -332,6 +406,19 @@ func (i *Interpreter) ResolveTypes(stmts []parser.Statement) error {
 }
 
 func (i *Interpreter) TypeCheck(stmts []parser.Statement) error {
+	if err := checkDiscardReads(stmts); err != nil {
+		return err
+	}
+
+	if err := checkLoopControl(stmts, false, false); err != nil {
+		return err
+	}
+
+	// everything declared at the top level is visible from function bodies
+	if err := checkSiblingAssignments(stmts, withNames(nil, blockNames(stmts)...), nil); err != nil {
+		return err
+	}
+
 	for _, stmt := range stmts {
 		switch stmt := stmt.(type) {
 
@@ -472,21 +559,43 @@ func (i *Interpreter) EvalProgram(stmts []parser.Statement) (Value, error) {
 }
 
 func (i *Interpreter) EvalStatements(stmts []parser.Statement) (ControlSignal, error) {
-	for _, s := range stmts {
+	var last ControlSignal = SignalNone{}
+
+	for idx, s := range stmts {
 		sig, err := i.EvalStatement(s)
 		if err != nil {
 			return SignalNone{}, err
 		}
 
-		switch sig.(type) {
+		switch v := sig.(type) {
 		case SignalReturn, SignalBreak, SignalContinue:
 			return sig, nil
+		case SignalValue:
+			if i.PrintResults && i.Env.parent == nil {
+				i.printResult(v.Value)
+			}
+
+			// keep the value of a trailing expression so functions can return it
+			if _, ok := s.(*parser.ExpressionStatement); ok && idx == len(stmts)-1 {
+				last = v
+			}
 		}
 
 		i.tickLifetimes()
 	}
 
-	return SignalNone{}, nil
+	return last, nil
+}
+
+func (i *Interpreter) printResult(val Value) {
+	val = UnwrapFully(val)
+	if val == nil {
+		return
+	}
+	if _, isNil := val.(NilValue); isNil {
+		return
+	}
+	fmt.Fprintln(i.output(), i.format(val))
 }
 
 func (i *Interpreter) EvalBlock(stmts []parser.Statement, newScope bool, vars map[string]Value) (ControlSignal, error) {
@@ -512,6 +621,10 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 		return SignalNone{}, nil
 	}
 
+	if err := i.checkLimits(s); err != nil {
+		return SignalNone{}, err
+	}
+
 	switch stmt := s.(type) {
 	case *parser.VarStatement:
 		var val Value
@@ -531,7 +644,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				return SignalNone{}, err
 			}
 
-			vals, err := i.unpackForAssign(stmt, res, 1)
+			vals, err := i.unpackForAssign(stmt, stmt.Value, res, 1)
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -550,7 +663,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			return SignalNone{}, err
 		}
 
-		val, err = i.assignWithType(stmt, val, expectedTI)
+		val, err = i.assignNamed(stmt, stmt.Name.Value, val, expectedTI)
 		if err != nil {
 			return SignalNone{}, err
 		}
@@ -596,19 +709,13 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			return SignalNone{}, err
 		}
 
-		vals, err := i.unpackForAssign(stmt, res, 1)
+		vals, err := i.unpackForAssign(stmt, stmt.Value, res, 1)
 		if err != nil {
 			return SignalNone{}, err
 		}
 
 		val := vals[0]
 
-		if tup, ok := val.(TupleValue); ok {
-			if len(tup.Values) > 1 {
-				return SignalNone{}, NewRuntimeError(stmt, fmt.Sprintf("expected 1 value but got %d", len(tup.Values)))
-			}
-		}
-
 		// variable must not exist
 		if _, ok, _ := i.Env.GetLocal(stmt.Name.Value); ok {
 			return SignalNone{}, NewRuntimeError(stmt, fmt.Sprintf("cant redeclare var: %s", stmt.Name.Value))
@@ -692,7 +799,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				return SignalNone{}, err
 			}
 
-			values, err = i.unpackForAssign(stmt, res, len(stmt.Names))
+			values, err = i.unpackForAssign(stmt, stmt.Values[0], res, len(stmt.Names))
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -704,7 +811,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					return SignalNone{}, err
 				}
 
-				vals, err := i.unpackForAssign(stmt, res, 1)
+				vals, err := i.unpackForAssign(stmt, expr, res, 1)
 				if err != nil {
 					return SignalNone{}, err
 				}
@@ -736,7 +843,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					fmt.Sprintf("cannot redeclare var: %s", name.Value))
 			}
 
-			v, err := i.assignWithType(stmt, values[idx], expectedTI)
+			v, err := i.assignNamed(stmt, name.Value, values[idx], expectedTI)
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -767,7 +874,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				return SignalNone{}, err
 			}
 
-			values, err = i.unpackForAssign(stmt, res, len(stmt.Names))
+			values, err = i.unpackForAssign(stmt, stmt.Values[0], res, len(stmt.Names))
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -779,7 +886,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					return SignalNone{}, err
 				}
 
-				vals, err := i.unpackForAssign(stmt, res, 1)
+				vals, err := i.unpackForAssign(stmt, expr, res, 1)
 				if err != nil {
 					return SignalNone{}, err
 				}
@@ -862,16 +969,17 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 		}
 
 		if stmt.Value != nil {
-			val, err = i.evalOne(stmt.Value)
+			res, err := i.EvalExpression(stmt.Value)
 			if err != nil {
 				return SignalNone{}, err
 			}
 
-			if tup, ok := val.(TupleValue); ok {
-				if len(tup.Values) > 1 {
-					return SignalNone{}, NewRuntimeError(stmt, fmt.Sprintf("expected 1 value but got %d", len(tup.Values)))
-				}
+			vals, err := i.unpackForAssign(stmt, stmt.Value, res, 1)
+			if err != nil {
+				return SignalNone{}, err
 			}
+
+			val = vals[0]
 		} else if expectedTI != nil {
 			val, err = i.defaultValueFromTypeInfo(stmt, expectedTI)
 			if err != nil {
@@ -886,7 +994,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			return SignalNone{}, NewRuntimeError(s, fmt.Sprintf("cant redeclare const: %s", stmt.Name.Value))
 		}
 
-		val, err = i.assignWithType(stmt, val, expectedTI)
+		val, err = i.assignNamed(stmt, stmt.Name.Value, val, expectedTI)
 		if err != nil {
 			return SignalNone{}, err
 		}
@@ -933,7 +1041,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				return SignalNone{}, err
 			}
 
-			values, err = i.unpackForAssign(stmt, res, len(stmt.Names))
+			values, err = i.unpackForAssign(stmt, stmt.Values[0], res, len(stmt.Names))
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -945,7 +1053,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					return SignalNone{}, err
 				}
 
-				vals, err := i.unpackForAssign(stmt, res, 1)
+				vals, err := i.unpackForAssign(stmt, expr, res, 1)
 				if err != nil {
 					return SignalNone{}, err
 				}
@@ -977,7 +1085,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					fmt.Sprintf("cannot redeclare var: %s", name.Value))
 			}
 
-			v, err := i.assignWithType(stmt, values[idx], expectedTI)
+			v, err := i.assignNamed(stmt, name.Value, values[idx], expectedTI)
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -1010,7 +1118,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				return SignalNone{}, err
 			}
 
-			values, err = i.unpackForAssign(stmt, res, len(stmt.Targets))
+			values, err = i.unpackForAssign(stmt, stmt.Values[0], res, len(stmt.Targets))
 			if err != nil {
 				return SignalNone{}, err
 			}
@@ -1022,7 +1130,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					return SignalNone{}, err
 				}
 
-				vals, err := i.unpackForAssign(stmt, res, 1)
+				vals, err := i.unpackForAssign(stmt, expr, res, 1)
 				if err != nil {
 					return SignalNone{}, err
 				}
@@ -1039,6 +1147,9 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 		for _, expr := range stmt.Targets {
 			t, err := i.resolveAssignableTarget(expr)
 			if err != nil {
+				if _, ok := err.(RuntimeError); !ok {
+					err = NewRuntimeError(expr, err.Error())
+				}
 				return SignalNone{}, err
 			}
 			targets = append(targets, t)
@@ -1048,7 +1159,7 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			if op, ok := compoundOps[stmt.Op]; ok {
 				cur, err := targets[idx].Get(i)
 				if err != nil {
-					return SignalNone{}, err
+					return SignalNone{}, NewRuntimeError(stmt.Targets[idx], err.Error())
 				}
 
 				res, err := i.evalInfix(
@@ -1067,6 +1178,9 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				}
 
 				err = targets[idx].Set(i, res)
+				if err != nil {
+					return SignalNone{}, NewRuntimeError(stmt.Targets[idx], err.Error())
+				}
 			} else {
 				err := targets[idx].Set(i, copyValue(values[idx]))
 				if err != nil {
@@ -1088,6 +1202,13 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			values = append(values, v)
 		}
 
+		// give f() or give (a, b) hands on every value of the tuple
+		if len(values) == 1 {
+			if tup, ok := values[0].(TupleValue); ok {
+				values = tup.Values
+			}
+		}
+
 		return SignalReturn{Values: values}, nil
 
 	case *parser.ExpressionStatement:
@@ -1128,23 +1249,30 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 	case *parser.StartStatement:
 		i.Wg.Add(1)
 
-		go func(parent *Interpreter) {
-			defer i.Wg.Done()
+		// clone before the goroutine runs, so it never reads the parent's state while the parent changes it
+		sub := i.Clone()
 
-			sub := parent.Clone()
+		go func() {
+			defer i.Wg.Done()
 
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Println("panic in start:", r)
+					fmt.Fprintln(sub.output(), "panic in start:", r)
 				}
 			}()
 
+			var err error
 			if stmt.Body != nil {
-				sub.EvalBlock(stmt.Body, true, nil)
+				_, err = sub.EvalBlock(stmt.Body, true, nil)
 			} else if stmt.Expr != nil {
-				sub.EvalExpression(stmt.Expr)
+				_, err = sub.EvalExpression(stmt.Expr)
+			}
+
+			// nothing is waiting on the result, so report it here instead of dropping it
+			if err != nil {
+				fmt.Fprintln(sub.output(), "error in start:", err)
 			}
-		}(i)
+		}()
 
 		return SignalNone{}, nil
 
@@ -1227,8 +1355,8 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			}
 		}
 
-		switchVal = UnwrapFully(switchVal)
-
+		// the first when that matches runs and nothing falls through, snap leaves the choose early
+		// while next and give carry on out to the loop or function around it
 		for _, c := range stmt.Cases {
 			matched := false
 			for _, expr := range c.Exprs {
@@ -1237,9 +1365,18 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 					return SignalNone{}, err
 				}
 
-				caseVal = UnwrapFully(caseVal)
+				// a when matches the same way == would
+				eq, err := i.evalInfix(&parser.InfixExpression{
+					NodeBase: c.NodeBase,
+					Left:     stmt.Value,
+					Right:    expr,
+					Operator: "==",
+				}, switchVal, "==", caseVal)
+				if err != nil {
+					return SignalNone{}, err
+				}
 
-				if valuesEqual(switchVal, caseVal) {
+				if b, ok := eq.(BoolValue); ok && b.V {
 					matched = true
 					break
 				}
@@ -1249,26 +1386,59 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				continue
 			}
 
-			sig, err := i.EvalBlock(c.Body, true, nil)
-			if err != nil {
-				return SignalNone{}, err
+			return chooseSignal(i.EvalBlock(c.Body, true, nil))
+		}
+
+		if stmt.Default != nil {
+			return chooseSignal(i.EvalBlock(stmt.Default.Body, true, nil))
+		}
+
+		return SignalNone{}, nil
+
+	case *parser.MatchStatement:
+		val, err := i.evalOne(stmt.Value)
+		if err != nil {
+			return SignalNone{}, err
+		}
+
+		v, ok := UnwrapFully(val).(VariantValue)
+		if !ok {
+			return SignalNone{}, NewRuntimeError(stmt, fmt.Sprintf("match expects a variant, got %s", UnwrapFully(val).Type()))
+		}
+
+		body := []parser.Statement(nil)
+		vars := map[string]Value{}
+		matched := false
+
+		for _, arm := range stmt.Arms {
+			if arm.Tag.Value != v.Tag {
+				continue
 			}
 
-			if _, ok := sig.(SignalNone); !ok {
-				return sig, nil
+			if arm.Binding != nil && arm.Binding.Value != "_" {
+				vars[arm.Binding.Value] = v.Payload
 			}
 
-			return SignalNone{}, nil
+			body = arm.Body
+			matched = true
+			break
 		}
 
-		if stmt.Default != nil {
-			sig, err := i.EvalBlock(stmt.Default.Body, true, nil)
-			if err != nil {
-				return SignalNone{}, err
-			}
-			if _, ok := sig.(SignalNone); !ok {
-				return sig, nil
+		if !matched {
+			if stmt.Default == nil {
+				return SignalNone{}, NewRuntimeError(stmt, fmt.Sprintf("no match arm for variant '%s'", v.Tag))
 			}
+
+			body = stmt.Default.Body
+		}
+
+		sig, err := i.EvalBlock(body, true, vars)
+		if err != nil {
+			return SignalNone{}, err
+		}
+
+		if _, ok := sig.(SignalNone); !ok {
+			return sig, nil
 		}
 
 		return SignalNone{}, nil
@@ -1301,6 +1471,10 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 		}
 
 		for {
+			if err := i.checkLimits(stmt); err != nil {
+				return SignalNone{}, err
+			}
+
 			i.Env = loopEnv
 			cond, err := i.evalOne(stmt.Condition)
 			if err != nil {
@@ -1354,6 +1528,10 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 		iterable = UnwrapFully(iterable)
 
 		runIteration := func(setVars func()) (ControlSignal, error) {
+			if err := i.checkLimits(stmt); err != nil {
+				return SignalNone{}, err
+			}
+
 			oldEnv := i.Env
 			env := NewEnvironment(oldEnv)
 			i.Env = env
@@ -1417,7 +1595,8 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 				}
 			}
 		case StringValue:
-			for idx, s := range v.V {
+			// count characters like indexing does, not bytes
+			for idx, s := range []rune(v.V) {
 				sig, err := runIteration(func() {
 					if stmt.Key != nil && stmt.Key.Value != "_" {
 						i.Env.Define(stmt.Key.Value, IntValue{V: idx}, false)
@@ -1443,6 +1622,10 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 			}
 		case IntValue:
 			for idx := range v.V {
+				if err := i.checkLimits(stmt); err != nil {
+					return SignalNone{}, err
+				}
+
 				oldEnv := i.Env
 				i.Env = NewEnvironment(oldEnv)
 
@@ -1479,6 +1662,10 @@ func (i *Interpreter) EvalStatement(s parser.Statement) (ControlSignal, error) {
 
 	case *parser.WhileStatement:
 		for {
+			if err := i.checkLimits(stmt); err != nil {
+				return SignalNone{}, err
+			}
+
 			cond, err := i.evalOne(stmt.Condition)
 			if err != nil {
 				return SignalNone{}, err
@@ -1569,6 +1756,9 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 		}
 
 		val, err := i.evalMemberExpression(expr, left, expr.Field.Value)
+		if err != nil {
+			return EvalResult{[]Value{NilValue{}}, nil}, err
+		}
 
 		return EvalResult{[]Value{val}, nil}, nil
 
@@ -1595,6 +1785,9 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 		}
 
 		val, err := i.evalCompositeLiteral(expr, ti)
+		if err != nil {
+			return EvalResult{[]Value{NilValue{}}, nil}, err
+		}
 
 		return EvalResult{[]Value{val}, nil}, nil
 
@@ -1669,6 +1862,11 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 			return EvalResult{[]Value{NilValue{}}, nil}, err
 		}
 
+		// the ok result of a map lookup is only kept for v, ok := m[k]
+		if !expr.ExpectOk && len(val.Values) > 1 {
+			return EvalResult{val.Values[:1], nil}, nil
+		}
+
 		return EvalResult{val.Values, nil}, nil
 
 	case *parser.SliceExpression:
@@ -1677,14 +1875,21 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 			return EvalResult{[]Value{NilValue{}}, nil}, err
 		}
 
-		start, err := i.evalOne(expr.Start)
-		if err != nil {
-			return EvalResult{[]Value{NilValue{}}, nil}, err
+		// a missing bound is nil, and defaults to the start or end
+		var start, end Value = NilValue{}, NilValue{}
+
+		if expr.Start != nil {
+			start, err = i.evalOne(expr.Start)
+			if err != nil {
+				return EvalResult{[]Value{NilValue{}}, nil}, err
+			}
 		}
 
-		end, err := i.evalOne(expr.End)
-		if err != nil {
-			return EvalResult{[]Value{NilValue{}}, nil}, err
+		if expr.Stop != nil {
+			end, err = i.evalOne(expr.Stop)
+			if err != nil {
+				return EvalResult{[]Value{NilValue{}}, nil}, err
+			}
 		}
 
 		val, err := i.evalSliceExpression(expr, left, start, end)
@@ -1804,6 +2009,36 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 
 		return EvalResult{[]Value{val}, nil}, nil
 
+	case *parser.TupleLiteral:
+		values := make([]Value, len(expr.Elements))
+		for idx, el := range expr.Elements {
+			v, err := i.evalOne(el)
+			if err != nil {
+				return EvalResult{[]Value{NilValue{}}, nil}, err
+			}
+
+			values[idx] = v
+		}
+
+		return EvalResult{[]Value{TupleValue{Values: values}}, nil}, nil
+
+	case *parser.ConditionalExpression:
+		cond, err := i.evalOne(expr.Condition)
+		if err != nil {
+			return EvalResult{[]Value{NilValue{}}, nil}, err
+		}
+
+		truthy, err := isTruthy(cond)
+		if err != nil {
+			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(expr.Condition, err.Error())
+		}
+
+		if truthy {
+			return i.EvalExpression(expr.Consequence)
+		}
+
+		return i.EvalExpression(expr.Alternative)
+
 	case *parser.InfixExpression:
 		if expr.Operator == "&&" {
 			left, err := i.evalOne(expr.Left)
@@ -1907,7 +2142,7 @@ func (i *Interpreter) EvalExpression(e parser.Expression) (EvalResult, error) {
 			if err != nil {
 				return EvalResult{[]Value{NilValue{}}, nil}, err
 			}
-			out.WriteString(val.String())
+			out.WriteString(i.format(val))
 		}
 
 		return EvalResult{[]Value{StringValue{out.String()}}, nil}, nil
@@ -2180,6 +2415,10 @@ func (i *Interpreter) evalMapLiteral(expr *parser.CompositeLiteral, expected *Ty
 			return NilValue{}, err
 		}
 
+		if _, ok := elems[MapKey(k)]; ok {
+			return NilValue{}, NewRuntimeError(e.Key, fmt.Sprintf("duplicate key %s in map literal", k.String()))
+		}
+
 		elems[MapKey(k)] = v
 		keys[MapKey(k)] = k
 	}
@@ -2221,7 +2460,9 @@ func (i *Interpreter) runCase(c *parser.SelectCaseClause, recvVal Value) (Contro
 
 func (i *Interpreter) evalCall(e *parser.FuncCall) (Value, error) {
 	if ident, ok := e.Callee.(*parser.Identifier); ok {
-		if ti, ok := i.TypeEnv[ident.Value]; ok {
+		// a builtin sharing its name with a type, like variant, is a call not a cast
+		_, isBuiltin := i.Env.builtins[ident.Value]
+		if ti, ok := i.TypeEnv[ident.Value]; ok && !isBuiltin {
 			if len(e.Args) != 1 {
 				return NilValue{}, NewRuntimeError(e, "type cast expects 1 arg")
 			}
@@ -2278,6 +2519,8 @@ func (i *Interpreter) evalTypeCast(target *TypeInfo, arg parser.Expression, node
 		switch v := v.(type) {
 		case StringValue:
 			val = v.V
+		case BoolValue:
+			val = i.format(v)
 		default:
 			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("cannot cast '%s' to '%s'", i.TypeInfoFromValue(v).Name, target.Name))
 		}
@@ -2430,6 +2673,8 @@ func (i *Interpreter) evalTypeCastValue(target *TypeInfo, val Value, node parser
 		switch v := v.(type) {
 		case StringValue:
 			val = v.V
+		case BoolValue:
+			val = i.format(v)
 		case EnumValue:
 			ev, err := extractEnumValue(node, v, TypeInt)
 			if err != nil {
@@ -2585,9 +2830,10 @@ func (i *Interpreter) evalArgs(args []parser.Expression) ([]Value, error) {
 }
 
 func (i *Interpreter) evalFuncCall(expr *parser.FuncCall) (Value, error) {
-	// builtin
+	// builtin, unless a variable or function in scope shadows it
 	if ident, ok := expr.Callee.(*parser.Identifier); ok {
-		if b, ok := i.Env.builtins[ident.Value]; ok {
+		_, shadowed, _ := i.Env.Get(ident.Value)
+		if b, ok := i.Env.builtins[ident.Value]; ok && !shadowed {
 			args, err := i.evalArgs(expr.Args)
 			if err != nil {
 				return NilValue{}, err
@@ -2596,6 +2842,9 @@ func (i *Interpreter) evalFuncCall(expr *parser.FuncCall) (Value, error) {
 				return NilValue{}, NewRuntimeError(expr,
 					fmt.Sprintf("expected %d args, got %d", b.Arity, len(args)))
 			}
+			if err := i.checkPure(expr, b); err != nil {
+				return NilValue{}, err
+			}
 			return b.Fn(i, expr, args)
 		}
 	}
@@ -2611,11 +2860,18 @@ func (i *Interpreter) evalFuncCall(expr *parser.FuncCall) (Value, error) {
 		return NilValue{}, err
 	}
 
-	switch fn := val.(type) {
+	return i.callValue(val, args, expr)
+}
+
+func (i *Interpreter) callValue(val Value, args []Value, expr *parser.FuncCall) (Value, error) {
+	switch fn := UnwrapFully(val).(type) {
 	case *BuiltinFunc:
 		if fn.Arity >= 0 && len(args) != fn.Arity {
 			return NilValue{}, NewRuntimeError(expr, fmt.Sprintf("expected %d args, got %d", fn.Arity, len(args)))
 		}
+		if err := i.checkPure(expr, fn); err != nil {
+			return NilValue{}, err
+		}
 		return fn.Fn(i, expr, args)
 	case *Func:
 		return i.callFunction(fn, args, expr)
@@ -2647,6 +2903,13 @@ func (i *Interpreter) callFunction(fn *Func, args []Value, callNode parser.Node)
 		}
 	}
 
+	if i.MaxCallDepth > 0 && i.depth >= i.MaxCallDepth {
+		return NilValue{}, NewRuntimeError(callNode, fmt.Sprintf("stack overflow: exceeded max call depth of %d", i.MaxCallDepth))
+	}
+
+	i.depth++
+	defer func() { i.depth-- }()
+
 	newEnv := NewEnvironment(fn.Env)
 
 	fixedCount := paramCount
@@ -2735,6 +2998,16 @@ func (i *Interpreter) callFunction(fn *Func, args []Value, callNode parser.Node)
 		return NilValue{}, deferErr
 	}
 
+	// a trailing expression is the result when nothing was given explicitly
+	if v, ok := sig.(SignalValue); ok && len(fn.TypeName.Returns) > 0 {
+		values := []Value{v.Value}
+		if tuple, ok := v.Value.(TupleValue); ok {
+			values = tuple.Values
+		}
+
+		sig = SignalReturn{Values: values}
+	}
+
 	// handle return
 	if ret, ok := sig.(SignalReturn); ok {
 		if len(fn.TypeName.Returns) > 0 && len(fn.TypeName.Returns) != len(ret.Values) {
@@ -2797,10 +3070,9 @@ func (i *Interpreter) evalIndexExpression(node parser.Expression, left, idx Valu
 			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, "index must be int")
 		}
 
-		idx := idxVal.V
-
-		if idx < 0 || idx >= len(arr.Elements) {
-			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, fmt.Sprintf("index: %d, out of bounds", idx))
+		idx, inBounds := resolveIndex(idxVal.V, len(arr.Elements))
+		if !inBounds {
+			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, indexOutOfBounds(idxVal.V, len(arr.Elements)))
 		}
 
 		elem := arr.Elements[idx]
@@ -2825,13 +3097,13 @@ func (i *Interpreter) evalIndexExpression(node parser.Expression, left, idx Valu
 			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, "index must be int")
 		}
 
-		idx := idxVal.V
+		r := []rune(left.(StringValue).V)
 
-		if idx < 0 || idx >= len(left.(StringValue).V) {
-			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, fmt.Sprintf("index: %d, out of bounds", idx))
+		idx, inBounds := resolveIndex(idxVal.V, len(r))
+		if !inBounds {
+			return EvalResult{[]Value{NilValue{}}, nil}, NewRuntimeError(node, indexOutOfBounds(idxVal.V, len(r)))
 		}
 
-		r := []rune(left.(StringValue).V)
 		return EvalResult{[]Value{StringValue{V: string(r[idx])}}, nil}, nil
 
 	case TypeMap:
@@ -2949,20 +3221,37 @@ func (i *Interpreter) evalSliceExpression(node parser.Expression, left, startVal
 		end = intVal.V
 	}
 
-	if start < 0 || end < 0 || start > end || end > length {
-		return NilValue{}, NewRuntimeError(node,
-			fmt.Sprintf("slice bounds out of range [%d:%d]", start, end))
+	// negative bounds count back from the end like indexes do
+	reqStart, reqEnd := start, end
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+
+	if start < 0 || start > end || end > length {
+		msg := fmt.Sprintf("slice bounds out of range [%d:%d] with length %d", reqStart, reqEnd, length)
+		if reqStart < 0 || reqEnd < 0 {
+			msg += fmt.Sprintf(", they resolve to [%d:%d]", start, end)
+		}
+
+		return NilValue{}, NewRuntimeError(node, msg)
 	}
 
 	switch typ.Kind {
 
 	case TypeArray, TypeFixedArray:
 		arr := left.(ArrayValue)
-		newElems := arr.Elements[start:end]
+
+		// copy so pushing onto the slice never writes into the original
+		newElems := make([]Value, end-start)
+		copy(newElems, arr.Elements[start:end])
 
 		return ArrayValue{
 			Elements: newElems,
 			ElemType: arr.ElemType,
+			Capacity: len(newElems),
 		}, nil
 
 	case TypeString:
@@ -3056,11 +3345,11 @@ func (i *Interpreter) evalMemberExpression(node parser.Expression, left Value, f
 		}
 		val, ok := obj.Fields[field]
 		if !ok {
-			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("unknown field %s", field))
+			return NilValue{}, NewRuntimeError(node, noField(field, obj.TypeName))
 		}
 		expectedType, ok := structTI.Fields[field]
 		if !ok {
-			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("unknown field %s", field))
+			return NilValue{}, NewRuntimeError(node, noField(field, obj.TypeName))
 		}
 		// skip type check if type info is missing
 		actualTI := UnwrapAlias(i.TypeInfoFromValue(val))
@@ -3074,6 +3363,19 @@ func (i *Interpreter) evalMemberExpression(node parser.Expression, left Value, f
 		}
 		return val, nil
 
+	// a map with string keys can be read like a struct, m.name is m["name"]
+	case MapValue:
+		if UnwrapAlias(obj.KeyType).Kind != TypeString {
+			return NilValue{}, NewRuntimeError(node,
+				fmt.Sprintf("only maps with string keys can use '.', got '%s'", obj.KeyType.Name))
+		}
+
+		val, ok := obj.Entries[MapKey(StringValue{V: field})]
+		if !ok {
+			return NilValue{}, NewRuntimeError(node, noField(field, i.TypeInfoFromValue(obj)))
+		}
+		return val, nil
+
 	case TypeValue:
 		if obj.TypeInfo.Kind != TypeEnum {
 			return NilValue{}, NewRuntimeError(node,
@@ -3094,7 +3396,7 @@ func (i *Interpreter) evalMemberExpression(node parser.Expression, left Value, f
 	}
 
 	return NilValue{}, NewRuntimeError(node,
-		fmt.Sprintf("member expression expects enums or structs, but got '%s'",
+		fmt.Sprintf("member expression expects enums, structs or maps, but got '%s'",
 			i.TypeInfoFromValue(left).Name))
 }
 
@@ -3255,6 +3557,9 @@ func (i *Interpreter) evalInfix(node *parser.InfixExpression, left Value, op str
 	case ENUM:
 		return evalEnumInfix(node, left.(EnumValue), op, right.(EnumValue))
 
+	case VARIANT:
+		return evalVariantInfix(node, left.(VariantValue), op, right.(VariantValue))
+
 	case POINTER:
 		return evalPointerInfix(node, left.(*PointerValue), op, right.(*PointerValue))
 
@@ -3274,6 +3579,18 @@ func (i *Interpreter) evalInfix(node *parser.InfixExpression, left Value, op str
 	)
 }
 
+func intPow(base, exp int) int {
+	result := 1
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
 func evalIntInfix(node *parser.InfixExpression, left IntValue, op string, right IntValue) (Value, error) {
 	switch op {
 	case "+":
@@ -3295,13 +3612,27 @@ func evalIntInfix(node *parser.InfixExpression, left IntValue, op string, right
 		}
 
 		return IntValue{V: left.V % right.V}, nil
+	case "**":
+		if right.V < 0 {
+			return FloatValue{V: math.Pow(float64(left.V), float64(right.V))}, nil
+		}
+
+		return IntValue{V: intPow(left.V, right.V)}, nil
 	case "|":
 		return IntValue{V: left.V | right.V}, nil
 	case "&":
 		return IntValue{V: left.V & right.V}, nil
 	case ">>":
+		if right.V < 0 {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("negative shift count %d", right.V))
+		}
+
 		return IntValue{V: left.V >> right.V}, nil
 	case "<<":
+		if right.V < 0 {
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("negative shift count %d", right.V))
+		}
+
 		return IntValue{V: left.V << right.V}, nil
 	case "^":
 		return IntValue{V: left.V ^ right.V}, nil
@@ -3336,6 +3667,10 @@ func evalFloatInfix(node *parser.InfixExpression, left FloatValue, op string, ri
 		}
 
 		return FloatValue{V: left.V / right.V}, nil
+	case "**":
+		return FloatValue{V: math.Pow(left.V, right.V)}, nil
+	case "&", "|", "^", "<<", ">>":
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("operator %s only works on ints, got float", op))
 	case "==":
 		return BoolValue{V: left.V == right.V}, nil
 	case "!=":
@@ -3361,6 +3696,14 @@ func evalStringInfix(node *parser.InfixExpression, left StringValue, op string,
 		return BoolValue{V: left.V == right.V}, nil
 	case "!=":
 		return BoolValue{V: left.V != right.V}, nil
+	case "<":
+		return BoolValue{V: left.V < right.V}, nil
+	case ">":
+		return BoolValue{V: left.V > right.V}, nil
+	case "<=":
+		return BoolValue{V: left.V <= right.V}, nil
+	case ">=":
+		return BoolValue{V: left.V >= right.V}, nil
 	}
 
 	return NilValue{}, NewRuntimeError(node, fmt.Sprintf("invalid operator %s %s %s", left.V, op, right.V))
@@ -3386,7 +3729,7 @@ func evalNilInfix(node *parser.InfixExpression, op string, other Value) (Value,
 		_, isNil := other.(NilValue)
 		return BoolValue{V: !isNil}, nil
 	default:
-		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("invalid operator nil %s %s", op, other.String()))
+		return NilValue{}, NewRuntimeError(node, fmt.Sprintf("invalid operator '%s' with nil, only == and != can compare against nil", op))
 	}
 }
 
@@ -3429,6 +3772,20 @@ func evalEnumInfix(node *parser.InfixExpression, left EnumValue, op string, righ
 	}
 }
 
+func evalVariantInfix(node *parser.InfixExpression, left VariantValue, op string, right VariantValue) (Value, error) {
+	switch op {
+	case "==":
+		return BoolValue{V: valuesEqual(left, right)}, nil
+	case "!=":
+		return BoolValue{V: !valuesEqual(left, right)}, nil
+	default:
+		return NilValue{}, NewRuntimeError(
+			node,
+			fmt.Sprintf("invalid operator: variant %s variant", op),
+		)
+	}
+}
+
 func evalPointerInfix(node *parser.InfixExpression, left Value, op string, right Value) (Value, error) {
 	switch op {
 	case "==":
@@ -3665,7 +4022,16 @@ func (i *Interpreter) evalPostfix(node *parser.PostfixExpression, left Value, op
 			return NilValue{}, NewRuntimeError(node, err.Error())
 		}
 
-		one := IntValue{V: 1}
+		switch UnwrapFully(cur).(type) {
+		case IntValue, FloatValue:
+		default:
+			return NilValue{}, NewRuntimeError(node, fmt.Sprintf("invalid operation: %s on non-numeric type %s", op, i.TypeInfoFromValue(cur).Name))
+		}
+
+		var one Value = IntValue{V: 1}
+		if nv, ok := cur.(NamedValue); ok {
+			one = NamedValue{TypeName: nv.TypeName, Value: one}
+		}
 
 		var infixOp string
 		if op == "++" {
@@ -3702,7 +4068,13 @@ func isTruthy(val Value) (bool, error) {
 	val = UnwrapFully(val)
 	b, ok := val.(BoolValue)
 	if !ok {
-		return false, fmt.Errorf("condition must be boolean")
+		// nil is not false either, compare it with == nil instead
+		got := NIL
+		if val != nil {
+			got = val.Type()
+		}
+
+		return false, fmt.Errorf("condition must be boolean, got %s", got)
 	}
 	return b.V, nil
 }
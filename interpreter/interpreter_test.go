@@ -0,0 +1,289 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+	"github.com/z-sk1/ayla-lang/parser"
+)
+
+// parse parses src and fails the test on a syntax error
+func parse(t testing.TB, src string) []parser.Statement {
+	t.Helper()
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse error: %v", errs[0])
+	}
+
+	return program.Statements
+}
+
+// runIn runs src on i the same way ayla run does and waits for anything it started
+func runIn(t testing.TB, i *Interpreter, src string) error {
+	t.Helper()
+
+	return runStatements(i, parse(t, src))
+}
+
+func runStatements(i *Interpreter, stmts []parser.Statement) error {
+	if err := i.RegisterForward(stmts); err != nil {
+		return err
+	}
+
+	if err := i.ResolveTypes(stmts); err != nil {
+		return err
+	}
+
+	if err := i.TypeCheck(stmts); err != nil {
+		return err
+	}
+
+	_, err := i.EvalStatements(stmts)
+	i.Wg.Wait()
+
+	return err
+}
+
+// run runs src with stdin as its input and gives back what it printed
+func run(t *testing.T, src, stdin string) (string, error) {
+	t.Helper()
+
+	var out strings.Builder
+
+	i := New("test.ayla")
+	i.Stdin = strings.NewReader(stdin)
+	i.Stdout = &out
+
+	err := runIn(t, i, src)
+	return out.String(), err
+}
+
+// scriptTest is a program and what it should print, or the error it should stop with
+type scriptTest struct {
+	src     string
+	want    string
+	wantErr string
+}
+
+// runScripts runs every test with no input and checks what it printed or the error it gave
+func runScripts(t *testing.T, tests []scriptTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		out, err := run(t, tt.src, "")
+
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.src, tt.wantErr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+
+		if out != tt.want {
+			t.Errorf("%s: printed %q, want %q", tt.src, out, tt.want)
+		}
+	}
+}
+
+func TestStatementCallToUndefinedFunctionStops(t *testing.T) {
+	out, err := run(t, "putln(\"before\")\nmissing()\nputln(\"after\")\n", "")
+	if err == nil {
+		t.Fatal("expected an error from calling an undefined function")
+	}
+
+	if out != "before\n" {
+		t.Errorf("expected the program to stop at the bad call, printed %q", out)
+	}
+}
+
+func TestStartErrorsGoToStdout(t *testing.T) {
+	out, err := run(t, "start {\n    missing()\n}\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(out, "error in start:") {
+		t.Errorf("expected the error to be written to Stdout, got %q", out)
+	}
+}
+
+func TestStartBlocksShareStdout(t *testing.T) {
+	src := "for n := range 8 {\n    start {\n        for k := range 50 {\n            putln(\"line\", n, k)\n        }\n        missing()\n    }\n}\n"
+
+	out, err := run(t, src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, errors := 0, 0
+	for _, line := range strings.Split(out, "\n") {
+		var n, k int
+		if _, err := fmt.Sscanf(line, "line %d %d", &n, &k); err == nil {
+			lines++
+		}
+
+		if strings.HasPrefix(line, "error in start:") {
+			errors++
+		}
+	}
+
+	if lines != 8*50 || errors != 8 {
+		t.Errorf("expected 400 whole lines and 8 errors, got %d and %d in\n%s", lines, errors, out)
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"each arithmetic statement", "1 + 2\n3 * 4\n10 / 4\n", "3\n12\n2\n"},
+		{"floats and strings", "1.5 * 2\n\"a\" + \"b\"\n", "3\nab\n"},
+		{"declarations print nothing", "say x = 5\nx * 2\n", "10\n"},
+		{"nil results are skipped", "putln(\"hi\")\n1 + 1\n", "hi\n2\n"},
+		{"only top level", "fun f() (int) {\n    1 + 1\n    give 3\n}\nf()\n", "3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+
+			i := New("test.ayla")
+			i.Stdout = &out
+			i.PrintResults = true
+
+			if err := runIn(t, i, tt.src); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("printed %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	// without PrintResults the results are dropped
+	out, err := run(t, "1 + 2\n", "")
+	if err != nil || out != "" {
+		t.Errorf("printed %q with error %v, want nothing", out, err)
+	}
+}
+
+func TestImplicitReturn(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "fun sq(n int) (int) {\n    n * n\n}\nputln(sq(4))\n", want: "16\n"},
+		{src: "f := fun(a int) (int) { a + 1 }\nputln(f(1))\n", want: "2\n"},
+		{src: "fun name() (string) {\n    say s = \"ay\"\n    s + \"la\"\n}\nputln(name())\n", want: "ayla\n"},
+		// an explicit give wins over the trailing expression
+		{src: "fun early() (int) {\n    give 5\n    7\n}\nputln(early())\n", want: "5\n"},
+		{src: "fun pick(n int) (int) {\n    ayla n > 0 {\n        give 1\n    }\n    -1\n}\nputln(pick(2), pick(-2))\n", want: "1 -1\n"},
+		// only the last statement counts, putln gives nothing back for an int
+		{src: "fun first() (int) {\n    1\n    putln(\"last\")\n}\nputln(first())\n", wantErr: "type mismatch: expected 'int' but got 'nil'"},
+		{src: "fun nothing() {\n    3\n}\nputln(nothing())\n", want: "nil\n"},
+	})
+}
+
+func TestForClausesRun(t *testing.T) {
+	const funcs = "say calls = 0\nsay n = 0\nfun setup() {\n    calls++\n}\nfun done() (int) {\n    give 3\n}\nfun step() {\n    n++\n}\n"
+
+	runScripts(t, []scriptTest{
+		// setup runs once, the condition and step every time around
+		{src: funcs + "for setup(); n < done(); step() {\n    put(n)\n}\nputln(\"\", calls)\n", want: "012 1\n"},
+		{src: "say n = 0\nfor ; n < 3; n++ {\n    put(n)\n}\nputln()\n", want: "012\n"},
+		{src: "for i := 0; i < 3; {\n    put(i)\n    i += 2\n}\nputln()\n", want: "02\n"},
+		{src: "say n = 0\nfor ; n < 3; {\n    n++\n}\nputln(n)\n", want: "3\n"},
+		{src: "say a = 0\nsay b = 0\nfor a, b = 0, 1; a < 9; a, b = b, a + b {\n    put(a, \",\")\n}\nputln()\n", want: "0,1,1,2,3,5,8,\n"},
+		{src: "for k, v := range []int{4, 5} {\n    put(k, v, \";\")\n}\nputln()\n", want: "04;15;\n"},
+	})
+}
+
+func TestConditionalExpression(t *testing.T) {
+	const boom = "fun boom() (int) {\n    explode(\"taken\")\n    give 0\n}\n"
+
+	runScripts(t, []scriptTest{
+		{src: "say a = 3\nsay b = 5\nputln(a > b ? a : b, a < b ? a : b)\n", want: "5 3\n"},
+		// only the side that is picked runs
+		{src: boom + "putln(yes ? 1 : boom(), no ? boom() : 2)\n", want: "1 2\n"},
+		{src: boom + "putln(yes ? boom() : 1)\n", wantErr: "taken"},
+		// nested on either side, grouping from the right
+		{src: "say a = 3\nputln(a > 1 ? a > 2 ? \"big\" : \"mid\" : \"small\")\n", want: "big\n"},
+		{src: "fun sign(n int) (string) {\n    give n < 0 ? \"neg\" : n == 0 ? \"zero\" : \"pos\"\n}\nputln(sign(-2), sign(0), sign(4))\n", want: "neg zero pos\n"},
+		{src: "putln(1 ? 2 : 3)\n", wantErr: "runtime error at 1:7: condition must be boolean, got int"},
+	})
+}
+
+func TestSpreadForwarding(t *testing.T) {
+	const sum = "fun sum(nums ...int) (int) {\n    say total = 0\n    for _, n := range nums {\n        total += n\n    }\n    give total\n}\n"
+
+	runScripts(t, []scriptTest{
+		{src: sum + "say xs = []int{1, 2, 3}\nputln(sum(xs...), sum(), sum(4, 5))\n", want: "6 0 9\n"},
+		// a variadic function handing its arguments on to another
+		{src: sum + "fun fwd(nums ...int) (int) {\n    give sum(nums...)\n}\nputln(fwd(1, 2), fwd())\n", want: "3 0\n"},
+		{src: sum + "fun label(name string, nums ...int) (string) {\n    give sputf(\"%s=%d\", name, sum(nums...))\n}\nputln(label(\"a\", []int{4, 5}...), label(\"b\"))\n", want: "a=9 b=0\n"},
+		// the spread elements go after the ones written out
+		{src: sum + "say xs = []int{2, 3}\nputln(sum(1, xs...))\n", want: "6\n"},
+		{src: sum + "say s = []string{\"a\"}\nsum(s...)\n", wantErr: "variadic param 'nums' expected 'int' but got 'string'"},
+		{src: "fun one(a int) (int) {\n    give a\n}\nsay xs = []int{1, 2}\none(xs...)\n", wantErr: "expected 1 args, got 2"},
+	})
+}
+
+func TestCallArgumentsOnSeparateLines(t *testing.T) {
+	const add3 = "fun add3(a int, b int, c int) (int) {\n    give a + b + c\n}\n"
+
+	runScripts(t, []scriptTest{
+		{src: add3 + "putln(add3(\n    1,\n    2,\n    3\n))\n", want: "6\n"},
+		{src: add3 + "putln(add3(\n    1,\n    2,\n    3,\n))\n", want: "6\n"},
+		{src: add3 + "putln(add3(1, 2, 3,))\n", want: "6\n"},
+		{src: add3 + "putln(add3(1,\n    2, 3))\n", want: "6\n"},
+		{src: add3 + "putln(add3(\n    1, // first\n    // the middle one\n    2,\n    /* last */ 3\n))\n", want: "6\n"},
+		{src: add3 + "putln(\n    add3(\n        1, 2,\n        3,\n    ),\n    \"done\",\n)\n", want: "6 done\n"},
+		{src: "putln(\n)\n", want: "\n"},
+	})
+}
+
+// TestCharLiterals covers single quoted literals, which are one character strings
+func TestCharLiterals(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "putln('a', typeof('a'), 'é')\n", want: "a string é\n"},
+		{src: "s := \"abc\"\nayla s[0] == 'a' {\n    putln(\"first\")\n}\n", want: "first\n"},
+		{src: "putln('a' == \"a\", 'a' != 'b', 'a' < 'b', 'z' > 'a', 'B' < 'a')\n", want: "yes yes yes yes yes\n"},
+		{src: "putln('\\t' == \"\\t\", '\\'', '\\\\', ord('a'))\n", want: "yes ' \\ 97\n"},
+		{src: "putln('a' + 1)\n", wantErr: "type mismatch: 'string' + 'int'"},
+	})
+}
+
+// TestMemberAccess covers reading and writing through dots, chained and mixed with indexing
+func TestMemberAccess(t *testing.T) {
+	const types = "type Point struct {\n    x int\n    y int\n}\ntype Line struct {\n    a Point\n    b Point\n}\n"
+	const line = "l := Line{a: Point{x: 1, y: 2}, b: Point{x: 3, y: 4}}\n"
+
+	runScripts(t, []scriptTest{
+		{src: types + line + "putln(l.a.x, l.b.y, l.a.x + l.b.x * 2)\n", want: "1 4 7\n"},
+		{src: types + line + "l.a.x = 7\nl.b.y += 1\nputln(l.a.x, l.b.y)\n", want: "7 5\n"},
+
+		// writing through an index changes the element in place
+		{src: types + "points := []Point{Point{x: 1, y: 2}, Point{x: 3, y: 4}}\npoints[0].x = 9\nputln(points[0].x, points[1].x)\n", want: "9 3\n"},
+		{src: types + "ls := []Line{Line{a: Point{x: 1, y: 2}, b: Point{x: 3, y: 4}}}\nls[0].a.y = 8\nputln(ls[0].a.y)\n", want: "8\n"},
+
+		// a struct is copied on assignment
+		{src: types + "p := Point{x: 1, y: 2}\nq := p\nq.x = 5\nputln(p.x, q.x)\n", want: "1 5\n"},
+
+		// maps take string keys through dots too
+		{src: "m := map[string]int{\"x\": 1}\nm.y = 2\nputln(m.x, m[\"y\"], len(m))\n", want: "1 2 2\n"},
+
+		{src: types + "p := Point{x: 1, y: 2}\nputln(p.z)\n", wantErr: "runtime error at 10:7: no field named 'z' in struct 'Point'"},
+		{src: types + "p := Point{x: 1, y: 2}\np.z = 1\n", wantErr: "runtime error at 10:1: no field named 'z' in struct 'Point'"},
+		{src: "m := map[string]int{\"x\": 1}\nputln(m.z)\n", wantErr: "no field named 'z' in map 'map[string]int'"},
+		{src: "n := 5\nputln(n.x)\n", wantErr: "member expression expects enums, structs or maps, but got 'int'"},
+	})
+}
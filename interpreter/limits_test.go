@@ -0,0 +1,119 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxStepsStopsRangeLoops(t *testing.T) {
+	loops := []string{
+		"for _ := range 50000000 {\n}",
+		"say nums = make([]int, 1000000)\nfor _, n := range nums {\n}",
+		"for _, c := range \"" + strings.Repeat("a", 100000) + "\" {\n}",
+	}
+
+	for _, src := range loops {
+		i := New("test.ayla")
+		i.MaxSteps = 1000
+
+		err := runIn(t, i, src)
+
+		var limit LimitError
+		if !errors.As(err, &limit) {
+			t.Errorf("%.40q: expected a LimitError, got %v", src, err)
+		}
+	}
+}
+
+func TestStopEndsRangeLoop(t *testing.T) {
+	stop := make(chan struct{})
+
+	i := New("test.ayla")
+	i.Stop = stop
+
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+
+	err := runIn(t, i, "for _ := range 1000000000 {\n}")
+
+	var limit LimitError
+	if !errors.As(err, &limit) {
+		t.Fatalf("expected a LimitError, got %v", err)
+	}
+}
+
+func TestStartSharesStepBudget(t *testing.T) {
+	src := `for w := range 4 {
+    start {
+        say n = 0
+        while n < 3000 {
+            n++
+        }
+    }
+}
+`
+
+	var out strings.Builder
+
+	i := New("test.ayla")
+	i.Stdout = &out
+	i.MaxSteps = 10000
+
+	if err := runIn(t, i, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "stopped after 10000 steps") {
+		t.Errorf("expected the goroutines to run out of steps between them, got %q", out.String())
+	}
+}
+
+func TestEvalSelectionStepLimit(t *testing.T) {
+	src := "fun() (int) {\n    for _ := range 50000000 {\n    }\n    give 1\n}()"
+
+	_, err := EvalSelection("test.ayla", src, selectionOf(t, src, src))
+	if err == nil || !strings.Contains(err.Error(), "stopped after") {
+		t.Errorf("expected the selection to run out of steps, got %v", err)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	const factorial = "fun factorial(n int) (int) {\n    ayla n <= 1 {\n        give 1\n    }\n\n    give n * factorial(n - 1)\n}\n"
+	const countdown = "fun down(n int) (int) {\n    ayla n == 0 {\n        give 0\n    }\n\n    give down(n - 1)\n}\n"
+
+	runScripts(t, []scriptTest{
+		{src: factorial + "putln(factorial(10), factorial(20))\n", want: "3628800 2432902008176640000\n"},
+		{src: countdown + "putln(down(4000))\n", want: "0\n"},
+		{src: "fun forever(n int) (int) {\n    give forever(n + 1)\n}\n\nforever(0)\n", wantErr: "runtime error at 2:10: stack overflow: exceeded max call depth of 5000"},
+		// calls going back and forth count the same as a function calling itself
+		{src: "fun ping(n int) (int) {\n    give pong(n + 1)\n}\nfun pong(n int) (int) {\n    give ping(n + 1)\n}\nping(0)\n", wantErr: "exceeded max call depth of 5000"},
+	})
+
+	tests := []struct {
+		limit int
+		n     int
+		fails bool
+	}{
+		{10, 9, false},
+		{10, 10, true},
+		{0, 6000, false},
+	}
+
+	for _, tt := range tests {
+		i := New("test.ayla")
+		i.MaxCallDepth = tt.limit
+
+		// down(n) is n+1 calls deep
+		err := runIn(t, i, fmt.Sprintf("%sdown(%d)\n", countdown, tt.n))
+		if failed := err != nil; failed != tt.fails {
+			t.Errorf("limit %d, down(%d): got error %v", tt.limit, tt.n, err)
+		}
+
+		// the depth goes back to zero after an overflow, so the next call still works
+		if err := runIn(t, i, "down(3)\n"); err != nil {
+			t.Errorf("limit %d: the interpreter was left unusable: %v", tt.limit, err)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/z-sk1/ayla-lang/parser"
+	"github.com/z-sk1/ayla-lang/token"
+)
+
+// Warning is code that runs but is probably not what was meant, Lint finds them without running anything
+type Warning struct {
+	Code      string
+	Message   string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+
+	// Hint marks a warning that is only a suggestion, the code may well be meant
+	Hint bool
+}
+
+func (w Warning) Error() string {
+	kind := "warning"
+	if w.Hint {
+		kind = "hint"
+	}
+
+	return fmt.Sprintf("%s at %d:%d: %s", kind, w.Line, w.Column, w.Message)
+}
+
+// warning codes, one per check
+const (
+	WarnDiscardedResult = "discarded-result"
+	WarnConditionEffect = "condition-side-effect"
+	WarnEmptyBody       = "empty-body"
+	WarnSnapInChoose    = "snap-in-choose"
+)
+
+func newWarning(node parser.Node, code, msg string) Warning {
+	line, col := node.Pos()
+	endLine, endCol := node.End()
+	return Warning{Code: code, Message: msg, Line: line, Column: col, EndLine: endLine, EndColumn: endCol}
+}
+
+// Lint runs every check over a parsed program and gives back the warnings in source order
+func (i *Interpreter) Lint(stmts []parser.Statement) []Warning {
+	var warnings []Warning
+
+	warnings = append(warnings, i.lintDiscardedResults(stmts)...)
+	warnings = append(warnings, i.lintConditionEffects(stmts)...)
+	warnings = append(warnings, i.lintEmptyBodies(stmts)...)
+	warnings = append(warnings, i.lintSnapInChoose(stmts)...)
+
+	sort.SliceStable(warnings, func(a, b int) bool {
+		if warnings[a].Line != warnings[b].Line {
+			return warnings[a].Line < warnings[b].Line
+		}
+		return warnings[a].Column < warnings[b].Column
+	})
+
+	return warnings
+}
+
+// lintConditionEffects warns about conditions that change something, like while pop(stack) != 0,
+// since the change happens again every time the condition is checked
+func (i *Interpreter) lintConditionEffects(stmts []parser.Statement) []Warning {
+	scopes := collectScopes(stmts)
+
+	var warnings []Warning
+
+	check := func(keyword string, cond parser.Expression) {
+		if cond == nil {
+			return
+		}
+
+		parser.Inspect([]parser.Statement{cond}, func(n parser.Node) bool {
+			switch n := n.(type) {
+			// a function written in the condition does not run just by being there
+			case *parser.FuncLiteral:
+				return false
+
+			case *parser.FuncCall:
+				ident, ok := n.Callee.(*parser.Identifier)
+				if !ok {
+					return true
+				}
+
+				if scopes.resolve(ident) != nil {
+					return true
+				}
+
+				if b, ok := i.Env.builtins[ident.Value]; ok && !b.NoSideEffects {
+					warnings = append(warnings, newWarning(ident, WarnConditionEffect,
+						fmt.Sprintf("%s() in %s condition has side effects, they happen every time the condition is checked", ident.Value, keyword)))
+				}
+
+			case *parser.PostfixExpression:
+				if n.Operator == "++" || n.Operator == "--" {
+					warnings = append(warnings, newWarning(n, WarnConditionEffect,
+						fmt.Sprintf("%s in %s condition changes a variable every time the condition is checked", n.Operator, keyword)))
+				}
+
+			case *parser.SendExpression:
+				warnings = append(warnings, newWarning(n, WarnConditionEffect,
+					fmt.Sprintf("sending in %s condition happens every time the condition is checked", keyword)))
+			}
+
+			return true
+		})
+	}
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.IfStatement:
+			check("an ayla", n.Condition)
+		case *parser.WhileStatement:
+			check("a while", n.Condition)
+		case *parser.ForStatement:
+			check("a for", n.Condition)
+		}
+
+		return true
+	})
+
+	return warnings
+}
+
+// lintEmptyBodies hints at ayla, elen and loop bodies with nothing in them, which usually means
+// something was forgotten or a brace ended up in the wrong place. a comment inside says it is on purpose
+func (i *Interpreter) lintEmptyBodies(stmts []parser.Statement) []Warning {
+	var warnings []Warning
+
+	check := func(what string, body []parser.Statement, braces parser.Braces) {
+		if len(body) > 0 || braces.Open.Type == "" || braces.Commented {
+			return
+		}
+
+		r := braces.Range()
+		warnings = append(warnings, Warning{
+			Code:      WarnEmptyBody,
+			Message:   fmt.Sprintf("%s is empty, put a comment inside if that is on purpose", what),
+			Line:      r.Start.Line,
+			Column:    r.Start.Column,
+			EndLine:   r.End.Line,
+			EndColumn: r.End.Column,
+			Hint:      true,
+		})
+	}
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.IfStatement:
+			check("ayla body", n.Consequence, n.ConsequenceBraces)
+			check("elen body", n.Alternative, n.AlternativeBraces)
+		case *parser.WhileStatement:
+			check("while body", n.Body, n.BodyBraces)
+		case *parser.ForStatement:
+			check("for body", n.Body, n.BodyBraces)
+		case *parser.ForRangeStatement:
+			check("for body", n.Body, n.BodyBraces)
+		}
+
+		return true
+	})
+
+	return warnings
+}
+
+// lintSnapInChoose warns about snap in a choose that is inside a loop, it only ends the choose
+// and the loop carries on, which is easy to miss when snap was meant to leave the loop
+func (i *Interpreter) lintSnapInChoose(stmts []parser.Statement) []Warning {
+	var warnings []Warning
+
+	var walk func(stmts []parser.Statement, inLoop, inChoose bool)
+	walk = func(stmts []parser.Statement, inLoop, inChoose bool) {
+		parser.Inspect(stmts, func(n parser.Node) bool {
+			switch n := n.(type) {
+			case *parser.WhileStatement:
+				walk(n.Body, true, false)
+				return false
+			case *parser.ForStatement:
+				walk(n.Body, true, false)
+				return false
+			case *parser.ForRangeStatement:
+				walk(n.Body, true, false)
+				return false
+
+			case *parser.SwitchStatement:
+				for _, c := range n.Cases {
+					walk(c.Body, inLoop, true)
+				}
+				if n.Default != nil {
+					walk(n.Default.Body, inLoop, true)
+				}
+				return false
+
+			// snap in a function can't reach the loop it was called from
+			case *parser.FuncStatement:
+				walk(n.Body, false, false)
+				return false
+			case *parser.FuncLiteral:
+				walk(n.Body, false, false)
+				return false
+			case *parser.MethodStatement:
+				walk(n.Body, false, false)
+				return false
+
+			case *parser.BreakStatement:
+				if inLoop && inChoose {
+					warnings = append(warnings, newWarning(n, WarnSnapInChoose,
+						"snap only ends the choose, the loop around it keeps going"))
+				}
+			}
+
+			return true
+		})
+	}
+
+	walk(stmts, false, false)
+
+	return warnings
+}
+
+// lintDiscardedResults warns about calling something that only gives back a value and then dropping it,
+// like writing append(nums, 4) on its own line and expecting nums to change
+func (i *Interpreter) lintDiscardedResults(stmts []parser.Statement) []Warning {
+	scopes := collectScopes(stmts)
+
+	var warnings []Warning
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		stmt, ok := n.(*parser.ExpressionStatement)
+		if !ok {
+			return true
+		}
+
+		call, ok := stmt.Expression.(*parser.FuncCall)
+		if !ok {
+			return true
+		}
+
+		ident, ok := call.Callee.(*parser.Identifier)
+		if !ok {
+			return true
+		}
+
+		discarded := false
+		if decl := scopes.resolve(ident); decl != nil {
+			discarded = decl.hasResult
+		} else if b, ok := i.Env.builtins[ident.Value]; ok {
+			discarded = b.ResultOnly
+		}
+
+		if discarded {
+			warnings = append(warnings, newWarning(ident, WarnDiscardedResult,
+				fmt.Sprintf("the result of %s() is never used, assign it to keep it or to _ to drop it", ident.Value)))
+		}
+
+		return true
+	})
+
+	return warnings
+}
+
+// lintScope is the span of source a name declared in it can be seen from, by byte offset
+type lintScope struct {
+	start, end int
+}
+
+func (s lintScope) contains(offset int) bool {
+	return s.start <= offset && offset < s.end
+}
+
+// lintDecl is a name the program declares, hasResult is only set when it is known to be
+// a function that gives back a value
+type lintDecl struct {
+	name      string
+	scope     lintScope
+	from      int // functions can be called before they are declared, so theirs is the scope's start
+	hasResult bool
+}
+
+// lintScopes is every declaration in a program, so a name can be resolved the way the interpreter would
+type lintScopes []lintDecl
+
+func nodeRange(n parser.Node) token.Range {
+	if r, ok := n.(interface{ Range() token.Range }); ok {
+		return r.Range()
+	}
+
+	return token.Range{}
+}
+
+// opensScope reports whether names declared inside n are only seen inside it
+func opensScope(n parser.Node) bool {
+	switch n.(type) {
+	case *parser.FuncStatement, *parser.MethodStatement, *parser.FuncLiteral,
+		*parser.IfStatement, *parser.WhileStatement, *parser.ForStatement, *parser.ForRangeStatement,
+		*parser.CaseClause, *parser.DefaultClause, *parser.MatchArm, *parser.SelectCaseClause,
+		*parser.WithStatement, *parser.DeferStatement, *parser.StartStatement:
+		return true
+	}
+
+	return false
+}
+
+func collectScopes(stmts []parser.Statement) lintScopes {
+	var decls lintScopes
+
+	// the scopes around the node being looked at, innermost last
+	stack := []lintScope{{start: 0, end: math.MaxInt}}
+
+	declare := func(ident *parser.Identifier, scope lintScope, from int, hasResult bool) {
+		if ident == nil || ident.Value == "_" {
+			return
+		}
+
+		decls = append(decls, lintDecl{name: ident.Value, scope: scope, from: from, hasResult: hasResult})
+	}
+
+	returnsFrom := func(v parser.Expression) bool {
+		fn, ok := v.(*parser.FuncLiteral)
+		return ok && len(fn.ReturnTypes) > 0
+	}
+
+	params := func(ps []*parser.Param, scope lintScope) {
+		for _, p := range ps {
+			declare(p.Name, scope, scope.start, false)
+		}
+	}
+
+	parser.Inspect(stmts, func(n parser.Node) bool {
+		r := nodeRange(n)
+		for len(stack) > 1 && !stack[len(stack)-1].contains(r.Start.Offset) {
+			stack = stack[:len(stack)-1]
+		}
+
+		outer := stack[len(stack)-1]
+		own := lintScope{start: r.Start.Offset, end: r.End.Offset}
+
+		switch n := n.(type) {
+		case *parser.FuncStatement:
+			declare(n.Name, outer, outer.start, len(n.ReturnTypes) > 0)
+			params(n.Params, own)
+		case *parser.MethodStatement:
+			if n.Receiver != nil {
+				declare(n.Receiver.Name, own, own.start, false)
+			}
+			params(n.Params, own)
+		case *parser.FuncLiteral:
+			params(n.Params, own)
+		case *parser.VarStatement:
+			declare(n.Name, outer, r.Start.Offset, returnsFrom(n.Value))
+		case *parser.ConstStatement:
+			declare(n.Name, outer, r.Start.Offset, returnsFrom(n.Value))
+		case *parser.MultiVarStatement:
+			for _, name := range n.Names {
+				declare(name, outer, r.Start.Offset, false)
+			}
+		case *parser.MultiConstStatement:
+			for _, name := range n.Names {
+				declare(name, outer, r.Start.Offset, false)
+			}
+		case *parser.ForRangeStatement:
+			declare(n.Key, own, own.start, false)
+			declare(n.Value, own, own.start, false)
+		case *parser.MatchArm:
+			declare(n.Binding, own, own.start, false)
+		case *parser.SelectCaseClause:
+			declare(n.AssignName, own, own.start, false)
+		}
+
+		if opensScope(n) {
+			stack = append(stack, own)
+		}
+
+		return true
+	})
+
+	return decls
+}
+
+// resolve finds the declaration a call to ident uses, the innermost one it can see,
+// nil means ident is not declared by the program, so it is a builtin or undefined
+func (s lintScopes) resolve(ident *parser.Identifier) *lintDecl {
+	at := nodeRange(ident).Start.Offset
+
+	var best *lintDecl
+	for idx := range s {
+		d := &s[idx]
+		if d.name != ident.Value || !d.scope.contains(at) || d.from > at {
+			continue
+		}
+
+		if best == nil || d.scope.start > best.scope.start || (d.scope.start == best.scope.start && d.from > best.from) {
+			best = d
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,34 @@
+package interpreter
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "ages := make(map[string]int)\nputln(len(ages), ages)\n", want: "0 map{}\n"},
+		{src: "ages := make(map[string]int)\nages[\"Ayla\"] = 12\nputln(ages[\"Ayla\"], len(ages))\n", want: "12 1\n"},
+		{src: "ages := map[string]int{\"Ziad\": 15}\nputln(ages[\"Ayla\"])\n", want: "nil\n"},
+		{src: "ages := map[string]int{\"Ziad\": 15}\nage, ok := ages[\"Ayla\"]\nputln(age, ok)\n", want: "nil no\n"},
+		{src: "ages := map[string]int{\"Ziad\": 15}\nages[\"Ziad\"] = 16\nputln(ages[\"Ziad\"], len(ages))\n", want: "16 1\n"},
+		{src: "ages := map[string]int{\"Ziad\": 15, \"Ayla\": 12}\ndelete(ages, \"Ziad\")\nputln(len(ages), ages[\"Ziad\"])\n", want: "1 nil\n"},
+		{src: "ages := map[string]int{\"Ziad\": 15}\ndelete(ages, \"Bob\")\nputln(len(ages))\n", want: "1\n"},
+		{src: "ages := map[string]int{}\nages[\"Ziad\"] = \"old\"\n", wantErr: "string"},
+	})
+}
+
+// TestDuplicateMapKeys covers literal keys that only turn out to be equal once evaluated
+func TestDuplicateMapKeys(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "m := map[string]int{\"a\": 1, \"a\": 2}\n", wantErr: "runtime error at 1:29: duplicate key a in map literal"},
+		{src: "m := map[int]int{1 + 0: 1, 1: 2}\n", wantErr: "duplicate key 1 in map literal"},
+		{src: "m := map[string]int{\"a\": 1, \"A\": 2}\nputln(len(m))\n", want: "2\n"},
+	})
+}
+
+// TestShadowingParameter makes sure a parameter can still be shadowed in a nested block, only a
+// second declaration in the same scope is refused
+func TestShadowingParameter(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "fun f(a int, b int) (int) {\n    ayla yes {\n        say a = b\n        putln(a)\n    }\n    give a\n}\nputln(f(1, 2))\n", want: "2\n1\n"},
+		{src: "fun f(a int, b int) (int) {\n    say a = b\n    give a\n}\nf(1, 2)\n", wantErr: "cant redeclare var: a"},
+	})
+}
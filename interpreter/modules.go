@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"fmt"
+	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 
@@ -26,7 +27,7 @@ func ArgInt(node parser.Node, args []Value, i int, name string) (int, error) {
 func ArgFloat(node parser.Node, args []Value, i int, name string) (float64, error) {
 	v, ok := toFloat(UnwrapFully(args[i]))
 	if !ok {
-		return 0, NewRuntimeError(node, fmt.Sprintf("%s: argument %d must be an float", name, i+1))
+		return 0, NewRuntimeError(node, fmt.Sprintf("%s: argument %d must be a number", name, i+1))
 	}
 	return v, nil
 }
@@ -439,8 +440,11 @@ func MakeVector2(v rl.Vector2, TypeEnv map[string]TypeValue) Value {
 
 func WrapFloat1(name string, fn func(float64) float64) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 1,
+		Name:          name,
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			f, err := ArgFloat(node, args, 0, name)
 			if err != nil {
@@ -454,8 +458,11 @@ func WrapFloat1(name string, fn func(float64) float64) *BuiltinFunc {
 
 func WrapFloat2(name string, fn func(float64, float64) float64) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			f1, err := ArgFloat(node, args, 0, name)
 			if err != nil {
@@ -472,10 +479,37 @@ func WrapFloat2(name string, fn func(float64, float64) float64) *BuiltinFunc {
 	}
 }
 
+// WrapFloat1RInt is for rounding functions, the result has to fit in an int
+func WrapFloat1RInt(name string, fn func(float64) float64) *BuiltinFunc {
+	return &BuiltinFunc{
+		Name:          name,
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			f, err := ArgFloat(node, args, 0, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			r := fn(f)
+			if math.IsNaN(r) || r < math.MinInt || r >= math.MaxInt {
+				return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: %v does not fit in an int", name, f))
+			}
+
+			return IntValue{V: int(r)}, nil
+		},
+	}
+}
+
 func WrapString1(name string, fn func(string) string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 1,
+		Name:          name,
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -489,8 +523,11 @@ func WrapString1(name string, fn func(string) string) *BuiltinFunc {
 
 func WrapString1RSlice(name string, fn func(string) []string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         1,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -513,8 +550,11 @@ func WrapString1RSlice(name string, fn func(string) []string) *BuiltinFunc {
 
 func WrapString2(name string, fn func(string, string) string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -531,10 +571,41 @@ func WrapString2(name string, fn func(string, string) string) *BuiltinFunc {
 	}
 }
 
+func WrapString3(name string, fn func(string, string, string) string) *BuiltinFunc {
+	return &BuiltinFunc{
+		Name:          name,
+		Arity:         3,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			s, err := ArgString(node, args, 0, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			s2, err := ArgString(node, args, 1, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			s3, err := ArgString(node, args, 2, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			return StringValue{V: fn(s, s2, s3)}, nil
+		},
+	}
+}
+
 func WrapString2IntRSlice(name string, fn func(string, string, int) []string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         3,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -567,8 +638,11 @@ func WrapString2IntRSlice(name string, fn func(string, string, int) []string) *B
 
 func WrapString2RSlice(name string, fn func(string, string) []string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -596,8 +670,11 @@ func WrapString2RSlice(name string, fn func(string, string) []string) *BuiltinFu
 
 func WrapString2RInt(name string, fn func(string, string) int) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			s, err := ArgString(node, args, 0, name)
 			if err != nil {
@@ -614,10 +691,36 @@ func WrapString2RInt(name string, fn func(string, string) int) *BuiltinFunc {
 	}
 }
 
+func WrapString2RBool(name string, fn func(string, string) bool) *BuiltinFunc {
+	return &BuiltinFunc{
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
+		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
+			s, err := ArgString(node, args, 0, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			s2, err := ArgString(node, args, 1, name)
+			if err != nil {
+				return NilValue{}, err
+			}
+
+			return BoolValue{V: fn(s, s2)}, nil
+		},
+	}
+}
+
 func WrapSliceStringRString(name string, fn func([]string, string) string) *BuiltinFunc {
 	return &BuiltinFunc{
-		Name:  name,
-		Arity: 2,
+		Name:          name,
+		Arity:         2,
+		ResultOnly:    true,
+		NoSideEffects: true,
+		SelectionSafe: true,
 		Fn: func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error) {
 			sliceVal, err := ArgArray(node, args, 0, name, "string")
 			if err != nil {
@@ -626,12 +729,13 @@ func WrapSliceStringRString(name string, fn func([]string, string) string) *Buil
 
 			slice := []string{}
 
-			for _, s := range sliceVal.Elements {
-				if _, ok := s.(StringValue); !ok {
-					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: first argument must be a []string", name))
+			for idx, el := range sliceVal.Elements {
+				s, ok := UnwrapFully(el).(StringValue)
+				if !ok {
+					return NilValue{}, NewRuntimeError(node, fmt.Sprintf("%s: element %d of the first argument must be a string, got %s", name, idx, i.TypeInfoFromValue(UnwrapFully(el)).Name))
 				}
 
-				slice = append(slice, s.(StringValue).V)
+				slice = append(slice, s.V)
 			}
 
 			s, err := ArgString(node, args, 1, name)
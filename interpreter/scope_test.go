@@ -0,0 +1,95 @@
+package interpreter
+
+import "testing"
+
+// TestScopedConsts covers keep inside functions, blocks and loops, a const there is local to the
+// call or pass it was declared in and can be shadowed like any other name
+func TestScopedConsts(t *testing.T) {
+	runScripts(t, []scriptTest{
+		// declared fresh on every call and every pass
+		{src: "fun f(n int) (int) {\n    keep k = n * 2\n    give k\n}\nputln(f(1), f(2))\n", want: "2 4\n"},
+		{src: "for i := 0; i < 3; i++ {\n    keep c = i\n    put(c)\n}\nputln()\n", want: "012\n"},
+
+		// a variable shadowing an outer const can be assigned, the const keeps its value
+		{src: "keep k = 1\nfun f() {\n    say k = 5\n    k = 6\n    putln(k)\n}\nf()\nputln(k)\n", want: "6\n1\n"},
+		{src: "keep k = 1\nayla yes {\n    say k = 2\n    k = 3\n    putln(k)\n}\nputln(k)\n", want: "3\n1\n"},
+
+		// a const shadowing an outer variable goes away with its block
+		{src: "say v = 1\nayla yes {\n    keep v = 2\n    putln(v)\n}\nv = 3\nputln(v)\n", want: "2\n3\n"},
+		{src: "say v = 1\nayla yes {\n    keep v = 2\n    v = 3\n}\n", wantErr: "cannot assign to const: v"},
+
+		// the const check finds the binding in the right scope
+		{src: "keep k = 1\nfun f() {\n    k = 2\n}\nf()\n", wantErr: "cannot assign to const: k"},
+		{src: "fun f() {\n    keep k = 1\n    k = 2\n}\nf()\n", wantErr: "cannot assign to const: k"},
+	})
+}
+
+// TestLexicalScope makes sure a function sees the scope it was defined in, never the locals of
+// whoever called it
+func TestLexicalScope(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "fun peek() {\n    putln(secret)\n}\nfun caller() {\n    say secret = 1\n    peek()\n}\ncaller()\n", wantErr: "runtime error at 2:11: undefined variable: secret"},
+		{src: "say g = 1\nfun bump() {\n    g = g + 1\n}\nbump()\nbump()\nputln(g)\n", want: "3\n"},
+
+		// every call gets its own locals, recursion included
+		{src: "fun fact(n int) (int) {\n    ayla n <= 1 {\n        give 1\n    }\n    say r = n * fact(n - 1)\n    give r\n}\nputln(fact(5))\n", want: "120\n"},
+
+		// a closure keeps the scope it was made in
+		{src: "fun make() (fun(int) (int)) {\n    say base = 10\n    give fun(x int) (int) { x + base }\n}\nsay add = make()\nputln(add(1))\n", want: "11\n"},
+		{src: "fun counter() (fun() (int)) {\n    say n = 0\n    give fun() (int) {\n        n = n + 1\n        give n\n    }\n}\nsay c = counter()\nc()\nputln(c(), counter()())\n", want: "2 1\n"},
+
+		// a user definition hides a builtin of the same name while it is in scope
+		{src: "fun len(x int) (int) {\n    give 99\n}\nputln(len(1))\n", want: "99\n"},
+		{src: "fun f() {\n    say len = 3\n    putln(len)\n}\nf()\nputln(len(\"ab\"))\n", want: "3\n2\n"},
+	})
+}
+
+// TestBlockScope covers the child scope every if, for and while body gets, a declaration inside
+// is gone once the block exits while assignments still reach the outer variable
+func TestBlockScope(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: "ayla yes {\n    say t = 1\n}\nputln(t)\n", wantErr: "runtime error at 4:7: undefined variable: t"},
+		{src: "ayla no {\n} elen {\n    say t = 1\n}\nputln(t)\n", wantErr: "undefined variable: t"},
+		{src: "for i := 0; i < 2; i++ {\n    say t = i\n}\nputln(t)\n", wantErr: "undefined variable: t"},
+		{src: "for i := 0; i < 2; i++ {\n}\nputln(i)\n", wantErr: "undefined variable: i"},
+		{src: "for _, v := range []int{1, 2} {\n    say sq = v * v\n}\nputln(sq)\n", wantErr: "undefined variable: sq"},
+		{src: "say n = 0\nwhile n < 3 {\n    say t = n\n    n++\n}\nputln(t)\n", wantErr: "undefined variable: t"},
+
+		// outer variables can still be assigned, and a name freed by a block can be declared again
+		{src: "say x = 1\nayla yes {\n    x = 2\n    say y = 3\n}\nsay y = 4\nputln(x, y)\n", want: "2 4\n"},
+		{src: "say total = 0\nfor _, v := range []int{1, 2} {\n    say sq = v * v\n    total += sq\n}\nputln(total)\n", want: "5\n"},
+
+		// each pass gets a fresh scope, so the declaration doesn't clash with the last one
+		{src: "for i := 0; i < 3; i++ {\n    say t = i\n    put(t)\n}\nputln()\n", want: "012\n"},
+	})
+}
+
+// TestSiblingBranchAssignment covers assigning to a variable that only a sibling branch declares,
+// it is reported before the script runs, whichever branch would have been taken
+func TestSiblingBranchAssignment(t *testing.T) {
+	const msg = "cannot assign to undeclared variable: x, it is only declared in another branch of this ayla, declare it before the ayla instead"
+
+	runScripts(t, []scriptTest{
+		{src: "say c = no\nayla c {\n    say x = 1\n} elen {\n    x = 2\n}\n", wantErr: "runtime error at 5:5: " + msg},
+		{src: "say c = yes\nayla c {\n    say x = 1\n} elen {\n    x = 2\n}\n", wantErr: msg},
+		{src: "say c = 2\nayla c == 1 {\n    say x = 1\n} elen ayla c == 2 {\n    x = 2\n}\n", wantErr: msg},
+		{src: "ayla no {\n    say x = 1\n} elen {\n    ayla yes {\n        x = 2\n    }\n}\n", wantErr: "runtime error at 5:9: " + msg},
+		{src: "fun f() {\n    ayla no {\n        say x = 1\n    } elen {\n        x = 2\n    }\n}\n", wantErr: msg},
+
+		// declared where the assignment can see it
+		{src: "say x = 0\nayla yes {\n    x = 1\n} elen {\n    x = 2\n}\nputln(x)\n", want: "1\n"},
+		{src: "ayla yes {\n    say x = 1\n    x = 2\n    putln(x)\n} elen {\n    say x = 3\n    x = 4\n}\n", want: "2\n"},
+		{src: "ayla no {\n    say x = 1\n} elen {\n    say x = 5\n    ayla yes {\n        x = 2\n    }\n    putln(x)\n}\n", want: "2\n"},
+	})
+}
+
+func TestSiblingBranchAssignmentStopsBeforeRunning(t *testing.T) {
+	out, err := run(t, "putln(\"before\")\nayla no {\n    say x = 1\n} elen {\n    x = 2\n}\n", "")
+	if err == nil {
+		t.Fatal("expected the assignment to be reported")
+	}
+
+	if out != "" {
+		t.Errorf("expected nothing to run, printed %q", out)
+	}
+}
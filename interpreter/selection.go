@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+	"github.com/z-sk1/ayla-lang/parser"
+	"github.com/z-sk1/ayla-lang/token"
+)
+
+// selectionSteps stops a selection that calls into a function that never ends
+const selectionSteps = 100000
+
+// EvalSelection evaluates the expression an editor picked out of doc at sel and gives back how
+// its result looks, with strings quoted the way ayla source writes them. positions in errors and in the parsed expression point
+// into doc. the keep declarations at the top of doc are in scope, nothing else in it runs,
+// and only builtins without side effects can be called
+func EvalSelection(file, doc string, sel token.Range) (string, error) {
+	if sel.Start.Offset < 0 || sel.Start.Offset > sel.End.Offset || sel.End.Offset > len(doc) {
+		return "", fmt.Errorf("selection %d-%d is outside the document", sel.Start.Offset, sel.End.Offset)
+	}
+
+	expr, errs := parser.ParseExpression(doc[sel.Start.Offset:sel.End.Offset], sel.Start)
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+
+	p := parser.New(lexer.New(doc))
+	p.File = file
+	program := p.ParseProgram()
+
+	i := New(file)
+	i.pure = true
+	i.MaxSteps = selectionSteps
+
+	for _, stmt := range program.Statements {
+		switch stmt.(type) {
+		case *parser.ConstStatement, *parser.MultiConstStatement, *parser.ConstStatementBlock:
+			// a keep that can't be worked out stays undefined, using it says so
+			i.EvalStatement(stmt)
+		}
+	}
+
+	v, err := i.evalOne(expr)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := UnwrapFully(v).(StringValue); ok {
+		if i.MaxPrintBytes > 0 {
+			if text, left := cutText(s.V, i.MaxPrintBytes); left > 0 {
+				return fmt.Sprintf("%s…(+%d more bytes)", lexer.QuoteString(text), left), nil
+			}
+		}
+
+		return lexer.QuoteString(s.V), nil
+	}
+
+	return i.format(v), nil
+}
+
+func (i *Interpreter) checkPure(node parser.Node, b *BuiltinFunc) error {
+	if i.pure && !b.SelectionSafe {
+		return NewRuntimeError(node, fmt.Sprintf("%s() can't be called here, it has side effects", b.Name))
+	}
+
+	return nil
+}
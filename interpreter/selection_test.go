@@ -0,0 +1,142 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/parser"
+	"github.com/z-sk1/ayla-lang/token"
+)
+
+// selectionOf gives the range of the first text in doc, the way an editor would send a selection
+func selectionOf(t *testing.T, doc, text string) token.Range {
+	t.Helper()
+
+	start := strings.Index(doc, text)
+	if start < 0 {
+		t.Fatalf("%q is not in the document", text)
+	}
+
+	at := func(offset int) token.Position {
+		before := doc[:offset]
+		line := strings.Count(before, "\n") + 1
+		col := len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+		return token.Position{Line: line, Column: col, Offset: offset}
+	}
+
+	return token.Range{Start: at(start), End: at(start + len(text))}
+}
+
+const selectionDoc = `keep limit = 10
+keep name = "ayla"
+keep tpl = "cost: \${limit}\tdone"
+keep word = "héllo 🙂"
+
+fun double(n int) (int) {
+    give n * 2
+}
+
+putln(limit * 2 + len(name))
+putln(tpl, word)
+putln(missing + limit)
+putln(readchar())
+putln(double(limit))
+`
+
+func TestEvalSelection(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"limit * 2 + len(name)", "24"},
+		{"name", `"ayla"`},
+		{"limit * 2", "20"},
+		// quoted so it reads back as the same string, ${ stays literal and non-ascii is left alone
+		{"tpl", `"cost: \${limit}\tdone"`},
+		{"word", `"héllo 🙂"`},
+	}
+
+	for _, tt := range tests {
+		got, err := EvalSelection("test.ayla", selectionDoc, selectionOf(t, selectionDoc, tt.text))
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEvalSelectionIsPure(t *testing.T) {
+	tests := []struct {
+		doc  string
+		text string
+		want string
+	}{
+		// only keep declarations run, so nothing the document does happens twice
+		{selectionDoc, "readchar()", "readchar() can't be called here, it has side effects"},
+		{"say x = 1\nx + 1\n", "x + 1", "undefined variable: x"},
+		{selectionDoc, "double(limit)", "undefined variable: double"},
+		{"keep x = 1\nputln(x)\n", "putln(x)", "putln() can't be called here, it has side effects"},
+	}
+
+	for _, tt := range tests {
+		_, err := EvalSelection("test.ayla", tt.doc, selectionOf(t, tt.doc, tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.text, tt.want, err)
+		}
+	}
+}
+
+func TestEvalSelectionErrorPositions(t *testing.T) {
+	tests := []struct {
+		doc  string
+		text string
+		want string
+	}{
+		// a runtime error points at the place in the document, not in the selection
+		{selectionDoc, "missing + limit", "runtime error at 12:7: undefined variable: missing"},
+		// and so does a syntax error
+		{"keep limit = 10\n\nputln(limit * 2 + )\n", "limit * 2 + ", "syntax error at 3:19: expected expression after '+'"},
+	}
+
+	for _, tt := range tests {
+		_, err := EvalSelection("test.ayla", tt.doc, selectionOf(t, tt.doc, tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.text, tt.want, err)
+		}
+	}
+}
+
+func TestSelectionOffsetsPointIntoDocument(t *testing.T) {
+	sel := selectionOf(t, selectionDoc, "limit * 2 + len(name)")
+
+	expr, errs := parser.ParseExpression(selectionDoc[sel.Start.Offset:sel.End.Offset], sel.Start)
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+
+	parser.Inspect([]parser.Statement{expr}, func(n parser.Node) bool {
+		ident, ok := n.(*parser.Identifier)
+		if !ok {
+			return true
+		}
+
+		r := ident.Range()
+		if got := selectionDoc[r.Start.Offset:r.End.Offset]; got != ident.Value {
+			t.Errorf("%s has offsets %d-%d, which cover %q in the document", ident.Value, r.Start.Offset, r.End.Offset, got)
+		}
+
+		return true
+	})
+}
+
+func TestEvalSelectionOutsideDocument(t *testing.T) {
+	sel := token.Range{Start: token.Position{Line: 1, Column: 1, Offset: 0}, End: token.Position{Line: 1, Column: 99, Offset: 99}}
+
+	if _, err := EvalSelection("test.ayla", "1 + 1", sel); err == nil {
+		t.Error("expected an error for a selection past the end of the document")
+	}
+}
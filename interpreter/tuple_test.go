@@ -0,0 +1,47 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+const minmax = "fun minmax() (int, int) {\n    give 1, 9\n}\n"
+
+func TestTupleAssignment(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"literal stays whole", "say t = (1, 2, 3)\nputln(t)\n", "(1, 2, 3)\n"},
+		{"short declaration", "t := (4, 5)\nputln(t)\n", "(4, 5)\n"},
+		{"const", "keep t = (6, 7)\nputln(t)\n", "(6, 7)\n"},
+		{"assignment", "say t = (1, 2)\nt = (3, 4)\nputln(t)\n", "(3, 4)\n"},
+		{"copied from a variable", "say t = (1, 2)\nsay u = t\nputln(u)\n", "(1, 2)\n"},
+		{"destructured", "say a, b = (1, 2)\nputln(a, b)\n", "1 2\n"},
+		{"variable destructured", "say t = (1, 2)\nsay a, b = t\nputln(b, a)\n", "2 1\n"},
+		{"call gives its first value", minmax + "say lo = minmax()\nputln(lo)\n", "1\n"},
+		{"call destructured", minmax + "say lo, hi = minmax()\nputln(lo, hi)\n", "1 9\n"},
+		{"call in parentheses", minmax + "say t = (minmax())\nputln(t)\n", "(1, 9)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(t, tt.src, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != tt.want {
+				t.Errorf("printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestTupleArityMismatch(t *testing.T) {
+	_, err := run(t, "say a, b, c = (1, 2)\n", "")
+	if err == nil || !strings.Contains(err.Error(), "expected 3 values, got 2") {
+		t.Errorf("expected an arity error, got %v", err)
+	}
+}
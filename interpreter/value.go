@@ -2,8 +2,10 @@ package interpreter
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/z-sk1/ayla-lang/parser"
 )
@@ -26,6 +28,7 @@ const (
 	TypeChannel
 	TypeInterface
 	TypeNamed
+	TypeVariant
 )
 
 type TypeInfo struct {
@@ -43,6 +46,9 @@ type TypeInfo struct {
 
 	Fields map[string]*TypeInfo
 
+	// FieldOrder is the order the fields were declared in, used when printing
+	FieldOrder []string
+
 	Elem *TypeInfo
 	Size int
 
@@ -95,6 +101,7 @@ const (
 	NATIVE      ValueType = "native"
 	POINTER     ValueType = "pointer"
 	INTERFACE   ValueType = "interface"
+	VARIANT     ValueType = "variant"
 )
 
 type Value interface {
@@ -125,7 +132,7 @@ func (v VariableTarget) Set(i *Interpreter, val Value) error {
 
 	expectedTI := UnwrapAlias(i.TypeInfoFromValue(v.Var.Value))
 
-	newVal, err := i.assignWithType(nil, val, expectedTI)
+	newVal, err := i.assignNamed(nil, v.Name, val, expectedTI)
 	if err != nil {
 		return fmt.Errorf("%s", err.Error())
 	}
@@ -145,6 +152,17 @@ func (t VariableTarget) Get(i *Interpreter) (Value, error) {
 	return v.Value, nil
 }
 
+// DiscardTarget is '_' on the left of an assignment, whatever is assigned is dropped
+type DiscardTarget struct{}
+
+func (DiscardTarget) Set(i *Interpreter, val Value) error {
+	return nil
+}
+
+func (DiscardTarget) Get(i *Interpreter) (Value, error) {
+	return NilValue{}, fmt.Errorf("cannot use '_' as a value")
+}
+
 type MemberTarget struct {
 	Struct    *StructValue
 	Field     string
@@ -177,7 +195,7 @@ type ArrayIndexTarget struct {
 
 func (a ArrayIndexTarget) Set(i *Interpreter, val Value) error {
 	if a.Index < 0 || a.Index >= len(a.Array.Elements) {
-		return fmt.Errorf("index %d out of bounds", a.Index)
+		return fmt.Errorf("%s", indexOutOfBounds(a.Index, len(a.Array.Elements)))
 	}
 
 	newVal, err := i.assignToType(val, a.ElemType)
@@ -191,7 +209,7 @@ func (a ArrayIndexTarget) Set(i *Interpreter, val Value) error {
 
 func (a ArrayIndexTarget) Get(i *Interpreter) (Value, error) {
 	if a.Index < 0 || a.Index >= len(a.Array.Elements) {
-		return NilValue{}, fmt.Errorf("index %d out of bounds", a.Index)
+		return NilValue{}, fmt.Errorf("%s", indexOutOfBounds(a.Index, len(a.Array.Elements)))
 	}
 
 	return a.Array.Elements[a.Index], nil
@@ -215,7 +233,12 @@ func (m MapIndexTarget) Set(i *Interpreter, val Value) error {
 		return fmt.Errorf("map value %s", err)
 	}
 
+	if m.Map.Keys == nil {
+		m.Map.Keys = make(map[string]Value)
+	}
+
 	m.Map.Entries[MapKey(key)] = newVal
+	m.Map.Keys[MapKey(key)] = key
 	return nil
 }
 
@@ -284,7 +307,11 @@ func (i *Interpreter) assignToType(val Value, expected *TypeInfo) (Value, error)
 	return i.promoteValueToType(val, expected), nil
 }
 
-type ControlSignal any
+// ControlSignal is how a statement tells the code running it what to do next, only the
+// Signal types below are signals so a Value can't be handed back as one by mistake
+type ControlSignal interface {
+	signal()
+}
 
 type SignalNone struct{}
 type SignalBreak struct{}
@@ -298,6 +325,12 @@ type SignalValue struct {
 	Value Value
 }
 
+func (SignalNone) signal()     {}
+func (SignalBreak) signal()    {}
+func (SignalContinue) signal() {}
+func (SignalReturn) signal()   {}
+func (SignalValue) signal()    {}
+
 type TupleValue struct {
 	Values []Value
 }
@@ -366,6 +399,18 @@ type BuiltinFunc struct {
 	Name  string
 	Arity int
 	Fn    func(i *Interpreter, node *parser.FuncCall, args []Value) (Value, error)
+
+	// TypeName is set when the builtin stands in for a typed function, like the ones memo makes
+	TypeName *TypeInfo
+
+	// ResultOnly builtins are only called for the value they give back, so dropping it is a mistake
+	ResultOnly bool
+
+	// NoSideEffects builtins change nothing the program can see, so running one again is harmless
+	NoSideEffects bool
+
+	// SelectionSafe builtins may run when an editor evaluates a selection, see EvalSelection
+	SelectionSafe bool
 }
 
 func (b *BuiltinFunc) Type() ValueType {
@@ -451,7 +496,36 @@ func (b BoolValue) Type() ValueType {
 }
 
 func (b BoolValue) String() string {
-	if b.V {
+	return BoolYesNo.format(b.V)
+}
+
+// BoolStyle is how bools are written out when a program prints them, the parser always reads yes and no
+type BoolStyle int
+
+const (
+	BoolYesNo BoolStyle = iota
+	BoolTrueFalse
+)
+
+// ParseBoolStyle reads a style the way the cli spells it, yesno or truefalse
+func ParseBoolStyle(s string) (BoolStyle, error) {
+	switch s {
+	case "yesno":
+		return BoolYesNo, nil
+	case "truefalse":
+		return BoolTrueFalse, nil
+	}
+
+	return BoolYesNo, fmt.Errorf("unknown bool style %q, expected yesno or truefalse", s)
+}
+
+func (s BoolStyle) format(b bool) string {
+	switch {
+	case s == BoolTrueFalse && b:
+		return "true"
+	case s == BoolTrueFalse:
+		return "false"
+	case b:
 		return "yes"
 	}
 
@@ -482,16 +556,179 @@ func (a ArrayValue) Type() ValueType {
 	return ARR
 }
 
+// DefaultMaxPrintElements and DefaultMaxPrintBytes are how much of one value printing writes
+// before cutting it short, see MaxPrintElements and MaxPrintBytes on the interpreter
+const (
+	DefaultMaxPrintElements = 10000
+	DefaultMaxPrintBytes    = 1 << 20
+)
+
 func (a ArrayValue) String() string {
-	out := "["
-	for i, el := range a.Elements {
-		if i > 0 {
-			out += ", "
+	return FormatValue(a, BoolYesNo)
+}
+
+// FormatValue is String with bools written in style, it cuts values short at the default limits
+func FormatValue(v Value, style BoolStyle) string {
+	return formatLimited(v, style, DefaultMaxPrintElements, DefaultMaxPrintBytes)
+}
+
+// formatLimited writes v with bools in style, arrays and maps stop after maxElements elements counting
+// nested ones, and anything stops once maxBytes have been written. 0 turns a limit off
+func formatLimited(v Value, style BoolStyle, maxElements, maxBytes int) string {
+	p := printer{style: style, elements: maxElements, limitElements: maxElements > 0, maxBytes: maxBytes}
+	p.write(v)
+	return p.out.String()
+}
+
+// printer writes values as text, ending a huge one with a marker instead of building a huge string
+type printer struct {
+	out   strings.Builder
+	style BoolStyle
+
+	elements      int
+	limitElements bool
+	maxBytes      int
+}
+
+func (p *printer) full() bool {
+	return p.maxBytes > 0 && p.out.Len() >= p.maxBytes
+}
+
+// more says whether another array or map element fits, and uses one up when it does
+func (p *printer) more() bool {
+	if p.full() {
+		return false
+	}
+
+	if p.limitElements {
+		if p.elements <= 0 {
+			return false
 		}
-		out += el.String()
+		p.elements--
+	}
+
+	return true
+}
+
+// writeText writes s, cutting it at a character boundary where it would go past maxBytes
+func (p *printer) writeText(s string) {
+	if p.maxBytes <= 0 {
+		p.out.WriteString(s)
+		return
+	}
+
+	text, left := cutText(s, max(p.maxBytes-p.out.Len(), 0))
+	p.out.WriteString(text)
+	if left > 0 {
+		fmt.Fprintf(&p.out, "…(+%d more bytes)", left)
+	}
+}
+
+// cutText cuts s down to at most n bytes without splitting a character, and says how many bytes it left out
+func cutText(s string, n int) (string, int) {
+	if len(s) <= n {
+		return s, 0
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n], len(s) - n
+}
+
+func (p *printer) write(v Value) {
+	switch v := v.(type) {
+	case BoolValue:
+		p.out.WriteString(p.style.format(v.V))
+
+	case NamedValue:
+		p.write(v.Value)
+
+	case InterfaceValue:
+		p.write(v.Value)
+
+	case UntypedValue:
+		p.write(v.Value)
+
+	case TupleValue:
+		p.out.WriteString("(")
+		for idx, el := range v.Values {
+			if idx > 0 {
+				p.out.WriteString(", ")
+			}
+			if p.full() {
+				p.out.WriteString("…")
+				break
+			}
+			p.write(el)
+		}
+		p.out.WriteString(")")
+
+	case VariantValue:
+		if _, ok := v.Payload.(NilValue); ok {
+			p.out.WriteString(v.Tag)
+			break
+		}
+
+		p.out.WriteString(v.Tag + "(")
+		p.write(v.Payload)
+		p.out.WriteString(")")
+
+	case *StructValue:
+		if v.TypeName == nil {
+			p.out.WriteString("struct{")
+		} else {
+			p.out.WriteString(v.TypeName.Name + "{")
+		}
+		for idx, name := range v.fieldNames() {
+			if idx > 0 {
+				p.out.WriteString(", ")
+			}
+			if p.full() {
+				p.out.WriteString("…")
+				break
+			}
+			p.out.WriteString(name + ": ")
+			p.write(v.Fields[name])
+		}
+		p.out.WriteString("}")
+
+	case ArrayValue:
+		p.out.WriteString("[")
+		for idx, el := range v.Elements {
+			if idx > 0 {
+				p.out.WriteString(", ")
+			}
+			if !p.more() {
+				fmt.Fprintf(&p.out, "…(+%d more elements)", len(v.Elements)-idx)
+				break
+			}
+			p.write(el)
+		}
+		p.out.WriteString("]")
+
+	case MapValue:
+		keys := v.sortedKeys()
+
+		p.out.WriteString("map{")
+		for idx, k := range keys {
+			if idx > 0 {
+				p.out.WriteString(", ")
+			}
+			if !p.more() {
+				fmt.Fprintf(&p.out, "…(+%d more elements)", len(keys)-idx)
+				break
+			}
+			p.write(k)
+			p.out.WriteString(": ")
+			p.write(v.Entries[MapKey(k)])
+		}
+		p.out.WriteString("}")
+
+	default:
+		p.writeText(v.String())
 	}
-	out += "]"
-	return out
 }
 
 type StructValue struct {
@@ -505,11 +742,25 @@ func (s *StructValue) Type() ValueType {
 }
 
 func (s *StructValue) String() string {
-	if s.TypeName == nil {
-		return fmt.Sprintf("struct{%v}", s.Fields)
+	return FormatValue(s, BoolYesNo)
+}
+
+// fieldNames lists the fields in the order the struct type declares them,
+// or sorted by name when the type doesn't say, like for native structs
+func (s *StructValue) fieldNames() []string {
+	for ti := s.TypeName; ti != nil; ti = ti.Underlying {
+		if ti.Kind == TypeStruct && len(ti.FieldOrder) == len(s.Fields) {
+			return ti.FieldOrder
+		}
 	}
 
-	return fmt.Sprintf("%s{%v}", s.TypeName.Name, s.Fields)
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
 }
 
 type MapValue struct {
@@ -525,19 +776,27 @@ func (m MapValue) Type() ValueType {
 }
 
 func (m MapValue) String() string {
+	return FormatValue(m, BoolYesNo)
+}
+
+func (m MapValue) sortedKeys() []Value {
 	keys := make([]Value, 0, len(m.Entries))
 
 	for _, k := range m.Keys {
 		keys = append(keys, k)
 	}
 
-	parts := make([]string, 0, len(keys))
-	for _, k := range keys {
-		v := m.Entries[MapKey(k)]
-		parts = append(parts, fmt.Sprintf("%s: %s", k.String(), v.String()))
-	}
+	// sort keys so printing a map is deterministic
+	sort.Slice(keys, func(a, b int) bool {
+		ka, aok := UnwrapFully(keys[a]).(IntValue)
+		kb, bok := UnwrapFully(keys[b]).(IntValue)
+		if aok && bok {
+			return ka.V < kb.V
+		}
+		return keys[a].String() < keys[b].String()
+	})
 
-	return fmt.Sprintf("map{%s}", strings.Join(parts, ", "))
+	return keys
 }
 
 type EnumVariant struct {
@@ -559,6 +818,24 @@ func (e EnumValue) String() string {
 	return fmt.Sprintf("%s.%s{%s}", e.Enum.Name, e.Variant.Name, e.Variant.Value.String())
 }
 
+// VariantValue is a tagged value made by variant(tag, payload)
+type VariantValue struct {
+	Tag     string
+	Payload Value
+}
+
+func (v VariantValue) Type() ValueType {
+	return VARIANT
+}
+
+func (v VariantValue) String() string {
+	if _, ok := v.Payload.(NilValue); ok {
+		return v.Tag
+	}
+
+	return fmt.Sprintf("%s(%s)", v.Tag, v.Payload.String())
+}
+
 type TypeValue struct {
 	TypeInfo *TypeInfo
 }
@@ -723,6 +1000,8 @@ func (i *Interpreter) resolveTypeNode(t parser.TypeNode) (*TypeInfo, error) {
 	case *parser.StructType:
 		// anonymous struct type
 		fields := make(map[string]*TypeInfo)
+		order := make([]string, 0, len(tn.Fields))
+		fieldTypes := make([]string, 0, len(tn.Fields))
 
 		for _, f := range tn.Fields {
 			ft, err := i.resolveTypeNode(f.Type)
@@ -730,19 +1009,20 @@ func (i *Interpreter) resolveTypeNode(t parser.TypeNode) (*TypeInfo, error) {
 				return nil, err
 			}
 			fields[f.Name.Value] = ft
+			order = append(order, f.Name.Value)
+			fieldTypes = append(fieldTypes, f.Name.Value+" "+ft.Name)
 		}
 
-		fieldTypes := make([]string, 0)
-		for _, f := range fields {
-			fieldTypes = append(fieldTypes, f.Name)
+		name := "struct{}"
+		if len(fieldTypes) > 0 {
+			name = fmt.Sprintf("struct{ %s }", strings.Join(fieldTypes, "; "))
 		}
 
-		name := fmt.Sprintf("struct{ %s }", strings.Join(fieldTypes, ", "))
-
 		return &TypeInfo{
-			Name:   name,
-			Kind:   TypeStruct,
-			Fields: fields,
+			Name:       name,
+			Kind:       TypeStruct,
+			Fields:     fields,
+			FieldOrder: order,
 		}, nil
 
 	case *parser.InterfaceType:
@@ -949,6 +1229,10 @@ func valuesEqual(a, b Value) bool {
 
 		return valuesEqual(av.Variant.Value, bv.Variant.Value)
 
+	case VariantValue:
+		bv, ok := b.(VariantValue)
+		return ok && av.Tag == bv.Tag && valuesEqual(UnwrapFully(av.Payload), UnwrapFully(bv.Payload))
+
 	case *PointerValue:
 		bv, ok := b.(*PointerValue)
 		return ok && av.Target == bv.Target
@@ -991,6 +1275,8 @@ func valueTypeOf(ti *TypeInfo) ValueType {
 		return FUNCTION
 	case TypeChannel:
 		return CHAN
+	case TypeVariant:
+		return VARIANT
 	default:
 		return NIL
 	}
@@ -1050,6 +1336,8 @@ func (i *Interpreter) TypeInfoFromValue(v Value) *TypeInfo {
 		return v.TypeInfo
 	case EnumValue:
 		return v.Enum
+	case VariantValue:
+		return i.TypeEnv["variant"].TypeInfo
 	case NamedValue:
 		return v.TypeName
 	case *Channel:
@@ -1075,6 +1363,12 @@ func (i *Interpreter) TypeInfoFromValue(v Value) *TypeInfo {
 			panic("PointerValue ElemType is nil")
 		}
 		return i.pointerTo(v.ElemType)
+	case *BuiltinFunc:
+		if v.TypeName != nil {
+			return v.TypeName
+		}
+
+		return i.TypeEnv["nil"].TypeInfo
 	default:
 		return i.TypeEnv["nil"].TypeInfo
 	}
@@ -1162,6 +1456,8 @@ func (i *Interpreter) defaultValueFromTypeInfo(node parser.Node, ti *TypeInfo) (
 		return NilValue{}, nil
 	case TypeChannel:
 		return NilValue{}, nil
+	case TypeVariant:
+		return NilValue{}, nil
 	case TypeNamed:
 		v, err := i.defaultValueFromTypeInfo(node, ti.Underlying)
 		if err != nil {
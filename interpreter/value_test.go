@@ -0,0 +1,205 @@
+package interpreter
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintingHugeArrayIsCutShort(t *testing.T) {
+	started := time.Now()
+
+	out, err := run(t, "say big = make([]int, 1000000)\nputln(big)\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(out, ", …(+990000 more elements)]\n") {
+		t.Errorf("expected the truncation marker at the end, got %q", out[max(len(out)-60, 0):])
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("printing took %s", elapsed)
+	}
+}
+
+func TestPrintLimitsBelongToTheInterpreter(t *testing.T) {
+	src := "say xs = []int{1, 2, 3, 4}\nputln(xs)\nputln(\"abcdefghijklmnopqrstuvwxyz\")\n"
+
+	var small, large strings.Builder
+
+	limited := New("test.ayla")
+	limited.MaxPrintElements = 2
+	limited.MaxPrintBytes = 10
+	limited.Stdout = &small
+
+	unlimited := New("test.ayla")
+	unlimited.MaxPrintElements = 0
+	unlimited.MaxPrintBytes = 0
+	unlimited.Stdout = &large
+
+	for _, i := range []*Interpreter{limited, unlimited} {
+		if err := runIn(t, i, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := "[1, 2, …(+2 more elements)]\nabcdefghij…(+16 more bytes)\n"; small.String() != want {
+		t.Errorf("limited interpreter printed %q, want %q", small.String(), want)
+	}
+
+	if want := "[1, 2, 3, 4]\nabcdefghijklmnopqrstuvwxyz\n"; large.String() != want {
+		t.Errorf("unlimited interpreter printed %q, want %q", large.String(), want)
+	}
+}
+
+func TestPrintBytesCutsNestedValues(t *testing.T) {
+	xs := make([]Value, 100)
+	for idx := range xs {
+		xs[idx] = StringValue{V: strings.Repeat("x", 100)}
+	}
+
+	out := formatLimited(ArrayValue{Elements: xs}, BoolYesNo, 0, 1000)
+	if len(out) > 1200 {
+		t.Errorf("expected about 1000 bytes, got %d", len(out))
+	}
+
+	if !strings.HasSuffix(out, "more elements)]") {
+		t.Errorf("expected an element marker at the end, got %q", out[len(out)-40:])
+	}
+}
+
+// methodSets reads the non test files of this package and gives the methods of every named type,
+// including the ones it gets from types embedded in it
+func methodSets(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own := map[string]map[string]bool{}
+	embeds := map[string][]string{}
+
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := goparser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					continue
+				}
+
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+
+				if id, ok := recv.(*ast.Ident); ok {
+					if own[id.Name] == nil {
+						own[id.Name] = map[string]bool{}
+					}
+					own[id.Name][d.Name.Name] = true
+				}
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+
+					if own[ts.Name.Name] == nil {
+						own[ts.Name.Name] = map[string]bool{}
+					}
+
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+
+					for _, field := range st.Fields.List {
+						typ := field.Type
+						if star, ok := typ.(*ast.StarExpr); ok {
+							typ = star.X
+						}
+
+						if id, ok := typ.(*ast.Ident); ok && len(field.Names) == 0 {
+							embeds[ts.Name.Name] = append(embeds[ts.Name.Name], id.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var collect func(name string, into map[string]bool, seen map[string]bool)
+	collect = func(name string, into map[string]bool, seen map[string]bool) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		for m := range own[name] {
+			into[m] = true
+		}
+
+		for _, e := range embeds[name] {
+			collect(e, into, seen)
+		}
+	}
+
+	sets := map[string]map[string]bool{}
+	for name := range own {
+		sets[name] = map[string]bool{}
+		collect(name, sets[name], map[string]bool{})
+	}
+
+	return sets
+}
+
+// TestNoValueIsAControlSignal keeps values and signals apart, so a value can never be returned
+// where EvalStatement promises a ControlSignal and slip through a type switch on the signal
+func TestNoValueIsAControlSignal(t *testing.T) {
+	var values, signals []string
+
+	for name, methods := range methodSets(t) {
+		isValue := methods["Type"] && methods["String"]
+		isSignal := methods["signal"]
+
+		if isValue && isSignal {
+			t.Errorf("%s is both a Value and a ControlSignal", name)
+		}
+
+		if isValue {
+			values = append(values, name)
+		}
+		if isSignal {
+			signals = append(signals, name)
+		}
+	}
+
+	// make sure the walk found what it is meant to look at
+	if !slices.Contains(values, "IntValue") || !slices.Contains(values, "NilValue") {
+		t.Errorf("expected IntValue and NilValue among the values, found %v", values)
+	}
+
+	if !slices.Contains(signals, "SignalNone") || !slices.Contains(signals, "SignalReturn") {
+		t.Errorf("expected SignalNone and SignalReturn among the signals, found %v", signals)
+	}
+}
@@ -0,0 +1,38 @@
+package interpreter
+
+import "testing"
+
+// result models a Result type, ok carries the value and err the reason it failed
+const result = `fun divide(a int, b int) (variant) {
+    ayla b == 0 {
+        give variant("err", "division by zero")
+    }
+
+    give variant("ok", a / b)
+}
+
+fun show(r variant) {
+    match r {
+        when ok(v) {
+            putln("result:", v)
+        }
+        when err(msg) {
+            putln("failed:", msg)
+        }
+    }
+}
+`
+
+func TestMatchVariant(t *testing.T) {
+	runScripts(t, []scriptTest{
+		{src: result + "show(divide(10, 2))\n", want: "result: 5\n"},
+		{src: result + "show(divide(1, 0))\n", want: "failed: division by zero\n"},
+		{src: result + "say r = divide(9, 3)\nputln(tagof(r))\n", want: "ok\n"},
+		{src: result + "show(variant(\"none\", nil))\n", wantErr: "no match arm for variant 'none'"},
+		{src: "match variant(\"none\", nil) {\n    when ok(v) {\n        putln(v)\n    }\n    otherwise {\n        putln(\"nothing\")\n    }\n}\n", want: "nothing\n"},
+		{src: "match variant(\"ok\", 1) {\n    when ok {\n        putln(\"no binding\")\n    }\n}\n", want: "no binding\n"},
+		{src: "match 5 {\n    when ok(v) {\n        putln(v)\n    }\n}\n", wantErr: "match expects a variant, got"},
+		{src: "putln(tagof(5))\n", wantErr: "tagof expects a variant"},
+		{src: "say v = variant(\"\", 1)\n", wantErr: "variant tag cannot be empty"},
+	})
+}
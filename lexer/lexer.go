@@ -1,7 +1,12 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/z-sk1/ayla-lang/token"
 )
@@ -10,12 +15,60 @@ type Lexer struct {
 	input        string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune // current char, position and readPosition are byte offsets
 
 	line   int
 	column int
+
+	// byte offset where the token being read starts
+	tokenStart int
+
+	// added to every offset handed out, for input that starts part way into a bigger source
+	base int
+
+	// inside a string a lone \r is part of the text instead of a line break
+	inString bool
+
+	// comments are skipped, but where they were is kept
+	comments []token.Range
+
+	errors []error
+}
+
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Errors returns everything the lexer could not make sense of, in the order it was read
+func (l *Lexer) Errors() []error {
+	return l.errors
+}
+
+// Comments is the span of every comment read so far, in source order
+func (l *Lexer) Comments() []token.Range {
+	return l.comments
+}
+
+// addComment records a comment that started at line, col and ends where the lexer is now
+func (l *Lexer) addComment(line, col int) {
+	l.comments = append(l.comments, token.Range{
+		Start: token.Position{Line: line, Column: col, Offset: l.base + l.tokenStart},
+		End:   token.Position{Line: l.line, Column: l.column, Offset: l.base + l.position},
+	})
 }
 
+func (l *Lexer) addError(line, col int, msg string) {
+	l.errors = append(l.errors, &Error{Message: msg, Line: line, Column: col})
+}
+
+// New lexes input as it is, so token offsets are byte offsets into input, CRLF and lone CR
+// line endings and a leading byte order mark are read past like whitespace
 func New(input string) *Lexer {
 	l := &Lexer{
 		input:  input,
@@ -23,40 +76,75 @@ func New(input string) *Lexer {
 		column: 0,
 	}
 
+	// editors on windows like to start utf-8 files with a byte order mark
+	if strings.HasPrefix(input, "\ufeff") {
+		l.readPosition = len("\ufeff")
+	}
+
 	l.readChar()
 	return l
 }
 
+// NewAt lexes input as if it started at pos in a bigger source, so tokens and errors
+// point into that source, like for an expression an editor picked out of a file
+func NewAt(input string, pos token.Position) *Lexer {
+	l := New(input)
+	l.line, l.column = pos.Line, pos.Column
+	l.base = pos.Offset
+
+	return l
+}
+
+// readChar moves to the next rune, line and column always describe where l.ch is,
+// so a newline belongs to the end of its own line
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
-	l.position = l.readPosition
-	l.readPosition++
 
-	if l.ch == '\n' {
-		l.line++
-		l.column = 0
-	} else {
-		l.column++
+	// CRLF is one line break, and so is a lone CR outside a string
+	if l.ch == '\r' && !l.inString {
+		l.ch = '\n'
+		if strings.HasPrefix(l.input[l.readPosition+1:], "\n") {
+			width = 2
+		}
 	}
+
+	l.position = l.readPosition
+	l.readPosition += width
+	l.column++
 }
 
-func isLetter(ch byte) bool {
+func isLetter(ch rune) bool {
+	if ch >= utf8.RuneSelf {
+		return unicode.IsLetter(ch)
+	}
+
 	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func isIdentStart(ch byte) bool {
+// digits plus the _ separator, the parser rejects misplaced separators
+func isNumberPart(ch rune) bool {
+	return isDigit(ch) || ch == '_'
+}
+
+func isIdentStart(ch rune) bool {
 	return isLetter(ch) || ch == '_'
 }
 
-func isIdentPart(ch byte) bool {
+func isIdentPart(ch rune) bool {
 	return isLetter(ch) || isDigit(ch) || ch == '_'
 }
 
@@ -72,7 +160,19 @@ func (l *Lexer) readIdentifier() string {
 func (l *Lexer) readNumber() string {
 	position := l.position
 
-	for isDigit(l.ch) {
+	// 0x, 0b and 0o prefixed ints, the parser checks the digits are valid
+	if l.ch == '0' && strings.ContainsRune("xXbBoO", l.peekChar()) && l.peekChar() != 0 {
+		l.readChar()
+		l.readChar()
+
+		for isIdentPart(l.ch) {
+			l.readChar()
+		}
+
+		return l.input[position:l.position]
+	}
+
+	for isNumberPart(l.ch) {
 		l.readChar()
 	}
 
@@ -80,14 +180,150 @@ func (l *Lexer) readNumber() string {
 	if l.ch == '.' && isDigit(l.peekChar()) {
 		l.readChar()
 
-		for isDigit(l.ch) {
+		for isNumberPart(l.ch) {
 			l.readChar()
 		}
 	}
 
+	l.readExponent()
+
 	return l.input[position:l.position]
 }
 
+// read an optional exponent like e3 or E-3, a missing digit is left for the parser to report
+func (l *Lexer) readExponent() {
+	if l.ch != 'e' && l.ch != 'E' {
+		return
+	}
+
+	l.readChar()
+
+	if l.ch == '+' || l.ch == '-' {
+		l.readChar()
+	}
+
+	for isNumberPart(l.ch) {
+		l.readChar()
+	}
+}
+
+// numberType picks INT or FLOAT for a number literal, a malformed one is
+// reported and becomes ILLEGAL
+func (l *Lexer) numberType(num string, line, col int) token.TokenType {
+	typ := token.TokenType(token.INT)
+	var err error
+
+	if isFloatLiteral(num) {
+		typ = token.FLOAT
+		_, err = ParseFloatLiteral(num)
+	} else {
+		_, err = ParseIntLiteral(num)
+	}
+
+	if err != nil {
+		l.addError(line, col, err.Error())
+		return token.ILLEGAL
+	}
+
+	return typ
+}
+
+var errSeparator = errors.New("invalid '_' in number literal")
+
+// stripDigitSeparators removes the _ separators from a number literal,
+// each one has to sit between two digits
+func stripDigitSeparators(lit string) (string, error) {
+	if !strings.Contains(lit, "_") {
+		return lit, nil
+	}
+
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+
+	// the prefix isn't a digit, so 0x_ff and 0b_1 are rejected like _100
+	prefix, digits := "", lit
+	if len(lit) > 1 && lit[0] == '0' && strings.ContainsRune("xXbBoO", rune(lit[1])) {
+		prefix, digits = lit[:2], lit[2:]
+
+		isDigit = func(c byte) bool {
+			return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+		}
+	}
+
+	for idx := 0; idx < len(digits); idx++ {
+		if digits[idx] != '_' {
+			continue
+		}
+
+		if idx == 0 || idx == len(digits)-1 || !isDigit(digits[idx-1]) || !isDigit(digits[idx+1]) {
+			return "", errSeparator
+		}
+	}
+
+	return prefix + strings.ReplaceAll(digits, "_", ""), nil
+}
+
+// ParseIntLiteral converts an int literal as written in source, including
+// 0x, 0b and 0o prefixes and _ separators
+func ParseIntLiteral(lit string) (int, error) {
+	digits, err := stripDigitSeparators(lit)
+	if err != nil {
+		return 0, err
+	}
+
+	base := 10
+	if len(digits) > 1 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+
+	val, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("integer literal %s is out of range", lit)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("malformed integer literal %s", lit)
+	}
+
+	return int(val), nil
+}
+
+// ParseFloatLiteral converts a float literal as written in source, including
+// exponents and _ separators
+func ParseFloatLiteral(lit string) (float64, error) {
+	digits, err := stripDigitSeparators(lit)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := strconv.ParseFloat(digits, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("float literal %s is out of range", lit)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("malformed float literal %s", lit)
+	}
+
+	return val, nil
+}
+
+func isFloatLiteral(num string) bool {
+	if len(num) > 1 && num[0] == '0' && strings.ContainsRune("xXbBoO", rune(num[1])) {
+		return false
+	}
+
+	return strings.ContainsAny(num, ".eE")
+}
+
 func (l *Lexer) readFloatStartingWithDot(hadWhiteSpace bool) token.Token {
 	position := l.position
 	line := l.line
@@ -95,13 +331,17 @@ func (l *Lexer) readFloatStartingWithDot(hadWhiteSpace bool) token.Token {
 
 	l.readChar() // consume '.'
 
-	for isDigit(l.ch) {
+	for isNumberPart(l.ch) {
 		l.readChar()
 	}
 
+	l.readExponent()
+
+	num := l.input[position:l.position]
+
 	return token.Token{
-		Type:                token.FLOAT,
-		Literal:             l.input[position:l.position],
+		Type:                l.numberType(num, line, col),
+		Literal:             num,
 		Line:                line,
 		Column:              col,
 		HadWhitespaceBefore: hadWhiteSpace,
@@ -110,53 +350,196 @@ func (l *Lexer) readFloatStartingWithDot(hadWhiteSpace bool) token.Token {
 
 func (l *Lexer) readRawString() string {
 	pos := l.position + 1
+	line, col := l.line, l.column
 
+	l.inString = true
 	for {
 		l.readChar()
 
-		if l.ch == '`' || l.ch == 0 {
+		if l.ch == '`' {
+			break
+		}
+
+		if l.ch == 0 {
+			l.addError(line, col, "unterminated raw string")
 			break
 		}
 	}
 
-	return l.input[pos:l.position]
+	l.inString = false
+
+	str := strings.ReplaceAll(l.input[pos:l.position], "\r\n", "\n")
+	l.readChar() // skip closing backtick
+	return str
 }
 
 func (l *Lexer) readString() string {
+	line, col := l.line, l.column
+
 	// skip the opening quote
+	l.inString = true
 	l.readChar()
 
 	start := l.position
 	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '\\' && l.peekChar() != 0 {
+			if !strings.ContainsRune(`nrt'"\\$`, l.peekChar()) {
+				l.addError(l.line, l.column, fmt.Sprintf("unknown escape sequence '\\%c'", l.peekChar()))
+			}
+
+			l.readChar() // skip escaped char
+		}
 		l.readChar()
 	}
-	str := l.input[start:l.position]
+
+	if l.ch == 0 {
+		l.addError(line, col, "unterminated string")
+	}
+	l.inString = false
+
+	str := strings.ReplaceAll(l.input[start:l.position], "\r\n", "\n")
 	l.readChar() // skip closing quote
 	return str
 }
 
+// readCharLiteral reads a single quoted character like 'a' or '\n',
+// it reports and returns false for an empty, unterminated or multi character literal
+func (l *Lexer) readCharLiteral() (string, bool) {
+	line, col := l.line, l.column
+
+	// skip the opening quote
+	l.readChar()
+
+	start := l.position
+	for l.ch != '\'' && l.ch != '\n' && l.ch != 0 {
+		if l.ch == '\\' && l.peekChar() != 0 && l.peekChar() != '\n' {
+			if !strings.ContainsRune(`nrt'"\\`, l.peekChar()) {
+				l.addError(l.line, l.column, fmt.Sprintf("unknown escape sequence '\\%c'", l.peekChar()))
+			}
+
+			l.readChar() // skip escaped char
+		}
+		l.readChar()
+	}
+
+	raw := l.input[start:l.position]
+
+	if l.ch != '\'' {
+		l.addError(line, col, "unterminated character literal")
+		return raw, false
+	}
+
+	l.readChar() // skip closing quote
+
+	if utf8.RuneCountInString(unescapeString(raw)) != 1 {
+		l.addError(line, col, fmt.Sprintf("character literal must hold exactly one character, got '%s'", raw))
+		return raw, false
+	}
+
+	return raw, true
+}
+
 func unescapeString(s string) string {
-	s = strings.ReplaceAll(s, `\n`, "\n")
-	s = strings.ReplaceAll(s, `\r`, "\r")
-	s = strings.ReplaceAll(s, `\t`, "\t")
-	s = strings.ReplaceAll(s, `\"`, `"`)
-	s = strings.ReplaceAll(s, `\\`, `\`)
-	return s
+	out, _ := unescapeDollars(s)
+	return out
+}
+
+// unescapeDollars unescapes s and also gives back where each \$ ended up, since a '$' written
+// that way is only a dollar sign and never starts a ${}
+func unescapeDollars(s string) (string, []int) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var dollars []int
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case '$':
+			dollars = append(dollars, out.Len())
+			out.WriteByte('$')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case '"':
+			out.WriteByte('"')
+		case '\'':
+			out.WriteByte('\'')
+		case '\\':
+			out.WriteByte('\\')
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String(), dollars
+}
+
+// EscapeString is the inverse of the lexer's unescaping, used when writing
+// a string value back out as source
+func EscapeString(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '$':
+			// a plain ${ would read back as interpolation
+			if i+1 < len(s) && s[i+1] == '{' {
+				out.WriteString(`\$`)
+			} else {
+				out.WriteByte('$')
+			}
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
 }
 
-func (l *Lexer) peekChar() byte {
+// QuoteString wraps s in double quotes with its special characters escaped
+func QuoteString(s string) string {
+	return `"` + EscapeString(s) + `"`
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
-func (l *Lexer) peekSecondChar() byte {
-	if l.readPosition+1 >= len(l.input) {
+func (l *Lexer) peekSecondChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+
+	_, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	if l.readPosition+width >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition+1]
+
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition+width:])
+	return r
 }
 
 func (l *Lexer) skipWhitespace() bool {
@@ -176,26 +559,26 @@ func (l *Lexer) skipSingleLineComment() {
 	}
 }
 
-func (l *Lexer) skipMultiLineComment() {
+func (l *Lexer) skipMultiLineComment() bool {
 	l.readChar() // consume *
 	l.readChar() // move past it
 
 	for {
 		if l.ch == 0 {
-			break
+			return false
 		}
 
 		if l.ch == '*' && l.peekChar() == '/' {
 			l.readChar()
 			l.readChar()
-			break
+			return true
 		}
 
 		l.readChar()
 	}
 }
 
-func (l *Lexer) match(ch byte) bool {
+func (l *Lexer) match(ch rune) bool {
 	if l.peekChar() == ch {
 		l.readChar()
 		return true
@@ -203,77 +586,119 @@ func (l *Lexer) match(ch byte) bool {
 	return false
 }
 
+// NextToken reads the next token and records the span of source it came from
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+
+	end := l.position
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+
+	tok.Offset = l.base + l.tokenStart
+	tok.Length = end - l.tokenStart
+	tok.EndLine, tok.EndColumn = l.line, l.column
+
+	if tok.Type == token.EOF {
+		tok.Length = 0
+		tok.EndLine, tok.EndColumn = tok.Line, tok.Column
+	}
+
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	hadWhiteSpace := l.skipWhitespace()
 
+	// every token is positioned at its first character
+	line, col := l.line, l.column
+	l.tokenStart = l.position
+
 	var tok token.Token
 
 	switch l.ch {
 	case '\n':
-		tok = token.Token{Type: token.NEWLINE, Literal: "NEWLINE", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.NEWLINE, Literal: "NEWLINE", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 
 	case '=':
 		if l.match('=') {
-			tok = token.Token{Type: token.EQ, Literal: "==", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.EQ, Literal: "==", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.ASSIGN, Literal: "=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.ASSIGN, Literal: "=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '+':
 		if l.peekChar() == '+' {
 			l.readChar()
-			tok = token.Token{Type: token.INC, Literal: "++", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.INC, Literal: "++", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('=') {
-			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.PLUS, Literal: "+", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.PLUS, Literal: "+", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 
 	case '-':
 		if l.peekChar() == '-' {
 			l.readChar()
-			tok = token.Token{Type: token.DEC, Literal: "--", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.DEC, Literal: "--", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('=') {
-			tok = token.Token{Type: token.SUB_ASSIGN, Literal: "-=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.SUB_ASSIGN, Literal: "-=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.SUB, Literal: "-", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.SUB, Literal: "-", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 
+	case '#':
+		l.skipSingleLineComment()
+		l.addComment(line, col)
+		return l.nextToken()
 	case ';':
-		tok = token.Token{Type: token.SEMICOLON, Literal: ";", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.SEMICOLON, Literal: ";", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '/':
 		if l.peekChar() == '/' {
 			l.skipSingleLineComment()
-			return l.NextToken()
+			l.addComment(line, col)
+			return l.nextToken()
 		} else if l.peekChar() == '*' {
-			l.skipMultiLineComment()
-			return l.NextToken()
+
+			if !l.skipMultiLineComment() {
+				l.addError(line, col, "unterminated block comment")
+				return token.Token{Type: token.ILLEGAL, Literal: "unterminated block comment", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
+			}
+			l.addComment(line, col)
+			return l.nextToken()
 		} else if l.match('=') {
-			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.SLASH, Literal: "/", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.SLASH, Literal: "/", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 
 	case '"':
-		str := unescapeString(l.readString())
-		tok = token.Token{Type: token.STRING, Literal: str, Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		str, dollars := unescapeDollars(l.readString())
+		tok = token.Token{Type: token.STRING, Literal: str, Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace, EscapedDollars: dollars}
 		return tok
+	case '\'':
+		raw, ok := l.readCharLiteral()
+		if !ok {
+			return token.Token{Type: token.ILLEGAL, Literal: raw, Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
+		}
+		return token.Token{Type: token.CHAR, Literal: unescapeString(raw), Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '`':
-		str := unescapeString(l.readRawString())
-		tok = token.Token{Type: token.STRING, Literal: str, Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		// raw strings keep their backslashes as they are
+		str := l.readRawString()
+		tok = token.Token{Type: token.STRING, Literal: str, Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		return tok
 	case ',':
-		tok = token.Token{Type: token.COMMA, Literal: ",", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.COMMA, Literal: ",", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case ':':
 		if l.match('=') {
-			tok = token.Token{Type: token.WALRUS, Literal: ":=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.WALRUS, Literal: ":=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.COLON, Literal: ":", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.COLON, Literal: ":", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 		
-	case '.':
-		line := l.line
-		col := l.column
+	case '?':
+		tok = token.Token{Type: token.QUESTION, Literal: "?", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 
+	case '.':
 		if isDigit(l.peekChar()) {
 			return l.readFloatStartingWithDot(hadWhiteSpace)
 		}
@@ -288,102 +713,106 @@ func (l *Lexer) NextToken() token.Token {
 			tok = token.Token{Type: token.DOT, Literal: ".", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '*':
-		if l.match('=') {
-			tok = token.Token{Type: token.MUL_ASSIGN, Literal: "*=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		if l.match('*') {
+			if l.match('=') {
+				tok = token.Token{Type: token.POW_ASSIGN, Literal: "**=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
+			} else {
+				tok = token.Token{Type: token.POW, Literal: "**", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
+			}
+		} else if l.match('=') {
+			tok = token.Token{Type: token.MUL_ASSIGN, Literal: "*=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.MUL, Literal: "*", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.MUL, Literal: "*", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '%':
 		if l.match('=') {
-			tok = token.Token{Type: token.MOD_ASSIGN, Literal: "%=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.MOD_ASSIGN, Literal: "%=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.MOD, Literal: "%", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.MOD, Literal: "%", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '<':
 		if l.match('=') {
-			tok = token.Token{Type: token.LTE, Literal: "<=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.LTE, Literal: "<=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('<') {
 			if l.match('=') {
-				tok = token.Token{Type: token.SHL_ASSIGN, Literal: "<<=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+				tok = token.Token{Type: token.SHL_ASSIGN, Literal: "<<=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 			} else {
-				tok = token.Token{Type: token.SHL, Literal: "<<", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+				tok = token.Token{Type: token.SHL, Literal: "<<", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 			}
 		} else if l.match('-') {
-			tok = token.Token{Type: token.ARROW, Literal: "<-", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.ARROW, Literal: "<-", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.LT, Literal: "<", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.LT, Literal: "<", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '>':
 		if l.match('=') {
-			tok = token.Token{Type: token.GTE, Literal: ">=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.GTE, Literal: ">=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('>') {
 			if l.match('=') {
-				tok = token.Token{Type: token.SHR_ASSIGN, Literal: ">>=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+				tok = token.Token{Type: token.SHR_ASSIGN, Literal: ">>=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 			} else {
-				tok = token.Token{Type: token.SHR, Literal: ">>", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+				tok = token.Token{Type: token.SHR, Literal: ">>", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 			}
 		} else {
-			tok = token.Token{Type: token.GT, Literal: ">", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.GT, Literal: ">", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '!':
 		if l.match('=') {
-			tok = token.Token{Type: token.NEQ, Literal: "!=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.NEQ, Literal: "!=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.BANG, Literal: "!", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.BANG, Literal: "!", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '&':
 		if l.match('&') {
-			tok = token.Token{Type: token.LAND, Literal: "&&", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.LAND, Literal: "&&", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('=') {
-			tok = token.Token{Type: token.AND_ASSIGN, Literal: "&=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.AND_ASSIGN, Literal: "&=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.AND, Literal: "&", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.AND, Literal: "&", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '|':
 		if l.match('|') {
-			tok = token.Token{Type: token.LOR, Literal: "||", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.LOR, Literal: "||", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else if l.match('=') {
-			tok = token.Token{Type: token.OR_ASSIGN, Literal: "|=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.OR_ASSIGN, Literal: "|=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.OR, Literal: "|", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.OR, Literal: "|", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case '^':
 		if l.match('=') {
-			tok = token.Token{Type: token.XOR_ASSIGN, Literal: "^=", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.XOR_ASSIGN, Literal: "^=", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.XOR, Literal: "^", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			tok = token.Token{Type: token.XOR, Literal: "^", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	case 0:
-		tok = token.Token{Type: token.EOF, Literal: "", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.EOF, Literal: "", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '(':
-		tok = token.Token{Type: token.LPAREN, Literal: "(", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.LPAREN, Literal: "(", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case ')':
-		tok = token.Token{Type: token.RPAREN, Literal: ")", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.RPAREN, Literal: ")", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '{':
-		tok = token.Token{Type: token.LBRACE, Literal: "{", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.LBRACE, Literal: "{", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '}':
-		tok = token.Token{Type: token.RBRACE, Literal: "}", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.RBRACE, Literal: "}", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case '[':
-		tok = token.Token{Type: token.LBRACKET, Literal: "[", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.LBRACKET, Literal: "[", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	case ']':
-		tok = token.Token{Type: token.RBRACKET, Literal: "]", Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+		tok = token.Token{Type: token.RBRACKET, Literal: "]", Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 	default:
 		if isIdentStart(l.ch) {
 			literal := l.readIdentifier()
 			tok.Type = token.LookupIdent(literal)
 			tok.Literal = literal
-			tok.Line = l.line
-			tok.Column = l.column
+			tok.Line = line
+			tok.Column = col
 			tok.HadWhitespaceBefore = hadWhiteSpace
 			return tok
 		} else if isDigit(l.ch) {
 			num := l.readNumber()
-			if strings.Contains(num, ".") {
-				return token.Token{Type: token.FLOAT, Literal: num, Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
-			}
-			return token.Token{Type: token.INT, Literal: num, Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			return token.Token{Type: l.numberType(num, line, col), Literal: num, Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		} else {
-			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.ch), Line: l.line, Column: l.column, HadWhitespaceBefore: hadWhiteSpace}
+			l.addError(line, col, fmt.Sprintf("unexpected character %q", l.ch))
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.ch), Line: line, Column: col, HadWhitespaceBefore: hadWhiteSpace}
 		}
 	}
 
@@ -0,0 +1,267 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/token"
+)
+
+// tokens reads every token of src up to and including EOF
+func tokens(src string) []token.Token {
+	l := New(src)
+
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+
+		if tok.Type == token.EOF {
+			return toks
+		}
+	}
+}
+
+func TestOffsetsPointIntoOriginalSource(t *testing.T) {
+	sources := []string{
+		"say x = 1\nputln(x)\n",
+		"say x = 1\r\nputln(x)\r\n",
+		"say x = 1\rputln(x)\r",
+		"\ufeffsay x = 1\r\nputln(x)\r\n",
+	}
+
+	for _, src := range sources {
+		for _, tok := range tokens(src) {
+			if tok.Type != token.IDENT && tok.Type != token.INT {
+				continue
+			}
+
+			if got := src[tok.Offset : tok.Offset+tok.Length]; got != tok.Literal {
+				t.Errorf("%q: %s at offset %d covers %q", src, tok.Literal, tok.Offset, got)
+			}
+		}
+	}
+}
+
+func TestLineEndingsCountAsOneLine(t *testing.T) {
+	for _, src := range []string{"a\nb\nc", "a\r\nb\r\nc", "a\rb\rc", "\ufeffa\r\nb\rc"} {
+		var lines []int
+		for _, tok := range tokens(src) {
+			if tok.Type == token.IDENT {
+				lines = append(lines, tok.Line)
+
+				if tok.Column != 1 {
+					t.Errorf("%q: %s at column %d, want 1", src, tok.Literal, tok.Column)
+				}
+			}
+		}
+
+		if len(lines) != 3 || lines[0] != 1 || lines[1] != 2 || lines[2] != 3 {
+			t.Errorf("%q: identifiers on lines %v, want [1 2 3]", src, lines)
+		}
+	}
+}
+
+func TestLineEndingsInStrings(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"\"a\rb\"", "a\rb"},
+		{"\"a\r\nb\"", "a\nb"},
+		{"`a\rb`", "a\rb"},
+		{"`a\r\nb`", "a\nb"},
+		{"\"a\\rb\"", "a\rb"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.src).NextToken()
+		if tok.Type != token.STRING || tok.Literal != tt.want {
+			t.Errorf("%q: got %s %q, want %q", tt.src, tok.Type, tok.Literal, tt.want)
+		}
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []token.TokenType
+	}{
+		{"a & b", []token.TokenType{token.IDENT, token.AND, token.IDENT, token.EOF}},
+		{"a | b", []token.TokenType{token.IDENT, token.OR, token.IDENT, token.EOF}},
+		{"a && b", []token.TokenType{token.IDENT, token.LAND, token.IDENT, token.EOF}},
+		{"a || b", []token.TokenType{token.IDENT, token.LOR, token.IDENT, token.EOF}},
+		{"a &= b", []token.TokenType{token.IDENT, token.AND_ASSIGN, token.IDENT, token.EOF}},
+		{"a |= b", []token.TokenType{token.IDENT, token.OR_ASSIGN, token.IDENT, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		toks := tokens(tt.src)
+		if len(toks) != len(tt.want) {
+			t.Fatalf("%q: got %d tokens, want %d", tt.src, len(toks), len(tt.want))
+		}
+
+		for n, tok := range toks {
+			if tok.Type != tt.want[n] {
+				t.Errorf("%q: token %d is %s, want %s", tt.src, n, tok.Type, tt.want[n])
+			}
+		}
+
+		op := toks[1]
+		if op.Line != 1 || op.Column != 3 {
+			t.Errorf("%q: %s at %d:%d, want 1:3", tt.src, op.Literal, op.Line, op.Column)
+		}
+
+		if right := toks[2]; right.Column != 4+len(op.Literal) {
+			t.Errorf("%q: %s at column %d, want %d", tt.src, right.Literal, right.Column, 4+len(op.Literal))
+		}
+	}
+}
+
+func TestUnknownCharacterIsIllegal(t *testing.T) {
+	l := New("say x = 1 @ 2")
+
+	var illegal token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			illegal = tok
+		}
+	}
+
+	if illegal.Literal != "@" || illegal.Line != 1 || illegal.Column != 11 {
+		t.Errorf("got %s %q at %d:%d, want ILLEGAL \"@\" at 1:11", illegal.Type, illegal.Literal, illegal.Line, illegal.Column)
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "unexpected character '@'") {
+		t.Errorf("expected one unexpected character error, got %v", errs)
+	}
+}
+
+func TestUnicodeIdentifiersAndStrings(t *testing.T) {
+	src := "say café = \"🙂 ünï ${naïve}\"\nsay Σx = 1"
+
+	tests := []struct {
+		typ     token.TokenType
+		literal string
+		line    int
+		column  int
+	}{
+		{token.VAR, "say", 1, 1},
+		{token.IDENT, "café", 1, 5},
+		{token.ASSIGN, "=", 1, 10},
+		{token.STRING, "🙂 ünï ${naïve}", 1, 12},
+		{token.NEWLINE, "NEWLINE", 1, 28},
+		{token.VAR, "say", 2, 1},
+		{token.IDENT, "Σx", 2, 5},
+		{token.ASSIGN, "=", 2, 8},
+		{token.INT, "1", 2, 10},
+		{token.EOF, "", 2, 11},
+	}
+
+	toks := tokens(src)
+	if len(toks) != len(tests) {
+		t.Fatalf("got %d tokens, want %d: %v", len(toks), len(tests), toks)
+	}
+
+	for n, tt := range tests {
+		tok := toks[n]
+		if tok.Type != tt.typ || tok.Literal != tt.literal || tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("token %d: got %s %q at %d:%d, want %s %q at %d:%d", n, tok.Type, tok.Literal, tok.Line, tok.Column, tt.typ, tt.literal, tt.line, tt.column)
+		}
+
+		// offsets are bytes even though columns count characters
+		if tok.Type == token.IDENT && src[tok.Offset:tok.Offset+tok.Length] != tok.Literal {
+			t.Errorf("%s covers %q", tok.Literal, src[tok.Offset:tok.Offset+tok.Length])
+		}
+	}
+}
+
+func TestPositionsAfterMultiLineString(t *testing.T) {
+	for _, src := range []string{
+		"say s = \"a\n  ${x}\nb\"\nsay y = 1",
+		"say s = `a\n  ${x}\nb`\nsay y = 1",
+		"say s = \"a\r\n  ${x}\r\nb\"\r\nsay y = 1",
+	} {
+		var y token.Token
+		for _, tok := range tokens(src) {
+			if tok.Type == token.STRING && tok.Literal != "a\n  ${x}\nb" {
+				t.Errorf("%q: string is %q", src, tok.Literal)
+			}
+
+			if tok.Literal == "y" {
+				y = tok
+			}
+		}
+
+		if y.Line != 4 || y.Column != 5 {
+			t.Errorf("%q: y at %d:%d, want 4:5", src, y.Line, y.Column)
+		}
+	}
+}
+
+func TestRawStringsKeepBackslashes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"`C:\\new\\folder`", `C:\new\folder`},
+		{"`tab\\t and quote \\\"`", `tab\t and quote \"`},
+		{"`\"double\" quotes`", `"double" quotes`},
+		{"`${x}\\n`", `${x}\n`},
+		// a quoted string still reads its escapes
+		{"\"C:\\\\new\\tx\"", "C:\\new\tx"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.src).NextToken()
+		if tok.Type != token.STRING || tok.Literal != tt.want {
+			t.Errorf("%s: got %s %q, want %q", tt.src, tok.Type, tok.Literal, tt.want)
+		}
+	}
+}
+
+func TestPowerIsOneToken(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []token.TokenType
+	}{
+		{"a ** b", []token.TokenType{token.IDENT, token.POW, token.IDENT, token.EOF}},
+		{"a **= b", []token.TokenType{token.IDENT, token.POW_ASSIGN, token.IDENT, token.EOF}},
+		{"a * b", []token.TokenType{token.IDENT, token.MUL, token.IDENT, token.EOF}},
+		{"a *= b", []token.TokenType{token.IDENT, token.MUL_ASSIGN, token.IDENT, token.EOF}},
+		{"a * *b", []token.TokenType{token.IDENT, token.MUL, token.MUL, token.IDENT, token.EOF}},
+		{"a***b", []token.TokenType{token.IDENT, token.POW, token.MUL, token.IDENT, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		toks := tokens(tt.src)
+		if len(toks) != len(tt.want) {
+			t.Fatalf("%q: got %d tokens, want %d", tt.src, len(toks), len(tt.want))
+		}
+
+		for n, tok := range toks {
+			if tok.Type != tt.want[n] {
+				t.Errorf("%q: token %d is %s, want %s", tt.src, n, tok.Type, tt.want[n])
+			}
+		}
+	}
+}
+
+func TestPrefixedIntegersAreOneToken(t *testing.T) {
+	for _, src := range []string{"0xFF", "0b1010", "0o17", "0XaB_cd"} {
+		toks := tokens(src)
+		if len(toks) != 2 || toks[0].Type != token.INT || toks[0].Literal != src {
+			t.Errorf("%q: got %v, want one INT token", src, toks)
+		}
+	}
+}
+
+func TestExponentStaysInFloat(t *testing.T) {
+	for _, src := range []string{"1e3", "1.5e3", "2.5e-3", "1E+2"} {
+		toks := tokens(src)
+		if len(toks) != 2 || toks[0].Type != token.FLOAT || toks[0].Literal != src {
+			t.Errorf("%q: got %v, want one FLOAT token", src, toks)
+		}
+	}
+}
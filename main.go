@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,11 +15,13 @@ import (
 	"math/rand"
 	"strings"
 
+	"github.com/z-sk1/ayla-lang/diagnostics"
 	"github.com/z-sk1/ayla-lang/interpreter"
 	"github.com/z-sk1/ayla-lang/lexer"
 	"github.com/z-sk1/ayla-lang/parser"
 	_ "github.com/z-sk1/ayla-lang/stdlib"
 	"github.com/z-sk1/ayla-lang/token"
+	"golang.org/x/term"
 )
 
 func main() {
@@ -45,10 +48,12 @@ func main() {
 	}
 
 	cmds := []string{
-		"run: ayla run [--debug] [--timed] <file>, runs the ayla script",
+		"run: ayla run [--debug] [--debug-positions] [--timed] [--results] [--bool-style=yesno|truefalse] <file>, runs the ayla script",
 		"build: ayla build <file> [-o <output>], turns the ayla script into a standalone executable",
 		"fmt: ayla fmt <file>, formats the ayla script",
+		"check: ayla check <file>, reports errors and warnings without running the script",
 		"install: ayla run install <url>, installs an ayla module and makes it global",
+		"doc: ayla doc --keywords [--json], lists the keywords and builtins",
 		"--version: ayla --version, returns the current version",
 		"--help: ayla --help, returns all the available commands",
 	}
@@ -62,7 +67,7 @@ func main() {
 	switch os.Args[1] {
 	case "run":
 		if len(os.Args) < 3 {
-			fmt.Println("usage: ayla run [--debug] [--timed] <file>")
+			fmt.Println("usage: ayla run [--debug] [--debug-positions] [--timed] [--results] [--bool-style=yesno|truefalse] <file>")
 			return
 		}
 
@@ -88,6 +93,14 @@ func main() {
 			return
 		}
 
+	case "check":
+		if len(os.Args) < 3 {
+			fmt.Println("usage: ayla check <file>")
+			return
+		}
+
+		check(os.Args[2])
+
 	case "install":
 		if len(os.Args) < 3 {
 			fmt.Println("usage: ayla install <url>")
@@ -96,6 +109,9 @@ func main() {
 
 		install()
 
+	case "doc":
+		doc()
+
 	case "--version":
 		fmt.Println("ayla-lang v1.5.0")
 
@@ -129,15 +145,13 @@ func repl() {
 		program := p.ParseProgram()
 
 		if len(p.Errors()) > 0 {
-			for _, err := range p.Errors() {
-				fmt.Println(err)
-			}
+			report("", line, p.Errors()...)
 			continue
 		}
 
-		val, err := interp.EvalProgram(program)
+		val, err := interp.EvalProgram(program.Statements)
 		if err != nil {
-			fmt.Println(err)
+			report("", line, err)
 			continue
 		}
 		if val != nil {
@@ -165,17 +179,90 @@ func readSourceFile(name string) (string, string, error) {
 	return "", "", fmt.Errorf("file not found: %s (.ayla or .ayl)", name)
 }
 
+type keywordDoc struct {
+	Control     []string `json:"control"`
+	Declaration []string `json:"declaration"`
+	Types       []string `json:"types"`
+	Literals    []string `json:"literals"`
+	Builtins    []string `json:"builtins"`
+}
+
+func doc() {
+	keywords := false
+	asJSON := false
+
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--keywords":
+			keywords = true
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Println("unknown flag: " + arg)
+			return
+		}
+	}
+
+	if !keywords {
+		fmt.Println("usage: ayla doc --keywords [--json]")
+		return
+	}
+
+	d := keywordDoc{
+		Control:     token.ControlKeywords(),
+		Declaration: token.DeclarationKeywords(),
+		Types:       token.TypeKeywords(),
+		Literals:    token.LiteralKeywords(),
+		Builtins:    interpreter.BuiltinNames(),
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Println("control: " + strings.Join(d.Control, " "))
+	fmt.Println("declaration: " + strings.Join(d.Declaration, " "))
+	fmt.Println("types: " + strings.Join(d.Types, " "))
+	fmt.Println("literals: " + strings.Join(d.Literals, " "))
+	fmt.Println("builtins: " + strings.Join(d.Builtins, " "))
+}
+
 func run() {
 	debug := false
+	debugPositions := false
 	timed := false
+	results := false
+	boolStyle := interpreter.BoolYesNo
 	filename := ""
 
 	for _, arg := range os.Args[2:] {
+		if name, ok := strings.CutPrefix(arg, "--bool-style="); ok {
+			style, err := interpreter.ParseBoolStyle(name)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			boolStyle = style
+			continue
+		}
+
 		switch arg {
 		case "--timed":
 			timed = true
 		case "--debug":
 			debug = true
+		case "--debug-positions":
+			debugPositions = true
+		case "--results":
+			results = true
 		default:
 			filename = arg
 		}
@@ -202,16 +289,19 @@ func run() {
 
 	l := lexer.New(source)
 	p := parser.New(l)
+	p.File = name
 
 	program := p.ParseProgram()
 	if debug {
-		fmt.Printf("AST: %#v\n", program)
+		fmt.Printf("AST:\n%s\n", program)
+	}
+
+	if debugPositions {
+		fmt.Print(parser.DumpPositions(program.Statements))
 	}
 
 	if len(p.Errors()) > 0 {
-		for _, err := range p.Errors() {
-			fmt.Printf("%s: %v\n", name, err)
-		}
+		report(name, source, p.Errors()...)
 		return
 	}
 
@@ -222,26 +312,28 @@ func run() {
 	}
 
 	interp := interpreter.New(name)
+	interp.PrintResults = results
+	interp.BoolStyle = boolStyle
 
-	if err := interp.RegisterForward(program); err != nil {
-		fmt.Printf("\n%s: %v\n", name, err)
+	if err := interp.RegisterForward(program.Statements); err != nil {
+		report(name, source, err)
 		return
 	}
 
-	if err := interp.ResolveTypes(program); err != nil {
-		fmt.Printf("\n%s: %v\n", name, err)
+	if err := interp.ResolveTypes(program.Statements); err != nil {
+		report(name, source, err)
 		return
 	}
 
-	if err := interp.TypeCheck(program); err != nil {
-		fmt.Printf("\n%s: %v\n", name, err)
+	if err := interp.TypeCheck(program.Statements); err != nil {
+		report(name, source, err)
 		return
 	}
 
-	_, err = interp.EvalStatements(program)
+	_, err = interp.EvalStatements(program.Statements)
 
 	if err != nil {
-		fmt.Printf("\n%s: %v\n", name, err)
+		report(name, source, err)
 		return
 	}
 	
@@ -255,6 +347,48 @@ func run() {
 	}
 }
 
+// check does everything run does before evaluating, then lints the program
+func check(filename string) {
+	source, name, err := readSourceFile(filename)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p := parser.New(lexer.New(source))
+	p.File = name
+
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		report(name, source, p.Errors()...)
+		return
+	}
+
+	interp := interpreter.New(name)
+
+	if err := interp.RegisterForward(program.Statements); err != nil {
+		report(name, source, err)
+		return
+	}
+
+	if err := interp.ResolveTypes(program.Statements); err != nil {
+		report(name, source, err)
+		return
+	}
+
+	if err := interp.TypeCheck(program.Statements); err != nil {
+		report(name, source, err)
+		return
+	}
+
+	warnings := []error{}
+	for _, w := range interp.Lint(program.Statements) {
+		warnings = append(warnings, w)
+	}
+
+	report(name, source, warnings...)
+}
+
 func runEmbedded(source string) {
 	exe, err := os.Executable()
 	if err != nil {
@@ -268,32 +402,30 @@ func runEmbedded(source string) {
 	program := p.ParseProgram()
 
 	if len(p.Errors()) > 0 {
-		for _, err := range p.Errors() {
-			fmt.Println(err)
-		}
+		report("", source, p.Errors()...)
 		return
 	}
 
 	interp := interpreter.New(exe)
 
-	if err := interp.RegisterForward(program); err != nil {
-		fmt.Println(err)
+	if err := interp.RegisterForward(program.Statements); err != nil {
+		report("", source, err)
 		return
 	}
 
-	if err := interp.ResolveTypes(program); err != nil {
-		fmt.Println(err)
+	if err := interp.ResolveTypes(program.Statements); err != nil {
+		report("", source, err)
 		return
 	}
 
-	if err := interp.TypeCheck(program); err != nil {
-		fmt.Println(err)
+	if err := interp.TypeCheck(program.Statements); err != nil {
+		report("", source, err)
 		return
 	}
 
-	_, err = interp.EvalStatements(program)
+	_, err = interp.EvalStatements(program.Statements)
 	if err != nil {
-		fmt.Println(err)
+		report("", source, err)
 	}
 }
 
@@ -382,20 +514,41 @@ func runFmt(path string) error {
 
 	l := lexer.New(string(src))
 	p := parser.New(l)
+	p.File = name
 	program := p.ParseProgram()
 
 	if len(p.Errors()) > 0 {
-		for _, e := range p.Errors() {
-			fmt.Println(e)
-		}
+		report(name, src, p.Errors()...)
 		return fmt.Errorf("parse failed")
 	}
 
+	// the formatter has nowhere to put comments yet, so writing would lose them
+	if len(program.Comments) > 0 {
+		return fmt.Errorf("fmt: %s has comments, which fmt would drop, it was left unchanged", name)
+	}
+
 	out := parser.FormatProgram(program)
 
+	// never write something that reads back differently, or that would format differently again
+	check := parser.New(lexer.New(out))
+	check.File = name
+	formatted := check.ParseProgram()
+
+	if len(check.Errors()) > 0 || !parser.Equal(program, formatted) || parser.FormatProgram(formatted) != out {
+		return fmt.Errorf("fmt: the formatted program does not match %s, it was left unchanged", name)
+	}
+
 	return os.WriteFile(name, []byte(out), 0644)
 }
 
+// report prints errors through the shared diagnostics renderer, with the source line under each one
+func report(file, source string, errs ...error) {
+	diagnostics.RenderAll(os.Stdout, diagnostics.Clean(diagnostics.FromErrors(file, errs)), diagnostics.RenderOptions{
+		Color:  term.IsTerminal(int(os.Stdout.Fd())),
+		Source: source,
+	})
+}
+
 func normalizeGitHubURL(url string) string {
 	if strings.Contains(url, "github.com") && !strings.Contains(url, "raw.githubusercontent.com") {
 		url = strings.Replace(url, "github.com", "raw.githubusercontent.com", 1)
@@ -2,14 +2,17 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/z-sk1/ayla-lang/lexer"
 	"github.com/z-sk1/ayla-lang/token"
 )
 
 type Node interface {
 	Pos() (int, int)
+	End() (int, int)
 	Format(*Formatter) string
 }
 
@@ -28,19 +31,32 @@ type Expression interface {
 
 type Formatter struct {
 	Indent int
+
+	// Parens wraps every infix expression in parentheses so the shape of the tree shows
+	Parens bool
 }
 
 func (f *Formatter) identStr() string {
 	return strings.Repeat("    ", f.Indent)
 }
 
-func FormatProgram(stmts []Statement) string {
-	f := &Formatter{}
+// Program is the root of a parsed file, with what belongs to the file as a whole
+type Program struct {
+	NodeBase
+	File       string
+	Statements []Statement
+
+	// Comments are the spans of every comment in the file, in source order
+	Comments []token.Range
+}
+
+// Format keeps up to one blank line between top level statements where the source had them
+func (p *Program) Format(f *Formatter) string {
 	var out strings.Builder
 	prevLine := 0
 
-	for i, stmt := range stmts {
-		line, _ := stmt.Pos()
+	for i, stmt := range p.Statements {
+		line := startLine(stmt)
 
 		if i > 0 {
 			diff := line - prevLine
@@ -55,12 +71,30 @@ func FormatProgram(stmts []Statement) string {
 		}
 
 		out.WriteString(stmt.Format(f))
-		prevLine = line
+		prevLine, _ = stmt.End()
 	}
 
 	return out.String()
 }
 
+// startLine is the first line any part of a statement is on, a statement's own token is not always its first
+func startLine(stmt Statement) int {
+	first, _ := stmt.Pos()
+
+	Inspect([]Statement{stmt}, func(n Node) bool {
+		if line, _ := n.Pos(); line > 0 && line < first {
+			first = line
+		}
+		return true
+	})
+
+	return first
+}
+
+func FormatProgram(p *Program) string {
+	return p.Format(&Formatter{})
+}
+
 func formatBlock(f *Formatter, stmts []Statement) string {
 	var out strings.Builder
 
@@ -94,17 +128,280 @@ func (f *Formatter) formatExprList(exprs []Expression) string {
 	return strings.Join(parts, ", ")
 }
 
+// DumpPositions lists every node with its line:column and where it ends, indented by nesting,
+// so a node that points at the wrong token is easy to spot
+func DumpPositions(stmts []Statement) string {
+	var out strings.Builder
+
+	for _, stmt := range stmts {
+		dumpPositions(&out, reflect.ValueOf(stmt), 0)
+	}
+
+	return out.String()
+}
+
+var nodeInterface = reflect.TypeOf((*Node)(nil)).Elem()
+
+func dumpPositions(out *strings.Builder, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return
+	}
+
+	if v.Type().Implements(nodeInterface) {
+		line, col := v.Interface().(Node).Pos()
+
+		name := v.Type().String()
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "*"), "parser.")
+
+		endLine, endCol := v.Interface().(Node).End()
+
+		fmt.Fprintf(out, "%s%s %d:%d-%d:%d", strings.Repeat("  ", depth), name, line, col, endLine, endCol)
+		if lit := nodeLiteral(v); lit != "" {
+			fmt.Fprintf(out, " %q", lit)
+		}
+		out.WriteString("\n")
+
+		depth++
+	}
+
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for idx := 0; idx < v.NumField(); idx++ {
+			field := v.Type().Field(idx)
+			if !field.IsExported() || field.Type == reflect.TypeOf(NodeBase{}) {
+				continue
+			}
+
+			dumpPositions(out, v.Field(idx), depth)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < v.Len(); idx++ {
+			dumpPositions(out, v.Index(idx), depth)
+		}
+	}
+}
+
+// Inspect calls fn for every node under stmts, parents before children,
+// returning false from fn skips that node's children
+func Inspect(stmts []Statement, fn func(Node) bool) {
+	for _, stmt := range stmts {
+		inspect(reflect.ValueOf(stmt), fn)
+	}
+}
+
+func inspect(v reflect.Value, fn func(Node) bool) {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return
+	}
+
+	if v.Type().Implements(nodeInterface) && !fn(v.Interface().(Node)) {
+		return
+	}
+
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for idx := 0; idx < v.NumField(); idx++ {
+			field := v.Type().Field(idx)
+			if !field.IsExported() || field.Type == reflect.TypeOf(NodeBase{}) {
+				continue
+			}
+
+			inspect(v.Field(idx), fn)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < v.Len(); idx++ {
+			inspect(v.Index(idx), fn)
+		}
+	}
+}
+
+// Equal reports whether two trees say the same thing, positions and comments are not compared
+func Equal(a, b Node) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+var (
+	nodeBaseType = reflect.TypeOf(NodeBase{})
+	bracesType   = reflect.TypeOf(Braces{})
+	commentsType = reflect.TypeOf([]token.Range{})
+)
+
+func equalValues(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+
+		return equalValues(a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		for idx := 0; idx < a.NumField(); idx++ {
+			switch a.Type().Field(idx).Type {
+			case nodeBaseType, bracesType, commentsType:
+				continue
+			}
+
+			if !equalValues(a.Field(idx), b.Field(idx)) {
+				return false
+			}
+		}
+
+		return true
+
+	// a missing list and an empty one are the same
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for idx := 0; idx < a.Len(); idx++ {
+			if !equalValues(a.Index(idx), b.Index(idx)) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		iter := a.MapRange()
+		for iter.Next() {
+			other := b.MapIndex(iter.Key())
+			if !other.IsValid() || !equalValues(iter.Value(), other) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+
+	return false
+}
+
+// nodeLiteral is the literal of the token a node was made from, if it has one
+func nodeLiteral(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	base := v.FieldByName("NodeBase")
+	if !base.IsValid() {
+		return ""
+	}
+
+	return base.Interface().(NodeBase).Token.Literal
+}
+
 type NodeBase struct {
 	Token token.Token
+
+	// Last is the final token of the node, like the '}' closing a block or the ')' of a call,
+	// the parser fills it in once the node is parsed
+	Last token.Token
 }
 
 func (n *NodeBase) Pos() (int, int) {
 	return n.Token.Line, n.Token.Column
 }
 
+// End is just past the last character of the node, nodes the parser never finished end with their token
+func (n *NodeBase) End() (int, int) {
+	if n.Last.Type == "" {
+		return n.Token.EndLine, n.Token.EndColumn
+	}
+
+	return n.Last.EndLine, n.Last.EndColumn
+}
+
+// Range is the span of the whole node, with byte offsets
+func (n *NodeBase) Range() token.Range {
+	r := n.Token.Range()
+	if n.Last.Type != "" {
+		r.End = n.Last.Range().End
+	}
+
+	return r
+}
+
+func (n *NodeBase) setLast(tok token.Token) {
+	n.Last = tok
+}
+
+// leftPos is where a node that opens with another expression starts, like a + b or p.x,
+// its own token is the operator in the middle so the start comes from the expression on the left
+func leftPos(left Node, n *NodeBase) (int, int) {
+	if isNil(left) {
+		return n.Pos()
+	}
+
+	return left.Pos()
+}
+
+// leftRange is Range for the same nodes as leftPos
+func leftRange(left Node, n *NodeBase) token.Range {
+	r := n.Range()
+	if lr, ok := left.(interface{ Range() token.Range }); ok && !isNil(left) {
+		r.Start = lr.Range().Start
+	}
+
+	return r
+}
+
 const (
 	_ int = iota
 	LOWEST
+	TERNARY     // ? :
 	LOR         // ||
 	LAND        // &&
 	BITOR       // |
@@ -116,6 +413,7 @@ const (
 	SHIFT       // << >>
 	SUM         // + -
 	PRODUCT     // * /
+	POWER       // **
 	PREFIX      // !x -z
 	MEMBER      // p.x
 	CALL        // ()
@@ -124,6 +422,8 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
+	token.QUESTION: TERNARY,
+
 	token.LOR:  LOR,
 	token.LAND: LAND,
 
@@ -151,6 +451,8 @@ var precedences = map[token.TokenType]int{
 	token.SLASH: PRODUCT,
 	token.MOD:   PRODUCT,
 
+	token.POW: POWER,
+
 	token.DOT:      MEMBER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
@@ -169,7 +471,7 @@ type VarStatement struct {
 }
 
 func (v *VarStatement) Format(f *Formatter) string {
-	out := "egg " + v.Name.Format(f)
+	out := "say " + v.Name.Format(f)
 
 	if v.Lifetime != nil {
 		out += "<" + v.Lifetime.Format(f) + ">"
@@ -273,7 +575,7 @@ func formatVarDeclNoKeyword(stmt Statement, f *Formatter) string {
 func (v *VarStatementBlock) Format(f *Formatter) string {
 	var out strings.Builder
 
-	out.WriteString("egg (\n")
+	out.WriteString("say (\n")
 
 	f.Indent++
 
@@ -326,7 +628,7 @@ func (m *MultiVarStatement) Format(f *Formatter) string {
 		names = append(names, n.Format(f))
 	}
 
-	out := "egg " + strings.Join(names, ", ")
+	out := "say " + strings.Join(names, ", ")
 
 	if m.Lifetime != nil {
 		out += "<" + m.Lifetime.Format(f) + ">"
@@ -378,7 +680,7 @@ type ConstStatement struct {
 }
 
 func (v *ConstStatement) Format(f *Formatter) string {
-	out := "rock " + v.Name.Format(f)
+	out := "keep " + v.Name.Format(f)
 
 	if v.Lifetime != nil {
 		out += "<" + v.Lifetime.Format(f) + ">"
@@ -402,7 +704,7 @@ type ConstStatementBlock struct {
 
 func (c *ConstStatementBlock) Format(f *Formatter) string {
 	var out strings.Builder
-	out.WriteString("rock (\n")
+	out.WriteString("keep (\n")
 
 	f.Indent++
 	for _, d := range c.Decls {
@@ -430,7 +732,7 @@ func (m *MultiConstStatement) Format(f *Formatter) string {
 		names = append(names, n.Format(f))
 	}
 
-	out := "rock " + strings.Join(names, ", ")
+	out := "keep " + strings.Join(names, ", ")
 
 	if m.Lifetime != nil {
 		out += "<" + m.Lifetime.Format(f) + ">"
@@ -547,6 +849,14 @@ func (t *TypeStatement) Format(f *Formatter) string {
 	)
 }
 
+func (t *TypeAssertExpression) Pos() (int, int) {
+	return leftPos(t.Expr, &t.NodeBase)
+}
+
+func (t *TypeAssertExpression) Range() token.Range {
+	return leftRange(t.Expr, &t.NodeBase)
+}
+
 type StructType struct {
 	NodeBase
 	Fields []*StructField
@@ -661,6 +971,10 @@ type InterfaceType struct {
 func (*InterfaceType) typeNode() {}
 
 func (i *InterfaceType) Format(f *Formatter) string {
+	if len(i.Methods) == 0 {
+		return "interface{}"
+	}
+
 	var out strings.Builder
 
 	out.WriteString("interface {\n")
@@ -694,23 +1008,13 @@ func (ft *FuncType) Format(f *Formatter) string {
 		params = append(params, p.Format(f))
 	}
 
-	out := ""
-
+	// interface methods have a name, a function type on its own starts with fun
+	out := "fun"
 	if ft.Name != nil {
-		out += ft.Name.Format(f)
-	}
-
-	out += "(" + strings.Join(params, ", ") + ")"
-
-	if len(ft.Returns) > 0 {
-		ret := []string{}
-		for _, r := range ft.Returns {
-			ret = append(ret, r.Format(f))
-		}
-		out += " " + strings.Join(ret, ", ")
+		out = ft.Name.Format(f)
 	}
 
-	return out
+	return out + "(" + strings.Join(params, ", ") + ")" + formatReturns(f, ft.Returns)
 }
 
 type PointerType struct {
@@ -752,14 +1056,33 @@ type StartStatement struct {
 }
 
 func (s *StartStatement) Format(f *Formatter) string {
+	if s.Expr != nil {
+		return "start " + s.Expr.Format(f)
+	}
+
 	return "start " + formatBlock(f, s.Body)
 }
 
+// Braces is where a block's '{' and '}' are, Commented is set when a comment sits between them
+type Braces struct {
+	Open      token.Token
+	Close     token.Token
+	Commented bool
+}
+
+// Range runs from the '{' to just past the '}'
+func (b Braces) Range() token.Range {
+	return token.Range{Start: b.Open.Range().Start, End: b.Close.Range().End}
+}
+
 type IfStatement struct {
 	NodeBase
 	Condition   Expression
 	Consequence []Statement
 	Alternative []Statement // optional else block
+
+	ConsequenceBraces Braces
+	AlternativeBraces Braces // left empty for else ayla
 }
 
 func (i *IfStatement) Format(f *Formatter) string {
@@ -769,6 +1092,11 @@ func (i *IfStatement) Format(f *Formatter) string {
 		formatBlock(f, i.Consequence),
 	)
 
+	// elen ayla has no braces of its own
+	if elseIf, ok := i.elseIf(); ok {
+		return out + " elen " + elseIf.Format(f)
+	}
+
 	if len(i.Alternative) > 0 {
 		out += " elen " + formatBlock(f, i.Alternative)
 	}
@@ -776,6 +1104,15 @@ func (i *IfStatement) Format(f *Formatter) string {
 	return out
 }
 
+func (i *IfStatement) elseIf() (*IfStatement, bool) {
+	if len(i.Alternative) != 1 || i.AlternativeBraces.Open.Type != "" {
+		return nil, false
+	}
+
+	next, ok := i.Alternative[0].(*IfStatement)
+	return next, ok
+}
+
 type Param struct {
 	NodeBase
 	Type     TypeNode
@@ -783,6 +1120,36 @@ type Param struct {
 	Variadic bool
 }
 
+// formatParams writes a parameter list, a variadic one is kept as ...T rather than the []T it is parsed into
+func formatParams(f *Formatter, params []*Param) string {
+	parts := make([]string, 0, len(params))
+
+	for _, p := range params {
+		typ := p.Type.Format(f)
+		if arr, ok := p.Type.(*ArrayType); ok && p.Variadic {
+			typ = "..." + arr.Elem.Format(f)
+		}
+
+		parts = append(parts, p.Name.Format(f)+" "+typ)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatReturns writes return types the way they are declared, always in parentheses
+func formatReturns(f *Formatter, types []TypeNode) string {
+	if len(types) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, t.Format(f))
+	}
+
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
 type FuncStatement struct {
 	NodeBase
 	Name        *Identifier
@@ -792,29 +1159,13 @@ type FuncStatement struct {
 }
 
 func (fn *FuncStatement) Format(f *Formatter) string {
-	params := []string{}
-
-	for _, p := range fn.Params {
-		params = append(params,
-			p.Name.Format(f)+" "+p.Type.Format(f),
-		)
-	}
-
 	out := fmt.Sprintf(
-		"fun %s(%s)",
+		"fun %s(%s)%s",
 		fn.Name.Format(f),
-		strings.Join(params, ", "),
+		formatParams(f, fn.Params),
+		formatReturns(f, fn.ReturnTypes),
 	)
 
-	if len(fn.ReturnTypes) > 0 {
-		types := []string{}
-		for _, t := range fn.ReturnTypes {
-			types = append(types, t.Format(f))
-		}
-
-		out += " " + strings.Join(types, ", ")
-	}
-
 	out += " " + formatBlock(f, fn.Body)
 
 	return out
@@ -830,6 +1181,14 @@ func (c *FuncCall) Format(f *Formatter) string {
 	return c.Callee.Format(f) + "(" + f.formatExprList(c.Args) + ")"
 }
 
+func (c *FuncCall) Pos() (int, int) {
+	return leftPos(c.Callee, &c.NodeBase)
+}
+
+func (c *FuncCall) Range() token.Range {
+	return leftRange(c.Callee, &c.NodeBase)
+}
+
 type FuncLiteral struct {
 	NodeBase
 	Params      []*Param
@@ -838,22 +1197,7 @@ type FuncLiteral struct {
 }
 
 func (fl *FuncLiteral) Format(f *Formatter) string {
-	params := []string{}
-	for _, p := range fl.Params {
-		params = append(params,
-			p.Name.Format(f)+" "+p.Type.Format(f),
-		)
-	}
-
-	out := "fun(" + strings.Join(params, ", ") + ")"
-
-	if len(fl.ReturnTypes) > 0 {
-		ret := []string{}
-		for _, t := range fl.ReturnTypes {
-			ret = append(ret, t.Format(f))
-		}
-		out += " " + strings.Join(ret, ", ")
-	}
+	out := "fun(" + formatParams(f, fl.Params) + ")" + formatReturns(f, fl.ReturnTypes)
 
 	out += " " + formatBlock(f, fl.Body)
 
@@ -876,29 +1220,15 @@ type MethodStatement struct {
 }
 
 func (m *MethodStatement) Format(f *Formatter) string {
-	params := []string{}
-	for _, p := range m.Params {
-		params = append(params,
-			p.Name.Format(f)+" "+p.Type.Format(f),
-		)
-	}
-
 	out := fmt.Sprintf(
-		"fun (%s %s) %s(%s)",
+		"fun (%s %s) %s(%s)%s",
 		m.Receiver.Name.Format(f),
 		m.Receiver.Type.Format(f),
 		m.Name.Format(f),
-		strings.Join(params, ", "),
+		formatParams(f, m.Params),
+		formatReturns(f, m.ReturnTypes),
 	)
 
-	if len(m.ReturnTypes) > 0 {
-		ret := []string{}
-		for _, t := range m.ReturnTypes {
-			ret = append(ret, t.Format(f))
-		}
-		out += " " + strings.Join(ret, ", ")
-	}
-
 	out += " " + formatBlock(f, m.Body)
 
 	return out
@@ -910,6 +1240,8 @@ type ForStatement struct {
 	Condition Expression // i < 5;
 	Post      Statement  // i = i + 1
 	Body      []Statement
+
+	BodyBraces Braces
 }
 
 func (fs *ForStatement) Format(f *Formatter) string {
@@ -928,7 +1260,7 @@ func (fs *ForStatement) Format(f *Formatter) string {
 	}
 
 	return fmt.Sprintf(
-		"four %s; %s; %s %s",
+		"for %s; %s; %s %s",
 		init,
 		cond,
 		post,
@@ -942,6 +1274,8 @@ type ForRangeStatement struct {
 	Value *Identifier
 	Expr  Expression
 	Body  []Statement
+
+	BodyBraces Braces
 }
 
 func (fr *ForRangeStatement) Format(f *Formatter) string {
@@ -956,7 +1290,7 @@ func (fr *ForRangeStatement) Format(f *Formatter) string {
 	}
 
 	return fmt.Sprintf(
-		"four %s%s := range %s %s",
+		"for %s%s := range %s %s",
 		key,
 		val,
 		fr.Expr.Format(f),
@@ -968,11 +1302,13 @@ type WhileStatement struct {
 	NodeBase
 	Condition Expression // i < 5
 	Body      []Statement
+
+	BodyBraces Braces
 }
 
 func (w *WhileStatement) Format(f *Formatter) string {
 	return fmt.Sprintf(
-		"why %s %s",
+		"while %s %s",
 		w.Condition.Format(f),
 		formatBlock(f, w.Body),
 	)
@@ -1032,13 +1368,12 @@ func (c *CaseClause) Format(f *Formatter) string {
 		out.WriteString(f.identStr())
 		out.WriteString(s.Format(f))
 		out.WriteString("\n")
-		f.Indent--
-		out.WriteString(f.identStr())
-		f.Indent++
-		out.WriteString("}\n")
 	}
 	f.Indent--
 
+	out.WriteString(f.identStr())
+	out.WriteString("}")
+
 	return out.String()
 }
 
@@ -1057,16 +1392,69 @@ func (d *DefaultClause) Format(f *Formatter) string {
 		out.WriteString(f.identStr())
 		out.WriteString(s.Format(f))
 		out.WriteString("\n")
-		f.Indent--
+	}
+	f.Indent--
+
+	out.WriteString(f.identStr())
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type MatchStatement struct {
+	NodeBase
+	Value   Expression
+	Arms    []*MatchArm
+	Default *DefaultClause
+}
+
+func (m *MatchStatement) Format(f *Formatter) string {
+	var out strings.Builder
+
+	out.WriteString("match ")
+	out.WriteString(m.Value.Format(f))
+	out.WriteString(" {\n")
+
+	f.Indent++
+
+	for _, a := range m.Arms {
+		out.WriteString(f.identStr())
+		out.WriteString(a.Format(f))
+		out.WriteString("\n")
+	}
+
+	if m.Default != nil {
 		out.WriteString(f.identStr())
-		f.Indent++
-		out.WriteString("}\n")
+		out.WriteString("otherwise ")
+		out.WriteString(formatBlock(f, m.Default.Body))
+		out.WriteString("\n")
 	}
+
 	f.Indent--
 
+	out.WriteString(f.identStr())
+	out.WriteString("}")
+
 	return out.String()
 }
 
+// MatchArm is a `when tag(binding) { ... }` arm, the binding is optional
+type MatchArm struct {
+	NodeBase
+	Tag     *Identifier
+	Binding *Identifier
+	Body    []Statement
+}
+
+func (m *MatchArm) Format(f *Formatter) string {
+	pattern := m.Tag.Value
+	if m.Binding != nil {
+		pattern += "(" + m.Binding.Value + ")"
+	}
+
+	return fmt.Sprintf("when %s %s", pattern, formatBlock(f, m.Body))
+}
+
 type SelectStatement struct {
 	NodeBase
 	Cases   []*SelectCaseClause
@@ -1119,13 +1507,12 @@ func (s *SelectCaseClause) Format(f *Formatter) string {
 		out.WriteString(f.identStr())
 		out.WriteString(stmt.Format(f))
 		out.WriteString("\n")
-		f.Indent--
-		out.WriteString(f.identStr())
-		f.Indent++
-		out.WriteString("}\n")
 	}
 	f.Indent--
 
+	out.WriteString(f.identStr())
+	out.WriteString("}")
+
 	return out.String()
 }
 
@@ -1148,7 +1535,7 @@ type BreakStatement struct {
 }
 
 func (b *BreakStatement) Format(f *Formatter) string {
-	return "kitkat"
+	return "snap"
 }
 
 type ContinueStatement struct {
@@ -1166,9 +1553,9 @@ type ReturnStatement struct {
 
 func (r *ReturnStatement) Format(f *Formatter) string {
 	if len(r.Values) == 0 {
-		return "back"
+		return "give"
 	}
-	return "back " + f.formatExprList(r.Values)
+	return "give " + f.formatExprList(r.Values)
 }
 
 type ImportStatement struct {
@@ -1187,6 +1574,10 @@ type DeferStatement struct {
 }
 
 func (d *DeferStatement) Format(f *Formatter) string {
+	if d.Call == nil {
+		return "defer " + formatBlock(f, d.Body)
+	}
+
 	return "defer " + d.Call.Format(f)
 }
 
@@ -1195,9 +1586,19 @@ type CompositeLiteral struct {
 	Type     TypeNode              // works for Foo, []int, map[string]int, etc.
 	Elements []Expression          // for slice/array
 	Fields   map[string]Expression // for struct
+	Order    []string              // field names in the order they were written
 	Pairs    []MapPair             // for map
 }
 
+// Pos is where the type is written, the node's token is the '{' after it
+func (c *CompositeLiteral) Pos() (int, int) {
+	return leftPos(c.Type, &c.NodeBase)
+}
+
+func (c *CompositeLiteral) Range() token.Range {
+	return leftRange(c.Type, &c.NodeBase)
+}
+
 func (c *CompositeLiteral) Format(f *Formatter) string {
 	var out strings.Builder
 
@@ -1210,8 +1611,8 @@ func (c *CompositeLiteral) Format(f *Formatter) string {
 		elems = append(elems, e.Format(f))
 	}
 
-	for k, v := range c.Fields {
-		elems = append(elems, k+": "+v.Format(f))
+	for _, k := range c.Order {
+		elems = append(elems, k+": "+c.Fields[k].Format(f))
 	}
 
 	for _, p := range c.Pairs {
@@ -1242,15 +1643,27 @@ type SliceExpression struct {
 	NodeBase
 	Left  Expression
 	Start Expression
-	End   Expression
+	Stop  Expression
 }
 
 func (s *SliceExpression) Format(f *Formatter) string {
-	return fmt.Sprintf("%s[%s:%s]",
-		s.Left.Format(f),
-		s.Start.Format(f),
-		s.End.Format(f),
-	)
+	start, end := "", ""
+	if s.Start != nil {
+		start = s.Start.Format(f)
+	}
+	if s.Stop != nil {
+		end = s.Stop.Format(f)
+	}
+
+	return fmt.Sprintf("%s[%s:%s]", s.Left.Format(f), start, end)
+}
+
+func (s *SliceExpression) Pos() (int, int) {
+	return leftPos(s.Left, &s.NodeBase)
+}
+
+func (s *SliceExpression) Range() token.Range {
+	return leftRange(s.Left, &s.NodeBase)
 }
 
 type IndexExpression struct {
@@ -1264,6 +1677,14 @@ func (i *IndexExpression) Format(f *Formatter) string {
 	return fmt.Sprintf("%s[%s]", i.Left.Format(f), i.Index.Format(f))
 }
 
+func (i *IndexExpression) Pos() (int, int) {
+	return leftPos(i.Left, &i.NodeBase)
+}
+
+func (i *IndexExpression) Range() token.Range {
+	return leftRange(i.Left, &i.NodeBase)
+}
+
 type SendExpression struct {
 	NodeBase
 	Channel Expression
@@ -1274,6 +1695,14 @@ func (s *SendExpression) Format(f *Formatter) string {
 	return fmt.Sprintf("%s <- %s", s.Channel.Format(f), s.Value.Format(f))
 }
 
+func (s *SendExpression) Pos() (int, int) {
+	return leftPos(s.Channel, &s.NodeBase)
+}
+
+func (s *SendExpression) Range() token.Range {
+	return leftRange(s.Channel, &s.NodeBase)
+}
+
 type ReceiveExpression struct {
 	NodeBase
 	Channel  Expression
@@ -1304,6 +1733,11 @@ type IntLiteral struct {
 }
 
 func (i IntLiteral) Format(f *Formatter) string {
+	// keep the literal as written, so 0xff stays hex
+	if i.Token.Type == token.INT && i.Token.Literal != "" {
+		return i.Token.Literal
+	}
+
 	return strconv.Itoa(i.Value)
 }
 
@@ -1313,6 +1747,10 @@ type FloatLiteral struct {
 }
 
 func (fl FloatLiteral) Format(f *Formatter) string {
+	if fl.Token.Type == token.FLOAT && fl.Token.Literal != "" {
+		return fl.Token.Literal
+	}
+
 	return strconv.FormatFloat(fl.Value, 'f', -1, 64)
 }
 
@@ -1322,7 +1760,11 @@ type StringLiteral struct {
 }
 
 func (s StringLiteral) Format(f *Formatter) string {
-	return fmt.Sprintf(`"%s"`, s.Value)
+	if s.Token.Type == token.CHAR {
+		return "'" + strings.ReplaceAll(lexer.EscapeString(s.Value), "'", `\'`) + "'"
+	}
+
+	return lexer.QuoteString(s.Value)
 }
 
 type InterpolatedString struct {
@@ -1336,7 +1778,12 @@ func (i *InterpolatedString) Format(f *Formatter) string {
 	out.WriteString(`"`)
 
 	for _, p := range i.Parts {
-		out.WriteString(p.Format(f))
+		if s, ok := p.(*StringLiteral); ok {
+			out.WriteString(lexer.EscapeString(s.Value))
+			continue
+		}
+
+		out.WriteString("${" + p.Format(f) + "}")
 	}
 
 	out.WriteString(`"`)
@@ -1374,6 +1821,14 @@ func (m *MemberExpression) Format(f *Formatter) string {
 	return fmt.Sprintf("%s.%s", m.Left.Format(f), m.Field.Format(f))
 }
 
+func (m *MemberExpression) Pos() (int, int) {
+	return leftPos(m.Left, &m.NodeBase)
+}
+
+func (m *MemberExpression) Range() token.Range {
+	return leftRange(m.Left, &m.NodeBase)
+}
+
 type Identifier struct {
 	NodeBase
 	Value string
@@ -1400,9 +1855,46 @@ type InfixExpression struct {
 }
 
 func (i *InfixExpression) Format(f *Formatter) string {
+	if f.Parens {
+		return fmt.Sprintf("(%s %s %s)", i.Left.Format(f), i.Operator, i.Right.Format(f))
+	}
+
 	return fmt.Sprintf("%s %s %s", i.Left.Format(f), i.Operator, i.Right.Format(f))
 }
 
+func (i *InfixExpression) Pos() (int, int) {
+	return leftPos(i.Left, &i.NodeBase)
+}
+
+func (i *InfixExpression) Range() token.Range {
+	return leftRange(i.Left, &i.NodeBase)
+}
+
+// ConditionalExpression is cond ? a : b, only the side that is picked gets evaluated
+type ConditionalExpression struct {
+	NodeBase
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (c *ConditionalExpression) Format(f *Formatter) string {
+	s := fmt.Sprintf("%s ? %s : %s", c.Condition.Format(f), c.Consequence.Format(f), c.Alternative.Format(f))
+	if f.Parens {
+		return "(" + s + ")"
+	}
+
+	return s
+}
+
+func (c *ConditionalExpression) Pos() (int, int) {
+	return leftPos(c.Condition, &c.NodeBase)
+}
+
+func (c *ConditionalExpression) Range() token.Range {
+	return leftRange(c.Condition, &c.NodeBase)
+}
+
 type PrefixExpression struct {
 	NodeBase
 	Operator string
@@ -1419,9 +1911,29 @@ type GroupedExpression struct {
 }
 
 func (g *GroupedExpression) Format(f *Formatter) string {
+	// the infix inside already brings its own parentheses
+	if _, ok := g.Expression.(*InfixExpression); ok && f.Parens {
+		return g.Expression.Format(f)
+	}
+
 	return fmt.Sprintf("(%s)", g.Expression.Format(f))
 }
 
+// TupleLiteral is (a, b), several values passed around as one, like what a function with more than one result gives back
+type TupleLiteral struct {
+	NodeBase
+	Elements []Expression
+}
+
+func (t *TupleLiteral) Format(f *Formatter) string {
+	parts := make([]string, len(t.Elements))
+	for idx, el := range t.Elements {
+		parts[idx] = el.Format(f)
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
 type PostfixExpression struct {
 	NodeBase
 	Left     Expression
@@ -1431,3 +1943,11 @@ type PostfixExpression struct {
 func (p *PostfixExpression) Format(f *Formatter) string {
 	return p.Left.Format(f) + p.Operator
 }
+
+func (p *PostfixExpression) Pos() (int, int) {
+	return leftPos(p.Left, &p.NodeBase)
+}
+
+func (p *PostfixExpression) Range() token.Range {
+	return leftRange(p.Left, &p.NodeBase)
+}
@@ -0,0 +1,21 @@
+package parser
+
+import "testing"
+
+func TestMalformedCharLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"putln('ab')", "syntax error at 1:7: character literal must hold exactly one character, got 'ab'"},
+		{"putln('')", "syntax error at 1:7: character literal must hold exactly one character, got ''"},
+		{"say c = 'a", "syntax error at 1:9: unterminated character literal"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
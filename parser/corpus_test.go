@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+// TestFormatRoundTrip formats every program in testdata and checks the output parses back to the
+// same tree and formats to itself, then that every kind of node showed up in at least one program
+func TestFormatRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	more, err := filepath.Glob(filepath.Join("testdata", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, more...)
+
+	seen := map[string]bool{}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := New(lexer.New(string(src)))
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Errorf("%s: %v", file, errs[0])
+			continue
+		}
+
+		recordTypes(reflect.ValueOf(program), seen)
+
+		out := FormatProgram(program)
+
+		check := New(lexer.New(out))
+		formatted := check.ParseProgram()
+		if errs := check.Errors(); len(errs) > 0 {
+			t.Errorf("%s: the formatted program does not parse: %v\n%s", file, errs[0], out)
+			continue
+		}
+
+		if !Equal(program, formatted) {
+			t.Errorf("%s: the formatted program parses to a different tree\n%s", file, out)
+		}
+
+		if again := FormatProgram(formatted); again != out {
+			t.Errorf("%s: formatting twice changed the output\nfirst:\n%s\nsecond:\n%s", file, out, again)
+		}
+	}
+
+	for _, name := range nodeTypes(t) {
+		if !seen[name] {
+			t.Errorf("no program in testdata uses %s, add one to testdata/corpus", name)
+		}
+	}
+}
+
+// recordTypes walks v the way DumpPositions does and notes the name of every struct it passes
+func recordTypes(v reflect.Value, seen map[string]bool) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		seen[v.Type().Name()] = true
+
+		for idx := 0; idx < v.NumField(); idx++ {
+			if v.Type().Field(idx).IsExported() && v.Field(idx).Type() != reflect.TypeOf(NodeBase{}) {
+				recordTypes(v.Field(idx), seen)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < v.Len(); idx++ {
+			recordTypes(v.Index(idx), seen)
+		}
+	}
+}
+
+// nodeTypes reads ast.go for every struct that embeds NodeBase, so a new kind of node
+// fails the round trip test until the corpus has a program using it
+func nodeTypes(t *testing.T) []string {
+	t.Helper()
+
+	file, err := goparser.ParseFile(token.NewFileSet(), "ast.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return false
+		}
+
+		for _, field := range st.Fields.List {
+			if id, ok := field.Type.(*ast.Ident); ok && len(field.Names) == 0 && id.Name == "NodeBase" {
+				names = append(names, spec.Name.Name)
+			}
+		}
+
+		return false
+	})
+
+	if len(names) == 0 {
+		t.Fatal("found no node types in ast.go")
+	}
+
+	return names
+}
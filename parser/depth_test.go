@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+func TestDeepNestingIsAnError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"parentheses", strings.Repeat("(", 20000) + "1" + strings.Repeat(")", 20000)},
+		{"prefix", "say x = " + strings.Repeat("!", 20000) + "yes"},
+		{"calls", strings.Repeat("f(", 20000) + strings.Repeat(")", 20000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := parseErrors(tt.src)
+			if len(errs) != 1 || !strings.Contains(errs[0], "expression too deeply nested") {
+				t.Errorf("expected one nesting error, got %q", errs)
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	parse := func(src string, depth int) []error {
+		p := New(lexer.New(src))
+		p.MaxDepth = depth
+		p.ParseProgram()
+		return p.Errors()
+	}
+
+	src := "putln(((1 + 2)))"
+	if errs := parse(src, 8); len(errs) != 0 {
+		t.Errorf("expected %q to fit in 8, got %v", src, errs)
+	}
+
+	if errs := parse(src, 3); len(errs) != 1 {
+		t.Errorf("expected %q to be too deep for 3, got %v", src, errs)
+	}
+
+	// the depth is per expression, the next statement starts again from zero
+	if errs := parse("say a = ((1))\nsay b = ((2))\n", 4); len(errs) != 0 {
+		t.Errorf("expected each statement to be counted on its own, got %v", errs)
+	}
+}
+
+// TestCorpusIsFarUnderMaxDepth parses every corpus program with a hundredth of the default
+// limit, real code nests nowhere near it so the limit can't start rejecting it
+func TestCorpusIsFarUnderMaxDepth(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := New(lexer.New(string(src)))
+		p.MaxDepth = DefaultMaxDepth / 100
+		p.ParseProgram()
+
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Errorf("%s: %v", file, errs[0])
+		}
+	}
+}
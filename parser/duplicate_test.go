@@ -0,0 +1,34 @@
+package parser
+
+import "testing"
+
+func TestDuplicateNames(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"fun f(a int, a int) {}", "syntax error at 1:14: duplicate parameter 'a' (got a)"},
+		{"fun f(a int, b int, c int, b string) {}", "syntax error at 1:28: duplicate parameter 'b' (got b)"},
+		{"say f = fun(a int, a int) {}", "syntax error at 1:20: duplicate parameter 'a' (got a)"},
+		{"type P struct {\n    x int\n    x int\n}", "syntax error at 3:5: duplicate field 'x' in struct type (got x)"},
+		{"type P struct {\n    x int\n}\nsay p = P{x: 1, x: 2}", "syntax error at 4:17: duplicate field 'x' in literal (got x)"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestDistinctNamesAreFine(t *testing.T) {
+	for _, src := range []string{
+		"fun f(a int, b int) {\n    ayla yes {\n        say a = b\n    }\n}",
+		"type P struct {\n    x int\n    y int\n}\nsay p = P{x: 1, y: 2}",
+	} {
+		if got := parseErrors(src); len(got) > 0 {
+			t.Errorf("%q: unexpected errors %q", src, got)
+		}
+	}
+}
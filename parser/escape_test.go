@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+// stringValue parses a single say statement and gives back the string it was set to
+func stringValue(t *testing.T, src string) string {
+	t.Helper()
+
+	p := New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parsing %q: %v", src, errs[0])
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("parsing %q: expected 1 statement, got %d", src, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*VarStatement)
+	if !ok {
+		t.Fatalf("parsing %q: expected a say statement, got %T", src, program.Statements[0])
+	}
+
+	lit, ok := stmt.Value.(*StringLiteral)
+	if !ok {
+		t.Fatalf("parsing %q: expected a string literal, got %T", src, stmt.Value)
+	}
+
+	return lit.Value
+}
+
+func TestQuoteStringRoundTrip(t *testing.T) {
+	// the characters that need escaping, the ones that make up ${} and a few multi byte ones
+	alphabet := []rune("ab $${}}\\\"'`\n\r\t é世")
+	rng := rand.New(rand.NewPCG(1, 3))
+
+	for n := 0; n < 5000; n++ {
+		runes := make([]rune, rng.IntN(12))
+		for k := range runes {
+			runes[k] = alphabet[rng.IntN(len(alphabet))]
+		}
+		s := string(runes)
+
+		src := "say s = " + lexer.QuoteString(s)
+		if got := stringValue(t, src); got != s {
+			t.Fatalf("quoting %q: read back %q from %s", s, got, src)
+		}
+
+		p := New(lexer.New(src))
+		formatted := FormatProgram(p.ParseProgram())
+		if got := stringValue(t, formatted); got != s {
+			t.Fatalf("quoting %q: read back %q after formatting to %s", s, got, formatted)
+		}
+	}
+}
+
+func TestEscapedDollarIsNotInterpolated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`say s = "\${x}"`, "${x}"},
+		{`say s = "cost: \${price} $"`, "cost: ${price} $"},
+		{`say s = "$ {x}"`, "$ {x}"},
+	}
+
+	for _, tt := range tests {
+		if got := stringValue(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
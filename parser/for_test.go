@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+// parseFor parses src, which should be a single for loop, and gives it back
+func parseFor(t *testing.T, src string) *ForStatement {
+	t.Helper()
+
+	p := New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("%q: %v", src, errs[0])
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("%q: expected one statement, got %d", src, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ForStatement)
+	if !ok {
+		t.Fatalf("%q: expected a for loop, got %T", src, program.Statements[0])
+	}
+
+	return stmt
+}
+
+func TestForClauses(t *testing.T) {
+	tests := []struct {
+		src  string
+		init string
+		cond string
+		post string
+	}{
+		{"for i := 0; i < 3; i++ {}", "i := 0", "i < 3", "i++"},
+		{"for setup(); n < done(); step() {}", "setup()", "n < done()", "step()"},
+		{"for ; n < 5; n++ {}", "", "n < 5", "n++"},
+		{"for i = 0; i < 5; {}", "i = 0", "i < 5", ""},
+		{"for ; n < 5; {}", "", "n < 5", ""},
+		{"for a, b = 0, 1; a < 9; a, b = b, a + b {}", "a, b = 0, 1", "a < 9", "a, b = b, a + b"},
+	}
+
+	f := &Formatter{}
+	for _, tt := range tests {
+		stmt := parseFor(t, tt.src)
+
+		var init, post string
+		if stmt.Init != nil {
+			init = stmt.Init.Format(f)
+		}
+		if stmt.Post != nil {
+			post = stmt.Post.Format(f)
+		}
+
+		if init != tt.init || stmt.Condition.Format(f) != tt.cond || post != tt.post {
+			t.Errorf("%q: got init %q, condition %q, post %q", tt.src, init, stmt.Condition.Format(f), post)
+		}
+	}
+}
+
+func TestForPostCannotDeclare(t *testing.T) {
+	for _, src := range []string{
+		"for i := 0; i < 2; j := 1 {}",
+		"for i := 0; i < 2; say j = 1 {}",
+		"for i := 0; i < 2; keep j = 1 {}",
+	} {
+		errs := parseErrors(src)
+		if len(errs) == 0 || !strings.Contains(errs[0], "syntax error at 1:20: cannot declare variables in the post statement of a for loop") {
+			t.Errorf("%q: got %q", src, errs)
+		}
+	}
+}
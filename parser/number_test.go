@@ -0,0 +1,64 @@
+package parser
+
+import "testing"
+
+func TestMalformedIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"putln(0x)", "syntax error at 1:7: malformed integer literal 0x"},
+		{"putln(0b12)", "syntax error at 1:7: malformed integer literal 0b12"},
+		{"putln(0o9)", "syntax error at 1:7: malformed integer literal 0o9"},
+		{"say x = 0xg", "syntax error at 1:9: malformed integer literal 0xg"},
+		{"putln(0x8000000000000000)", "syntax error at 1:7: integer literal 0x8000000000000000 is out of range"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestMalformedFloatLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"putln(1e)", "syntax error at 1:7: malformed float literal 1e"},
+		{"putln(1e+)", "syntax error at 1:7: malformed float literal 1e+"},
+		{"say x = 1.5E-", "syntax error at 1:9: malformed float literal 1.5E-"},
+		{"putln(1e400)", "syntax error at 1:7: float literal 1e400 is out of range"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestMisplacedDigitSeparators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"putln(1__0)", "syntax error at 1:7: invalid '_' in number literal"},
+		{"putln(100_)", "syntax error at 1:7: invalid '_' in number literal"},
+		{"putln(1_.5)", "syntax error at 1:7: invalid '_' in number literal"},
+		{"putln(1e_1)", "syntax error at 1:7: invalid '_' in number literal"},
+		{"say x = 2.5_", "syntax error at 1:9: invalid '_' in number literal"},
+		{"say x = 0x_ff", "syntax error at 1:9: invalid '_' in number literal"},
+		{"say x = 0b_1", "syntax error at 1:9: invalid '_' in number literal"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
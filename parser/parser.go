@@ -2,7 +2,8 @@ package parser
 
 import (
 	"fmt"
-	"strconv"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/z-sk1/ayla-lang/lexer"
@@ -18,9 +19,25 @@ type Parser struct {
 
 	stopTokens map[token.TokenType]bool
 
+	// File is the name of the source being parsed, it is kept on the Program
+	File string
+
+	// max nesting of expressions before giving up, guards against stack overflow
+	MaxDepth int
+	depth    int
+	tooDeep  bool
+
+	// how many '{' are open at curTok, used to find where a block ends when recovering
+	braces int
+
+	// set by the first error in a statement, later errors in it are only fallout until synchronize
+	recovering bool
+
 	errors []error
 }
 
+const DefaultMaxDepth = 10000
+
 type ParseError struct {
 	Message string
 	Line    int
@@ -29,29 +46,99 @@ type ParseError struct {
 }
 
 func (e ParseError) Error() string {
-	if e.Token.Literal == "" {
-		e.Token.Literal = "nothing"
+	return fmt.Sprintf("syntax error at %d:%d: %s (got %s)", e.Line, e.Column, e.Message, e.Found())
+}
+
+// Found describes the token the parser ran into
+func (e ParseError) Found() string {
+	switch {
+	case e.Token.Type == token.NEWLINE:
+		return "end of line"
+	case e.Token.Type == token.EOF, e.Token.Literal == "":
+		return "nothing"
 	}
 
-	return fmt.Sprintf("syntax error at %d:%d: %s (got %s)", e.Line, e.Column, e.Message, e.Token.Literal)
+	return e.Token.Literal
 }
 
+// Errors returns the lexer's errors followed by the parser's own
 func (p *Parser) Errors() []error {
-	return p.errors
+	lexErrs := p.l.Errors()
+	if len(lexErrs) == 0 {
+		return p.errors
+	}
+
+	errs := make([]error, 0, len(lexErrs)+len(p.errors))
+	errs = append(errs, lexErrs...)
+	return append(errs, p.errors...)
 }
 
 func (p *Parser) addError(msg string) {
-	p.errors = append(p.errors, &ParseError{Message: msg, Line: p.curTok.Line, Column: p.curTok.Column, Token: p.curTok})
+	p.addErrorAt(p.curTok, msg)
+}
+
+// addErrorAt records an error at tok, which should be the token that was found instead of what was wanted
+func (p *Parser) addErrorAt(tok token.Token, msg string) {
+	// the lexer already reported illegal tokens, anything else would just be noise
+	if p.tooDeep || p.recovering || tok.Type == token.ILLEGAL {
+		return
+	}
+
+	p.recovering = true
+
+	p.errors = append(p.errors, &ParseError{Message: msg, Line: tok.Line, Column: tok.Column, Token: tok})
+}
+
+// expect moves to the next token if it is t, otherwise it reports "expected t context" at the token that is there instead.
+// context says where t belongs, like "after index expression"
+func (p *Parser) expect(t token.TokenType, context string) bool {
+	if p.peekTok.Type == t {
+		p.nextToken()
+		return true
+	}
+
+	p.addErrorAt(p.peekTok, fmt.Sprintf("expected %s %s", tokenName(t), context))
+	return false
 }
 
-func atoi(a string) int {
-	val, _ := strconv.Atoi(a)
-	return val
+// markEnd records curTok as the last token of n, parse functions always leave curTok on the last token they used
+func (p *Parser) markEnd(n interface{ Pos() (int, int) }) {
+	ender, ok := n.(interface{ setLast(token.Token) })
+	if !ok || isNil(n) {
+		return
+	}
+
+	// a node that failed to parse can leave curTok before where it started
+	line, col := n.Pos()
+	if p.curTok.Line < line || (p.curTok.Line == line && p.curTok.Column < col) {
+		return
+	}
+
+	ender.setLast(p.curTok)
 }
 
-func atof(a string) float64 {
-	val, _ := strconv.ParseFloat(a, 64)
-	return val
+// isNil catches the nil pointers parse functions give back on errors, which are not nil once they are a Statement
+func isNil(n any) bool {
+	if n == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// tokenName is how a token type reads in an error message
+func tokenName(t token.TokenType) string {
+	switch t {
+	case token.IDENT:
+		return "identifier"
+	case token.NEWLINE:
+		return "end of line"
+	case token.EOF:
+		return "end of file"
+	}
+
+	return fmt.Sprintf("'%s'", t)
 }
 
 func (p *Parser) parseIdentList() []Expression {
@@ -177,6 +264,7 @@ func (p *Parser) isAssignToken(t token.TokenType) bool {
 		token.MUL_ASSIGN,
 		token.SLASH_ASSIGN,
 		token.MOD_ASSIGN,
+		token.POW_ASSIGN,
 		token.AND_ASSIGN,
 		token.OR_ASSIGN,
 		token.XOR_ASSIGN,
@@ -196,6 +284,7 @@ func New(l *lexer.Lexer) *Parser {
 			token.RPAREN:   true,
 			token.RBRACKET: true,
 		},
+		MaxDepth: DefaultMaxDepth,
 	}
 
 	p.nextToken()
@@ -207,6 +296,13 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curTok = p.peekTok
 
+	switch p.curTok.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		p.braces--
+	}
+
 	if len(p.peekBuf) > 0 {
 		p.peekTok = p.peekBuf[0]
 		p.peekBuf = p.peekBuf[1:]
@@ -272,16 +368,27 @@ func (p *Parser) consumeTerminators() {
 	}
 }
 
+func (p *Parser) consumePeekTerminators() {
+	for p.peekTok.Type == token.NEWLINE {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) isType() bool {
 	return p.isTypeToken(p.peekTok.Type) ||
 		(p.peekTok.Type == token.IDENT && p.peekN(1).Type == token.DOT) ||
-		(p.peekTok.Type == token.MUL && p.isPointerType())
+		(isStar(p.peekTok.Type) && p.isPointerType())
+}
+
+// ** is lexed as one token, but in types and derefs it is two *
+func isStar(t token.TokenType) bool {
+	return t == token.MUL || t == token.POW
 }
 
 func (p *Parser) isPointerType() bool {
 	i := 1
 
-	for p.peekN(i).Type == token.MUL {
+	for isStar(p.peekN(i).Type) {
 		i++
 	}
 
@@ -294,7 +401,7 @@ func (p *Parser) isPointerType() bool {
 func (p *Parser) isPointerTypeM1() bool {
 	i := 0
 
-	for p.peekN(i).Type == token.MUL {
+	for isStar(p.peekN(i).Type) {
 		i++
 	}
 
@@ -323,23 +430,41 @@ func (p *Parser) handleVariedResults(expr Expression, lhsCount int) Expression {
 	}
 }
 
-func (p *Parser) ParseProgram() []Statement {
+func (p *Parser) ParseProgram() *Program {
+	program := &Program{
+		NodeBase: NodeBase{Token: p.curTok},
+		File:     p.File,
+	}
+
 	var statements []Statement
 	for p.curTok.Type != token.EOF {
 		if p.curTok.Type == token.NEWLINE {
 			p.nextToken()
 		}
 
+		depth := p.braces
+		errCount := len(p.errors)
+
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if !isNil(stmt) {
+			p.markEnd(stmt)
 			statements = append(statements, stmt)
 		}
+
+		if len(p.errors) > errCount || p.recovering {
+			p.synchronize(depth)
+		}
+
 		p.nextToken()
 
 		p.consumeTerminators()
 	}
 
-	return statements
+	program.Statements = statements
+	program.Comments = p.l.Comments()
+	p.markEnd(program)
+
+	return program
 }
 
 func (p *Parser) parseStatement() Statement {
@@ -370,11 +495,18 @@ func (p *Parser) parseStatement() Statement {
 	case token.ENUM:
 		return p.parseEnumStatement()
 	case token.TYPE:
+		// type(x) is a call to the type builtin, not a declaration
+		if p.peekTok.Type == token.LPAREN {
+			return p.parseExpressionStatement()
+		}
+
 		return p.parseTypeStatement()
 	case token.SWITCH:
 		return p.parseSwitchStatement()
 	case token.SELECT:
 		return p.parseSelectStatement()
+	case token.MATCH:
+		return p.parseMatchStatement()
 	case token.FUNC:
 		if p.peekTok.Type == token.LPAREN {
 
@@ -383,6 +515,9 @@ func (p *Parser) parseStatement() Statement {
 			depth := 1
 			for depth > 0 {
 				tok := p.peekN(i)
+				if tok.Type == token.EOF {
+					break
+				}
 				if tok.Type == token.LPAREN {
 					depth++
 				}
@@ -398,9 +533,10 @@ func (p *Parser) parseStatement() Statement {
 				return p.parseMethodStatement()
 			}
 
+			start := p.curTok
 			expr := p.parseExpression(LOWEST)
 			return &ExpressionStatement{
-				NodeBase:   NodeBase{Token: p.curTok},
+				NodeBase:   NodeBase{Token: start},
 				Expression: expr,
 			}
 		}
@@ -423,7 +559,7 @@ func (p *Parser) parseStatement() Statement {
 		return p.parseReturnStatement()
 	case token.DEFER:
 		return p.parseDeferStatement()
-	case token.IDENT, token.MUL:
+	case token.IDENT, token.MUL, token.POW:
 		if p.peekUntilAssign() == token.WALRUS {
 			if p.peekTok.Type == token.COMMA {
 				return p.parseMultiVarStatementNoKeyword()
@@ -444,14 +580,25 @@ func (p *Parser) parseStatement() Statement {
 	case token.NEWLINE, token.EOF:
 		return nil
 	default:
-		expr := p.parseExpression(LOWEST)
-		if expr == nil {
-			return nil
-		}
-		return &ExpressionStatement{
-			NodeBase:   NodeBase{Token: p.curTok},
-			Expression: expr,
+		return p.parseExpressionStatement()
+	}
+}
+
+func (p *Parser) parseExpressionStatement() Statement {
+	errCount := len(p.errors)
+	start := p.curTok
+
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		if len(p.errors) == errCount {
+			p.addError(fmt.Sprintf("unexpected '%s'", p.curTok.Literal))
 		}
+		return nil
+	}
+
+	return &ExpressionStatement{
+		NodeBase:   NodeBase{Token: start},
+		Expression: expr,
 	}
 }
 
@@ -460,12 +607,10 @@ func (p *Parser) parseVarStatementBlock() *VarStatementBlock {
 		NodeBase: NodeBase{Token: p.curTok}, // egg
 	}
 
-	if p.peekTok.Type != token.LPAREN {
-		p.addError("expected '(' in egg block")
+	if !p.expect(token.LPAREN, "in say block") {
 		return nil
 	}
 
-	p.nextToken() // (
 	p.nextToken() // first token inside block
 
 	for p.curTok.Type != token.RPAREN && p.curTok.Type != token.EOF {
@@ -477,6 +622,7 @@ func (p *Parser) parseVarStatementBlock() *VarStatementBlock {
 
 		decl := p.parseVarBlockDecl()
 		if decl != nil {
+			p.markEnd(decl)
 			stmt.Decls = append(stmt.Decls, decl)
 		}
 
@@ -494,7 +640,7 @@ func (p *Parser) parseVarBlockDecl() Statement {
 	}
 
 	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier in egg block")
+		p.addError("expected identifier in say block")
 		return nil
 	}
 
@@ -518,12 +664,10 @@ func (p *Parser) parseVarBlockDecl() Statement {
 
 			stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-			if p.peekTok.Type != token.GT {
-				p.addError("expected '>' after lifetime expression")
+			if !p.expect(token.GT, "after lifetime expression") {
 				return nil
 			}
 
-			p.nextToken() // move to '>'
 		}
 
 		if p.isType() {
@@ -550,12 +694,10 @@ func (p *Parser) parseVarBlockDecl() Statement {
 
 			stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-			if p.peekTok.Type != token.GT {
-				p.addError("expected '>' after lifetime expression")
+			if !p.expect(token.GT, "after lifetime expression") {
 				return nil
 			}
 
-			p.nextToken() // move to '>'
 		}
 
 		if p.isType() {
@@ -600,8 +742,7 @@ func (p *Parser) parseVarStatement() *VarStatement {
 		stmt.Lifetime = p.parseExpression(LOWEST)
 		p.stopTokens[token.GT] = false
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 	}
@@ -642,12 +783,10 @@ func (p *Parser) parseVarStatementNoKeyword() *VarStatementNoKeyword {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	p.nextToken() // :=
@@ -672,12 +811,10 @@ func (p *Parser) parseMultiVarStatement() *MultiVarStatement {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	// optional type
@@ -715,12 +852,10 @@ func (p *Parser) parseMultiVarStatementNoKeyword() *MultiVarStatementNoKeyword {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	p.nextToken() // :=
@@ -754,12 +889,10 @@ func (p *Parser) parseConstStatementBlock() *ConstStatementBlock {
 		NodeBase: NodeBase{Token: p.curTok}, // const
 	}
 
-	if p.peekTok.Type != token.LPAREN {
-		p.addError("expected '(' in const block")
+	if !p.expect(token.LPAREN, "in keep block") {
 		return nil
 	}
 
-	p.nextToken() // (
 	p.nextToken() // first token inside block
 
 	for p.curTok.Type != token.RPAREN && p.curTok.Type != token.EOF {
@@ -771,6 +904,7 @@ func (p *Parser) parseConstStatementBlock() *ConstStatementBlock {
 
 		decl := p.parseConstBlockDecl()
 		if decl != nil {
+			p.markEnd(decl)
 			stmt.Decls = append(stmt.Decls, decl)
 		}
 
@@ -788,7 +922,7 @@ func (p *Parser) parseConstBlockDecl() Statement {
 	}
 
 	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier in rock block")
+		p.addError("expected identifier in keep block")
 		return nil
 	}
 
@@ -813,12 +947,10 @@ func (p *Parser) parseConstBlockDecl() Statement {
 
 			stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-			if p.peekTok.Type != token.GT {
-				p.addError("expected '>' after lifetime expression")
+			if !p.expect(token.GT, "after lifetime expression") {
 				return nil
 			}
 
-			p.nextToken() // move to '>'
 		}
 
 		if p.isType() {
@@ -844,12 +976,10 @@ func (p *Parser) parseConstBlockDecl() Statement {
 
 			stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-			if p.peekTok.Type != token.GT {
-				p.addError("expected '>' after lifetime expression")
+			if !p.expect(token.GT, "after lifetime expression") {
 				return nil
 			}
 
-			p.nextToken() // move to '>'
 		}
 
 		if p.isType() {
@@ -880,9 +1010,7 @@ func (p *Parser) parseConstStatement() *ConstStatement {
 	stmt.NodeBase = NodeBase{Token: p.curTok}
 
 	// rock -> name
-	p.nextToken()
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier after 'egg'")
+	if !p.expect(token.IDENT, "after 'keep'") {
 		return nil
 	}
 	stmt.Name = &Identifier{
@@ -897,12 +1025,10 @@ func (p *Parser) parseConstStatement() *ConstStatement {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	// type
@@ -936,12 +1062,10 @@ func (p *Parser) parseMultiConstStatement() *MultiConstStatement {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	// optional type
@@ -977,8 +1101,16 @@ func (p *Parser) parseImportStatement() *ImportStatement {
 }
 
 func (p *Parser) parseAssignOrExprStatement() Statement {
+	errCount := len(p.errors)
+	start := p.curTok
 
 	exprs := p.parseExpressionList()
+	if slices.Contains(exprs, nil) {
+		if len(p.errors) == errCount {
+			p.addError(fmt.Sprintf("unexpected '%s'", p.curTok.Literal))
+		}
+		return nil
+	}
 
 	if p.isAssignToken(p.peekTok.Type) {
 		op := p.peekTok.Type
@@ -986,9 +1118,15 @@ func (p *Parser) parseAssignOrExprStatement() Statement {
 		p.nextToken()
 
 		values := p.parseExpressionList()
+		if slices.Contains(values, nil) {
+			if len(p.errors) == errCount {
+				p.addError(fmt.Sprintf("expected expression after '%s'", op))
+			}
+			return nil
+		}
 
 		return &AssignmentStatement{
-			NodeBase: NodeBase{Token: p.curTok},
+			NodeBase: NodeBase{Token: start},
 			Targets:  exprs,
 			Op:       op,
 			Values:   values,
@@ -998,7 +1136,7 @@ func (p *Parser) parseAssignOrExprStatement() Statement {
 	// otherwise it's just an expression statement
 	if len(exprs) == 1 {
 		return &ExpressionStatement{
-			NodeBase:   NodeBase{Token: p.curTok},
+			NodeBase:   NodeBase{Token: start},
 			Expression: exprs[0],
 		}
 	}
@@ -1012,9 +1150,7 @@ func (p *Parser) parseTypeStatement() *TypeStatement {
 		NodeBase: NodeBase{Token: p.curTok},
 	}
 
-	p.nextToken()
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier after 'type'")
+	if !p.expect(token.IDENT, "after 'type'") {
 		return nil
 	}
 	stmt.Name = &Identifier{
@@ -1075,10 +1211,28 @@ func (p *Parser) parseType() TypeNode {
 		p.nextToken()
 		base := p.parseType()
 
-		return &PointerType{
+		ptr := &PointerType{
 			NodeBase: NodeBase{Token: p.curTok},
 			Base:     base,
 		}
+		p.markEnd(ptr)
+
+		return ptr
+
+	case token.POW:
+		p.nextToken()
+		base := p.parseType()
+
+		ptr := &PointerType{
+			NodeBase: NodeBase{Token: p.curTok},
+			Base: &PointerType{
+				NodeBase: NodeBase{Token: p.curTok},
+				Base:     base,
+			},
+		}
+		p.markEnd(ptr)
+
+		return ptr
 
 	case token.INT_TYPE,
 		token.FLOAT_TYPE,
@@ -1100,16 +1254,14 @@ func (p *Parser) parseType() TypeNode {
 		}
 
 		if p.peekTok.Type == token.DOT {
-			p.nextToken()
-			p.nextToken()
-
-			if p.curTok.Type != token.IDENT {
-				p.addError("expected identifier after '.'")
+			p.nextToken() // .
+			if !p.expect(token.IDENT, "after '.'") {
 				return nil
 			}
 
 			base = &QualifiedType{
-				Module: ident,
+				NodeBase: NodeBase{Token: ident.Token},
+				Module:   ident,
 				Name: &Identifier{
 					NodeBase: NodeBase{Token: p.curTok},
 					Value:    p.curTok.Literal,
@@ -1147,13 +1299,25 @@ func (p *Parser) parseType() TypeNode {
 		base = p.parseChanType()
 
 	default:
-		return p.exprToType(p.parseExpression(LOWEST))
+		errCount := len(p.errors)
+
+		expr := p.parseExpression(LOWEST)
+		if expr == nil {
+			if len(p.errors) == errCount {
+				p.addError("expected type")
+			}
+			return nil
+		}
+
+		return p.exprToType(expr)
 	}
 
 	if p.peekTok.Type == token.LT {
 		base = p.parseRangeType(base)
 	}
 
+	p.markEnd(base)
+
 	return base
 }
 
@@ -1176,7 +1340,7 @@ func (p *Parser) parseTypeList(end token.TokenType) []TypeNode {
 		list = append(list, p.parseType())
 	}
 	if p.peekTok.Type != end {
-		p.addError(fmt.Sprintf("expected '%s'", end))
+		p.addErrorAt(p.peekTok, fmt.Sprintf("expected '%s'", end))
 		return nil
 	}
 	p.nextToken()
@@ -1193,23 +1357,18 @@ func (p *Parser) parseRangeType(base TypeNode) TypeNode {
 		return nil
 	}
 
-	if p.peekTok.Type != token.DUODOT {
-		p.addError("expected '..' in range type")
+	if !p.expect(token.DUODOT, "in range type") {
 		return nil
 	}
 
-	p.nextToken() // move to '..'
 	p.nextToken() // first token of max
 
 	max := p.parseExpression(PREFIX)
 
-	if p.peekTok.Type != token.GT {
-		p.addError("expected '>' after range type")
+	if !p.expect(token.GT, "after range type") {
 		return nil
 	}
 
-	p.nextToken() // consume '>'
-
 	return &RangeType{
 		Base: base,
 		Min:  min,
@@ -1236,12 +1395,10 @@ func (p *Parser) parseArrayType() TypeNode {
 		return nil
 	}
 
-	if p.peekTok.Type != token.RBRACKET {
-		p.addError("expected ']'")
+	if !p.expect(token.RBRACKET, "after array size") {
 		return nil
 	}
 
-	p.nextToken()
 	p.nextToken()
 
 	at.Elem = p.parseType()
@@ -1252,12 +1409,10 @@ func (p *Parser) parseArrayType() TypeNode {
 func (p *Parser) parseInterfaceType() TypeNode {
 	tok := p.curTok
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after interface")
+	if !p.expect(token.LBRACE, "after interface") {
 		return nil
 	}
 
-	p.nextToken() // {
 	p.nextToken() // method
 
 	methods := []*FuncType{}
@@ -1275,9 +1430,7 @@ func (p *Parser) parseInterfaceType() TypeNode {
 			Value:    p.curTok.Literal,
 		}
 
-		p.nextToken() // (
-		if p.curTok.Type != token.LPAREN {
-			p.addError("expected '(' after method name")
+		if !p.expect(token.LPAREN, "after method name") {
 			return nil
 		}
 
@@ -1309,12 +1462,10 @@ func (p *Parser) parseInterfaceType() TypeNode {
 func (p *Parser) parseStructType() TypeNode {
 	tok := p.curTok
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after struct")
+	if !p.expect(token.LBRACE, "after struct") {
 		return nil
 	}
 
-	p.nextToken() // {
 	p.nextToken() // first field or }
 
 	fields := []*StructField{}
@@ -1332,6 +1483,13 @@ func (p *Parser) parseStructType() TypeNode {
 			Value:    p.curTok.Literal,
 		}
 
+		for _, f := range fields {
+			if f.Name.Value == fieldName.Value {
+				p.addError(fmt.Sprintf("duplicate field '%s' in struct type", fieldName.Value))
+				break
+			}
+		}
+
 		p.nextToken() // move to type
 
 		fieldType := p.parseType()
@@ -1353,11 +1511,9 @@ func (p *Parser) parseStructType() TypeNode {
 }
 
 func (p *Parser) parseMapType() TypeNode {
-	if p.peekTok.Type != token.LBRACKET {
-		p.addError("expected '[' in key type")
+	if !p.expect(token.LBRACKET, "in key type") {
 		return nil
 	}
-	p.nextToken() // [
 	p.nextToken() // key type (eg: string)
 
 	key := p.parseType()
@@ -1365,11 +1521,9 @@ func (p *Parser) parseMapType() TypeNode {
 		return nil
 	}
 
-	if p.peekTok.Type != token.RBRACKET {
-		p.addError("expected ']' after key type")
+	if !p.expect(token.RBRACKET, "after key type") {
 		return nil
 	}
-	p.nextToken() // ]
 	p.nextToken() // value type (eg: string)
 
 	val := p.parseType()
@@ -1391,12 +1545,10 @@ func (p *Parser) parseFuncType() TypeNode {
 		Returns:  make([]TypeNode, 0),
 	}
 
-	if p.peekTok.Type != token.LPAREN {
-		p.addError("expected '(' after fun")
+	if !p.expect(token.LPAREN, "after fun") {
 		return nil
 	}
 
-	p.nextToken()
 	typ.Params = p.parseTypeList(token.RPAREN)
 
 	if p.peekTok.Type == token.LPAREN {
@@ -1454,6 +1606,12 @@ func (p *Parser) parseCompositeLiteral(typ TypeNode) Expression {
 
 		if p.curTok.Type == token.IDENT && p.peekTok.Type == token.COLON {
 			fieldName := p.curTok.Literal
+			if _, ok := lit.Fields[fieldName]; ok {
+				p.addError(fmt.Sprintf("duplicate field '%s' in literal", fieldName))
+			} else {
+				lit.Order = append(lit.Order, fieldName)
+			}
+
 			p.nextToken() // :
 			p.nextToken() // value
 			lit.Fields[fieldName] = p.parseExpression(LOWEST)
@@ -1496,7 +1654,7 @@ func (p *Parser) parseCompositeLiteral(typ TypeNode) Expression {
 			break
 		}
 
-		p.addError("expected ',' or '}' in composite literal")
+		p.addErrorAt(p.peekTok, "expected ',' or '}' in composite literal")
 		return nil
 	}
 
@@ -1508,11 +1666,9 @@ func (p *Parser) parseEnumStatement() *EnumStatement {
 		NodeBase: NodeBase{Token: p.curTok}, // enum
 	}
 
-	if p.peekTok.Type != token.IDENT {
-		p.addError("expected identifier")
+	if !p.expect(token.IDENT, "after 'enum'") {
 		return nil
 	}
-	p.nextToken()
 
 	stmt.Name = &Identifier{
 		NodeBase: NodeBase{Token: p.curTok},
@@ -1527,11 +1683,9 @@ func (p *Parser) parseEnumStatement() *EnumStatement {
 	p.nextToken()
 	stmt.Type = p.parseType()
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{'")
+	if !p.expect(token.LBRACE, "after enum type") {
 		return nil
 	}
-	p.nextToken() // curTok == '{'
 
 	p.nextToken()
 
@@ -1562,14 +1716,11 @@ func (p *Parser) parseIfStatement() *IfStatement {
 	stmt.Condition = p.parseExpression(LOWEST)
 
 	// expect '{'
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after conditional")
+	if !p.expect(token.LBRACE, "after conditional") {
 		return nil
 	}
 
-	p.nextToken() // move to '{'
-
-	stmt.Consequence = p.parseBlockStatement()
+	stmt.Consequence, stmt.ConsequenceBraces = p.parseBracedBlock()
 
 	// else and else if
 	if p.peekTok.Type == token.ELSE {
@@ -1591,7 +1742,7 @@ func (p *Parser) parseIfStatement() *IfStatement {
 		}
 
 		p.nextToken() // '{'
-		stmt.Alternative = p.parseBlockStatement()
+		stmt.Alternative, stmt.AlternativeBraces = p.parseBracedBlock()
 	}
 
 	return stmt
@@ -1626,12 +1777,19 @@ func (p *Parser) parseDeferStatement() *DeferStatement {
 			p.addError("expected '}'")
 			return nil
 		}
+
+		return stmt
 	} else if p.curTok.Type != token.IDENT {
 		p.addError("expected function identifier after defer")
 		return nil
 	}
 
-	stmt.Call = p.parseFuncCall().(*FuncCall)
+	call, ok := p.parseFuncCall().(*FuncCall)
+	if !ok {
+		return nil
+	}
+
+	stmt.Call = call
 
 	return stmt
 }
@@ -1648,12 +1806,10 @@ func (p *Parser) parseSwitchStatement() *SwitchStatement {
 	} else {
 		stmt.Value = p.parseExpression(LOWEST)
 
-		if p.peekTok.Type != token.LBRACE {
-			p.addError("expected '{' after switch expression")
+		if !p.expect(token.LBRACE, "after switch expression") {
 			return nil
 		}
 
-		p.nextToken() // {
 		p.nextToken() // first token inside
 	}
 
@@ -1703,25 +1859,112 @@ func (p *Parser) parseCaseClause() *CaseClause {
 		clause.Exprs = append(clause.Exprs, p.parseExpression(LOWEST))
 	}
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after case expression")
+	if !p.expect(token.LBRACE, "after case expression") {
 		return nil
 	}
 
-	p.nextToken() // {
 	p.nextToken() // first stmt
 
-	clause.Body = []Statement{}
+	clause.Body = p.parseBlockBody()
+	p.markEnd(clause)
 
-	for p.curTok.Type != token.RBRACE && p.curTok.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			clause.Body = append(clause.Body, stmt)
+	return clause
+}
+
+func (p *Parser) parseMatchStatement() *MatchStatement {
+	stmt := &MatchStatement{
+		NodeBase: NodeBase{Token: p.curTok},
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if !p.expect(token.LBRACE, "after match expression") {
+		return nil
+	}
+
+	p.nextToken() // first token inside
+
+	stmt.Arms = []*MatchArm{}
+
+	for p.curTok.Type != token.EOF {
+
+		p.consumeTerminators()
+
+		switch p.curTok.Type {
+
+		case token.CASE:
+			arm := p.parseMatchArm()
+			if arm == nil {
+				return nil
+			}
+			stmt.Arms = append(stmt.Arms, arm)
+
+		case token.DEFAULT:
+			stmt.Default = p.parseDefaultClause()
+
+		case token.RBRACE:
+			return stmt
+
+		default:
+			p.addError("expected 'when' or 'otherwise'")
+			return nil
 		}
+
 		p.nextToken()
 	}
 
-	return clause
+	return stmt
+}
+
+func (p *Parser) parseMatchArm() *MatchArm {
+	arm := &MatchArm{
+		NodeBase: NodeBase{Token: p.curTok},
+	}
+
+	// consume `when`
+	p.nextToken()
+
+	if p.curTok.Type != token.IDENT {
+		p.addError("expected variant tag after 'when'")
+		return nil
+	}
+
+	arm.Tag = &Identifier{
+		NodeBase: NodeBase{Token: p.curTok},
+		Value:    p.curTok.Literal,
+	}
+
+	if p.peekTok.Type == token.LPAREN {
+		p.nextToken() // (
+		p.nextToken() // binding
+
+		if p.curTok.Type != token.IDENT {
+			p.addError("expected name to bind the variant payload to")
+			return nil
+		}
+
+		arm.Binding = &Identifier{
+			NodeBase: NodeBase{Token: p.curTok},
+			Value:    p.curTok.Literal,
+		}
+
+		if !p.expect(token.RPAREN, "after variant binding") {
+			return nil
+		}
+
+	}
+
+	if !p.expect(token.LBRACE, "after match arm") {
+		return nil
+	}
+
+	p.nextToken() // first stmt
+
+	arm.Body = p.parseBlockBody()
+	p.markEnd(arm)
+
+	return arm
 }
 
 func (p *Parser) parseSelectStatement() *SelectStatement {
@@ -1797,23 +2040,14 @@ func (p *Parser) parseSelectCaseClause() *SelectCaseClause {
 		clause.Op = p.parseExpression(LOWEST)
 	}
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{'")
+	if !p.expect(token.LBRACE, "after select case") {
 		return nil
 	}
 
-	p.nextToken() // {
 	p.nextToken() // first stmt
 
-	clause.Body = []Statement{}
-
-	for p.curTok.Type != token.RBRACE && p.curTok.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			clause.Body = append(clause.Body, stmt)
-		}
-		p.nextToken()
-	}
+	clause.Body = p.parseBlockBody()
+	p.markEnd(clause)
 
 	return clause
 }
@@ -1824,22 +2058,13 @@ func (p *Parser) parseDefaultClause() *DefaultClause {
 	}
 
 	// default {
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after default")
+	if !p.expect(token.LBRACE, "after default") {
 		return nil
 	}
-	p.nextToken() // {
 	p.nextToken() // first stmt
 
-	clause.Body = []Statement{}
-
-	for p.curTok.Type != token.RBRACE {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			clause.Body = append(clause.Body, stmt)
-		}
-		p.nextToken()
-	}
+	clause.Body = p.parseBlockBody()
+	p.markEnd(clause)
 
 	return clause
 }
@@ -1851,16 +2076,12 @@ func (p *Parser) parseMethodStatement() *MethodStatement {
 	}
 
 	// fun (
-	p.nextToken()
-	if p.curTok.Type != token.LPAREN {
-		p.addError("expected '(' after 'fun'")
+	if !p.expect(token.LPAREN, "after 'fun'") {
 		return nil
 	}
 
 	// receiver name
-	p.nextToken()
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier after '('")
+	if !p.expect(token.IDENT, "after '('") {
 		return nil
 	}
 
@@ -1878,16 +2099,12 @@ func (p *Parser) parseMethodStatement() *MethodStatement {
 	}
 
 	// )
-	p.nextToken()
-	if p.curTok.Type != token.RPAREN {
-		p.addError("expected ')' after type")
+	if !p.expect(token.RPAREN, "after type") {
 		return nil
 	}
 
 	// name
-	p.nextToken() // ident
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected method name after receiver")
+	if !p.expect(token.IDENT, "after receiver") {
 		return nil
 	}
 	stmt.Name = &Identifier{
@@ -1895,9 +2112,7 @@ func (p *Parser) parseMethodStatement() *MethodStatement {
 		Value:    p.curTok.Literal,
 	}
 
-	p.nextToken() // (
-	if p.curTok.Type != token.LPAREN {
-		p.addError("expected '(' after method name")
+	if !p.expect(token.LPAREN, "after method name") {
 		return nil
 	}
 	stmt.Params = p.parseFuncParams()
@@ -1924,6 +2139,7 @@ func (p *Parser) parseMethodStatement() *MethodStatement {
 func (p *Parser) parseFuncParams() []*Param {
 	params := []*Param{}
 	seenVariadic := false
+	seen := map[string]bool{}
 
 	if p.peekTok.Type == token.RPAREN {
 		p.nextToken() // consume ')'
@@ -1943,6 +2159,12 @@ func (p *Parser) parseFuncParams() []*Param {
 			Value:    p.curTok.Literal,
 		}
 
+		// any number of parameters can be left unnamed with _
+		if seen[paramName.Value] && paramName.Value != "_" {
+			p.addError(fmt.Sprintf("duplicate parameter '%s'", paramName.Value))
+		}
+		seen[paramName.Value] = true
+
 		p.nextToken()
 
 		variadic := false
@@ -1958,7 +2180,7 @@ func (p *Parser) parseFuncParams() []*Param {
 			p.nextToken()
 		}
 
-		if !(p.isTypeToken(p.curTok.Type) || (p.curTok.Type == token.IDENT && p.peekTok.Type == token.DOT) || (p.curTok.Type == token.MUL && p.isPointerTypeM1())) {
+		if !(p.isTypeToken(p.curTok.Type) || (p.curTok.Type == token.IDENT && p.peekTok.Type == token.DOT) || (isStar(p.curTok.Type) && p.isPointerTypeM1())) {
 			p.addError("expected type after parameter name")
 			return nil
 		}
@@ -1972,12 +2194,15 @@ func (p *Parser) parseFuncParams() []*Param {
 			}
 		}
 
-		params = append(params, &Param{
+		param := &Param{
 			NodeBase: NodeBase{Token: paramName.Token},
 			Name:     paramName,
 			Type:     paramType,
 			Variadic: variadic,
-		})
+		}
+		p.markEnd(param)
+
+		params = append(params, param)
 
 		if variadic && p.peekTok.Type == token.COMMA {
 			p.addError("variadic parameter must be last")
@@ -1991,7 +2216,7 @@ func (p *Parser) parseFuncParams() []*Param {
 		}
 
 		if p.peekTok.Type != token.RPAREN {
-			p.addError("expected ',' or ')'")
+			p.addErrorAt(p.peekTok, "expected ',' or ')' after parameter")
 			return nil
 		}
 
@@ -2009,7 +2234,7 @@ func (p *Parser) parseFuncReturnTypes() []TypeNode {
 		p.nextToken()
 
 		for p.curTok.Type != token.RPAREN {
-			if !(p.isTypeToken(p.curTok.Type) || (p.curTok.Type == token.IDENT && p.peekTok.Type == token.DOT) || (p.curTok.Type == token.MUL && p.isPointerTypeM1())) {
+			if !(p.isTypeToken(p.curTok.Type) || (p.curTok.Type == token.IDENT && p.peekTok.Type == token.DOT) || (isStar(p.curTok.Type) && p.isPointerTypeM1())) {
 				p.addError("expected return type")
 				return nil
 			}
@@ -2036,9 +2261,7 @@ func (p *Parser) parseFuncLiteral() *FuncLiteral {
 	}
 
 	// fun <params>
-	p.nextToken()
-	if p.curTok.Type != token.LPAREN {
-		p.addError("expected '(' after 'fun'")
+	if !p.expect(token.LPAREN, "after 'fun'") {
 		return nil
 	}
 	lit.Params = p.parseFuncParams()
@@ -2067,9 +2290,7 @@ func (p *Parser) parseFuncStatement() *FuncStatement {
 	}
 
 	// fun <name>
-	p.nextToken()
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected identifier after 'fun'")
+	if !p.expect(token.IDENT, "after 'fun'") {
 		return nil
 	}
 
@@ -2079,9 +2300,7 @@ func (p *Parser) parseFuncStatement() *FuncStatement {
 	}
 
 	// expect '('
-	p.nextToken()
-	if p.curTok.Type != token.LPAREN {
-		p.addError("expected '(' after function name")
+	if !p.expect(token.LPAREN, "after function name") {
 		return nil
 	}
 
@@ -2119,9 +2338,7 @@ func (p *Parser) parseFuncCall() Expression {
 	}
 
 	// expect '('
-	p.nextToken()
-	if p.curTok.Type != token.LPAREN {
-		p.addError("expected '(' after function name")
+	if !p.expect(token.LPAREN, "after function name") {
 		return nil
 	}
 
@@ -2181,12 +2398,10 @@ func (p *Parser) parseForVarNoKeyword() *VarStatementNoKeyword {
 
 		stmt.Lifetime = p.parseExpressionUntil(token.GT)
 
-		if p.peekTok.Type != token.GT {
-			p.addError("expected '>' after lifetime expression")
+		if !p.expect(token.GT, "after lifetime expression") {
 			return nil
 		}
 
-		p.nextToken() // move to '>'
 	}
 
 	p.nextToken() // :=
@@ -2197,6 +2412,12 @@ func (p *Parser) parseForVarNoKeyword() *VarStatementNoKeyword {
 }
 
 func (p *Parser) parseForPost() Statement {
+	if p.curTok.Type == token.VAR || p.curTok.Type == token.CONST ||
+		(p.curTok.Type == token.IDENT && p.peekTok.Type == token.WALRUS) {
+		p.addError("cannot declare variables in the post statement of a for loop")
+		return nil
+	}
+
 	return p.parseAssignOrExprStatement()
 }
 
@@ -2215,96 +2436,112 @@ func (p *Parser) parseContinueStatement() *ContinueStatement {
 }
 
 func (p *Parser) parseFor() Statement {
+	forTok := p.curTok
 	p.nextToken() // move past 'for'
 
 	if p.curTok.Type == token.VAR {
-		p.addError("unexpected 'egg', use := instead")
+		p.addError("unexpected 'say', use := instead")
 		return nil
 	}
 
 	// for range m {}
 	if p.curTok.Type == token.RANGE {
-		return p.parseForRangeStatement([]*Identifier{})
+		return p.parseForRangeStatement(forTok, []*Identifier{})
 	}
 
-	idents := []*Identifier{}
+	// the names before := range are only taken once it is certain this is a range loop,
+	// otherwise they are the start of the init, like a, b = 0, 1
+	if p.curTok.Type != token.IDENT || !p.rangeAhead() {
+		return p.parseForStatement(forTok)
+	}
+
+	idents := []*Identifier{{
+		NodeBase: NodeBase{Token: p.curTok},
+		Value:    p.curTok.Literal,
+	}}
+
+	for p.peekTok.Type == token.COMMA {
+		p.nextToken() // ,
+		p.nextToken() // ident
+
+		if p.curTok.Type != token.IDENT {
+			p.addError("expected identifier in for range")
+			return nil
+		}
 
-	if p.curTok.Type == token.IDENT {
 		idents = append(idents, &Identifier{
 			NodeBase: NodeBase{Token: p.curTok},
 			Value:    p.curTok.Literal,
 		})
-
-		for p.peekTok.Type == token.COMMA {
-			p.nextToken() // ,
-			p.nextToken() // ident
-
-			if p.curTok.Type != token.IDENT {
-				p.addError("expected identifier in for range")
-				return nil
-			}
-
-			idents = append(idents, &Identifier{
-				NodeBase: NodeBase{Token: p.curTok},
-				Value:    p.curTok.Literal,
-			})
-		}
 	}
 
-	if p.peekTok.Type == token.WALRUS && p.peekN(1).Type == token.RANGE {
-		p.nextToken() // :=
-		p.nextToken() // range
+	p.nextToken() // :=
+	p.nextToken() // range
 
-		if len(idents) > 2 {
-			p.addError("for range allows at most 2 variables")
-			return nil
-		}
+	return p.parseForRangeStatement(forTok, idents)
+}
 
-		return p.parseForRangeStatement(idents)
+// rangeAhead reports whether the names at curTok are followed by := range
+func (p *Parser) rangeAhead() bool {
+	n := 0
+	for p.peekN(n).Type == token.COMMA || p.peekN(n).Type == token.IDENT {
+		n++
 	}
 
-	return p.parseForStatement()
+	return p.peekN(n).Type == token.WALRUS && p.peekN(n+1).Type == token.RANGE
 }
 
-func (p *Parser) parseForStatement() *ForStatement {
+func (p *Parser) parseForStatement(forTok token.Token) *ForStatement {
 	stmt := &ForStatement{
-		NodeBase: NodeBase{Token: p.curTok},
+		NodeBase: NodeBase{Token: forTok},
 	}
 
-	stmt.Init = p.parseForInit()
+	// init and post can be left out, but both semicolons are still needed
+	if p.curTok.Type != token.SEMICOLON {
+		stmt.Init = p.parseForInit()
+		p.markEnd(stmt.Init)
+
+		if !p.expect(token.SEMICOLON, "after for init") {
+			return nil
+		}
 
-	if p.peekTok.Type != token.SEMICOLON {
-		p.addError("expected ';'")
-		return nil
 	}
 
-	p.nextToken() // ;
 	p.nextToken() // condition
+	if p.curTok.Type == token.SEMICOLON {
+		p.addError("expected condition in for loop")
+		return nil
+	}
+
 	stmt.Condition = p.parseExpression(LOWEST)
 
-	if p.peekTok.Type != token.SEMICOLON {
-		p.addError("expected ';'")
+	if !p.expect(token.SEMICOLON, "after for condition") {
 		return nil
 	}
 
-	p.nextToken() // ;
-	p.nextToken() // post
-	stmt.Post = p.parseForPost()
-
 	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{'")
+		p.nextToken() // post
+
+		post := p.parseForPost()
+		if post == nil {
+			return nil
+		}
+		p.markEnd(post)
+		stmt.Post = post
+	}
+
+	if !p.expect(token.LBRACE, "after for clauses") {
 		return nil
 	}
 
-	p.nextToken() // {
-	stmt.Body = p.parseBlockStatement()
+	stmt.Body, stmt.BodyBraces = p.parseBracedBlock()
 
 	return stmt
 }
 
-func (p *Parser) parseForRangeStatement(idents []*Identifier) *ForRangeStatement {
+func (p *Parser) parseForRangeStatement(forTok token.Token, idents []*Identifier) *ForRangeStatement {
 	stmt := &ForRangeStatement{
-		NodeBase: NodeBase{Token: p.curTok}, // range
+		NodeBase: NodeBase{Token: forTok},
 	}
 
 	if len(idents) > 2 {
@@ -2322,13 +2559,11 @@ func (p *Parser) parseForRangeStatement(idents []*Identifier) *ForRangeStatement
 	p.nextToken() // move to expr
 	stmt.Expr = p.parseExpression(LOWEST)
 
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after range expression")
+	if !p.expect(token.LBRACE, "after range expression") {
 		return nil
 	}
 
-	p.nextToken() // {
-	stmt.Body = p.parseBlockStatement()
+	stmt.Body, stmt.BodyBraces = p.parseBracedBlock()
 
 	return stmt
 }
@@ -2341,18 +2576,16 @@ func (p *Parser) parseWhileStatement() *WhileStatement {
 	p.nextToken()
 	stmt.Condition = p.parseExpression(LOWEST)
 	if stmt.Condition == nil {
-		p.addError("expected condition after 'why'")
+		p.addError("expected condition after 'while'")
 		return nil
 	}
 
 	// expect '{'
-	if p.peekTok.Type != token.LBRACE {
-		p.addError("expected '{' after condition")
+	if !p.expect(token.LBRACE, "after condition") {
 		return nil
 	}
-	p.nextToken() // move to '{'
 
-	stmt.Body = p.parseBlockStatement()
+	stmt.Body, stmt.BodyBraces = p.parseBracedBlock()
 	return stmt
 }
 
@@ -2367,9 +2600,7 @@ func (p *Parser) parseWithStatement() *WithStatement {
 		return nil
 	}
 
-	p.nextToken()
-	if p.curTok.Type != token.LBRACE {
-		p.addError("expected '{' after expression")
+	if !p.expect(token.LBRACE, "after expression") {
 		return nil
 	}
 
@@ -2386,21 +2617,113 @@ func (p *Parser) parseWithStatement() *WithStatement {
 }
 
 func (p *Parser) parseBlockStatement() []Statement {
+	p.nextToken() // move past '{'
+
+	return p.parseBlockBody()
+}
+
+// parseBracedBlock is parseBlockStatement that also says where the braces are
+func (p *Parser) parseBracedBlock() ([]Statement, Braces) {
+	braces := Braces{Open: p.curTok}
+
+	body := p.parseBlockStatement()
+	braces.Close = p.curTok
+
+	for _, c := range p.l.Comments() {
+		if c.Start.Offset > braces.Open.Offset && c.End.Offset <= braces.Close.Offset {
+			braces.Commented = true
+			break
+		}
+	}
+
+	return body, braces
+}
+
+// parseBlockBody parses statements up to the '}' closing the current block and leaves it as curTok,
+// a statement that fails to parse is skipped without ever eating that '}'
+func (p *Parser) parseBlockBody() []Statement {
 	statements := []Statement{}
+	depth := p.braces
 
-	p.nextToken() // move past '{'
+	// an empty block is already sitting on its '}', which has been counted
+	if p.curTok.Type == token.RBRACE {
+		depth++
+	}
+
+	for !p.closesBlock(depth) {
+		if p.curTok.Type == token.EOF {
+			p.addError("expected '}' to close block")
+			return statements
+		}
+
+		errCount := len(p.errors)
 
-	for p.curTok.Type != token.RBRACE && p.curTok.Type != token.EOF {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if !isNil(stmt) {
+			p.markEnd(stmt)
 			statements = append(statements, stmt)
 		}
+
+		if len(p.errors) > errCount || p.recovering {
+			p.synchronize(depth)
+			if p.closesBlock(depth) || p.curTok.Type == token.EOF {
+				continue
+			}
+		}
+
 		p.nextToken()
 	}
 
 	return statements
 }
 
+// closesBlock reports whether curTok has left the block whose statements sit at depth
+func (p *Parser) closesBlock(depth int) bool {
+	return p.braces < depth
+}
+
+// synchronize skips the rest of a broken statement so one mistake gives one error.
+// it stops at the end of the line, before a keyword that starts a statement,
+// or at the '}' that closes the block at depth, which is never consumed
+func (p *Parser) synchronize(depth int) {
+	defer func() { p.recovering = false }()
+
+	for p.curTok.Type != token.EOF && !p.closesBlock(depth) {
+		if p.braces == depth {
+			if p.curTok.Type == token.NEWLINE || p.curTok.Type == token.SEMICOLON {
+				return
+			}
+
+			if statementKeywords[p.peekTok.Type] {
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
+var statementKeywords = map[token.TokenType]bool{
+	token.VAR:      true,
+	token.CONST:    true,
+	token.IMPORT:   true,
+	token.ENUM:     true,
+	token.TYPE:     true,
+	token.FUNC:     true,
+	token.SWITCH:   true,
+	token.SELECT:   true,
+	token.MATCH:    true,
+	token.START:    true,
+	token.IF:       true,
+	token.WITH:     true,
+	token.FOR:      true,
+	token.WHILE:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.RETURN:   true,
+	token.DEFER:    true,
+}
+
 func (p *Parser) parseIndexExpression(left Expression) Expression {
 	tok := p.curTok // '['
 
@@ -2426,11 +2749,9 @@ func (p *Parser) parseIndexExpression(left Expression) Expression {
 				end = p.parseExpression(LOWEST)
 			}
 		} else {
-			if p.peekTok.Type != token.RBRACKET {
-				p.addError("expected ']'")
+			if !p.expect(token.RBRACKET, "after index expression") {
 				return nil
 			}
-			p.nextToken() // consume ']'
 
 			return &IndexExpression{
 				NodeBase: NodeBase{Token: tok},
@@ -2441,18 +2762,16 @@ func (p *Parser) parseIndexExpression(left Expression) Expression {
 	}
 
 	if p.curTok.Type != token.RBRACKET {
-		if p.peekTok.Type != token.RBRACKET {
-			p.addError("expected ']' after slice expression")
+		if !p.expect(token.RBRACKET, "after slice expression") {
 			return nil
 		}
-		p.nextToken() // move to ']'
 	}
 
 	return &SliceExpression{
 		NodeBase: NodeBase{Token: tok},
 		Left:     left,
 		Start:    start,
-		End:      end,
+		Stop:     end,
 	}
 }
 
@@ -2468,13 +2787,10 @@ func (p *Parser) parseDotExpression(left Expression) Expression {
 			return nil
 		}
 
-		if p.peekTok.Type != token.RPAREN {
-			p.addError("expected ')' after type assertion")
+		if !p.expect(token.RPAREN, "after type assertion") {
 			return nil
 		}
 
-		p.nextToken() // consume ')'
-
 		return &TypeAssertExpression{
 			NodeBase: NodeBase{Token: p.curTok},
 			Expr:     left,
@@ -2482,10 +2798,7 @@ func (p *Parser) parseDotExpression(left Expression) Expression {
 		}
 	}
 
-	p.nextToken() // move to identifier
-
-	if p.curTok.Type != token.IDENT {
-		p.addError("expected property name identifier after '.'")
+	if !p.expect(token.IDENT, "after '.'") {
 		return nil
 	}
 
@@ -2558,7 +2871,7 @@ func (p *Parser) parseArgList(end token.TokenType) []Expression {
 			return nil
 		}
 
-		p.consumeTerminators()
+		p.consumePeekTerminators()
 
 		if p.peekTok.Type == token.COMMA {
 			p.nextToken() // consume comma
@@ -2578,7 +2891,7 @@ func (p *Parser) parseArgList(end token.TokenType) []Expression {
 			return list
 		}
 
-		p.addError(fmt.Sprintf("expected ',' or '%s'", end))
+		p.addErrorAt(p.peekTok, fmt.Sprintf("expected ',' or '%s'", end))
 		return nil
 	}
 
@@ -2607,7 +2920,31 @@ func (p *Parser) parseExpressionUntil(stop token.TokenType) Expression {
 }
 
 func (p *Parser) parseExpression(precedence int) Expression {
+	p.depth++
+	defer func() {
+		p.depth--
+		if p.depth == 0 {
+			p.tooDeep = false
+		}
+	}()
+
+	if p.depth > p.MaxDepth {
+		p.addError("expression too deeply nested")
+		p.tooDeep = true
+
+		// the rest of the input can't be trusted, skip it
+		for p.peekTok.Type != token.EOF {
+			p.nextToken()
+		}
+		return nil
+	}
+
 	left := p.parsePrimary()
+	if left == nil {
+		return nil
+	}
+	p.markEnd(left)
+
 	for precedence < p.peekPrecedence() {
 		if p.stopTokens[p.peekTok.Type] {
 			break
@@ -2630,6 +2967,10 @@ func (p *Parser) parseExpression(precedence int) Expression {
 			p.nextToken()
 			left = p.parseSendExpression(left)
 
+		case token.QUESTION:
+			p.nextToken()
+			left = p.parseConditionalExpression(left)
+
 		case token.ELLIPSIS, token.INC, token.DEC:
 			p.nextToken()
 			left = &PostfixExpression{
@@ -2642,6 +2983,8 @@ func (p *Parser) parseExpression(precedence int) Expression {
 			p.nextToken()
 			left = p.parseInfixExpression(left)
 		}
+
+		p.markEnd(left)
 	}
 
 	return left
@@ -2657,14 +3000,101 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 	prec := p.curPrecedence()
 	p.nextToken()
 
-	expr.Right = p.parseExpression(prec)
+	// ** is right associative
+	if expr.Operator == "**" {
+		prec--
+	}
+
+	expr.Right = p.parseOperand(prec, expr.Operator)
+
+	return expr
+}
+
+// parseTupleLiteral parses the rest of (a, b, c) once the first element is read, a trailing comma is allowed
+func (p *Parser) parseTupleLiteral(open token.Token, first Expression) Expression {
+	tuple := &TupleLiteral{
+		NodeBase: NodeBase{Token: open},
+		Elements: []Expression{first},
+	}
+
+	for p.peekTok.Type == token.COMMA {
+		p.nextToken() // ,
+		if p.peekTok.Type == token.RPAREN {
+			break
+		}
+
+		p.nextToken()
+		el := p.parseOperand(LOWEST, ",")
+		if el == nil {
+			return nil
+		}
+
+		tuple.Elements = append(tuple.Elements, el)
+	}
+
+	if !p.expect(token.RPAREN, "after tuple") {
+		return nil
+	}
+
+	return tuple
+}
+
+// parseConditionalExpression parses cond ? a : b with curTok on the '?', the alternative takes
+// another conditional so a ? b : c ? d : e groups to the right
+func (p *Parser) parseConditionalExpression(cond Expression) Expression {
+	expr := &ConditionalExpression{
+		NodeBase:  NodeBase{Token: p.curTok},
+		Condition: cond,
+	}
+
+	p.nextToken()
+	expr.Consequence = p.parseOperand(LOWEST, "?")
+	if expr.Consequence == nil {
+		return nil
+	}
+
+	if !p.expect(token.COLON, "in ? expression") {
+		return nil
+	}
+
+	p.nextToken()
+	expr.Alternative = p.parseOperand(LOWEST, ":")
+	if expr.Alternative == nil {
+		return nil
+	}
+
+	return expr
+}
+
+// parseOperand parses what comes after an operator and reports it when nothing is there,
+// unless whatever stopped the expression already reported something better
+func (p *Parser) parseOperand(precedence int, operator string) Expression {
+	errCount := len(p.errors)
+
+	expr := p.parseExpression(precedence)
+	if expr == nil && len(p.errors) == errCount {
+		p.addError(fmt.Sprintf("expected expression after '%s'", operator))
+	}
+
 	return expr
 }
 
 func (p *Parser) parseStringLiteral() Expression {
 	raw := p.curTok.Literal
 
-	if !strings.Contains(raw, "${") {
+	interp := func(i int) bool {
+		return raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{' && !slices.Contains(p.curTok.EscapedDollars, i)
+	}
+
+	plain := true
+	for i := range raw {
+		if interp(i) {
+			plain = false
+			break
+		}
+	}
+
+	if plain {
 		return &StringLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: raw}
 	}
 
@@ -2672,7 +3102,7 @@ func (p *Parser) parseStringLiteral() Expression {
 	i := 0
 
 	for i < len(raw) {
-		if raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{' {
+		if interp(i) {
 			i += 2 // skip ${
 			start := i
 			depth := 1
@@ -2687,27 +3117,103 @@ func (p *Parser) parseStringLiteral() Expression {
 				i++
 			}
 
+			if depth > 0 {
+				p.addError("unclosed '${' in string")
+				break
+			}
+
 			exprSrc := raw[start : i-1]
 
-			expr := p.parseExpressionFromString(exprSrc)
+			expr := p.parseExpressionFromString(exprSrc, interpolationPos(p.curTok, raw[:start]))
+			if expr == nil {
+				continue
+			}
+
 			parts = append(parts, expr)
 		} else {
 			start := i
-			for i < len(raw) && !(raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{') {
+			for i < len(raw) && !interp(i) {
 				i++
 			}
 
-			parts = append(parts, &StringLiteral{Value: raw[start:i]})
+			from, to := interpolationPos(p.curTok, raw[:start]), interpolationPos(p.curTok, raw[:i])
+
+			tok := token.Token{Type: token.STRING, Literal: raw[start:i]}
+			tok.Line, tok.Column, tok.Offset = from.Line, from.Column, from.Offset
+			tok.EndLine, tok.EndColumn, tok.Length = to.Line, to.Column, to.Offset-from.Offset
+
+			parts = append(parts, &StringLiteral{NodeBase: NodeBase{Token: tok}, Value: raw[start:i]})
 		}
 	}
 
-	return &InterpolatedString{Parts: parts}
+	return &InterpolatedString{NodeBase: NodeBase{Token: p.curTok}, Parts: parts}
 }
 
-func (p *Parser) parseExpressionFromString(src string) Expression {
-	l := lexer.New(src)
+// parseExpressionFromString parses the inside of a ${} that starts at pos,
+// its errors are reported by this parser at their place in the string
+func (p *Parser) parseExpressionFromString(src string, pos token.Position) Expression {
+	if strings.TrimSpace(src) == "" {
+		p.addError("empty '${}' in string")
+		return nil
+	}
+
+	l := lexer.NewAt(src, pos)
 	subParser := New(l)
-	return subParser.parseExpression(LOWEST)
+	subParser.MaxDepth = p.MaxDepth
+
+	expr := subParser.parseExpression(LOWEST)
+
+	// comments are skipped by the lexer, so anything left over is a real token
+	if expr != nil && subParser.peekTok.Type != token.EOF {
+		subParser.nextToken()
+		subParser.addError(fmt.Sprintf("unexpected '%s' in '${}'", subParser.curTok.Literal))
+	}
+
+	errs := subParser.Errors()
+	if expr == nil && len(errs) == 0 {
+		p.addError("invalid expression in '${}'")
+	}
+
+	p.errors = append(p.errors, errs...)
+
+	return expr
+}
+
+// ParseExpression parses src as a single expression that starts at pos in a bigger source,
+// positions in the expression and its errors are in that source
+func ParseExpression(src string, pos token.Position) (Expression, []error) {
+	p := New(lexer.NewAt(src, pos))
+
+	p.consumeTerminators()
+	expr := p.parseExpression(LOWEST)
+
+	p.consumePeekTerminators()
+	if expr != nil && p.peekTok.Type != token.EOF {
+		p.nextToken()
+		p.addError(fmt.Sprintf("unexpected '%s' after the expression", p.curTok.Literal))
+	}
+
+	errs := p.Errors()
+	if expr == nil && len(errs) == 0 {
+		p.addError("expected an expression")
+		errs = p.Errors()
+	}
+
+	return expr, errs
+}
+
+// interpolationPos finds where the text after prefix starts in the string literal tok
+func interpolationPos(tok token.Token, prefix string) token.Position {
+	pos := token.Position{Line: tok.Line, Column: tok.Column + 1, Offset: tok.Offset + 1 + len(prefix)} // past the opening quote
+
+	if nl := strings.LastIndex(prefix, "\n"); nl >= 0 {
+		pos.Line += strings.Count(prefix, "\n")
+		prefix = prefix[nl+1:]
+		pos.Column = 1
+	}
+
+	pos.Column += len([]rune(prefix))
+	return pos
 }
 
 func (p *Parser) parsePrimary() Expression {
@@ -2717,7 +3223,7 @@ func (p *Parser) parsePrimary() Expression {
 		tok := p.curTok
 		p.nextToken()
 
-		right := p.parseExpression(PREFIX)
+		right := p.parseOperand(PREFIX, tok.Literal)
 		if right == nil {
 			return nil
 		}
@@ -2733,7 +3239,7 @@ func (p *Parser) parsePrimary() Expression {
 		tok := p.curTok
 		p.nextToken()
 
-		right := p.parseExpression(PREFIX)
+		right := p.parseOperand(PREFIX, tok.Literal)
 		if right == nil {
 			return nil
 		}
@@ -2749,7 +3255,7 @@ func (p *Parser) parsePrimary() Expression {
 		tok := p.curTok
 		p.nextToken()
 
-		right := p.parseExpression(PREFIX)
+		right := p.parseOperand(PREFIX, tok.Literal)
 		if right == nil {
 			return nil
 		}
@@ -2765,7 +3271,7 @@ func (p *Parser) parsePrimary() Expression {
 		tok := p.curTok
 		p.nextToken()
 
-		right := p.parseExpression(PREFIX)
+		right := p.parseOperand(PREFIX, tok.Literal)
 		if right == nil {
 			return nil
 		}
@@ -2776,6 +3282,25 @@ func (p *Parser) parsePrimary() Expression {
 			Right:    right,
 		}
 
+	case token.POW:
+		tok := p.curTok
+		p.nextToken()
+
+		right := p.parseOperand(PREFIX, tok.Literal)
+		if right == nil {
+			return nil
+		}
+
+		return &PrefixExpression{
+			NodeBase: NodeBase{Token: tok},
+			Operator: "*",
+			Right: &PrefixExpression{
+				NodeBase: NodeBase{Token: tok},
+				Operator: "*",
+				Right:    right,
+			},
+		}
+
 	case token.ARROW:
 		if p.peekTok.Type == token.CHAN {
 			return p.parseType()
@@ -2784,7 +3309,7 @@ func (p *Parser) parsePrimary() Expression {
 		tok := p.curTok
 		p.nextToken()
 
-		ch := p.parseExpression(PREFIX)
+		ch := p.parseOperand(PREFIX, tok.Literal)
 		if ch == nil {
 			return nil
 		}
@@ -2795,31 +3320,44 @@ func (p *Parser) parsePrimary() Expression {
 		}
 
 	case token.INT:
-		return &IntLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: atoi(p.curTok.Literal)}
+		val, err := lexer.ParseIntLiteral(p.curTok.Literal)
+		if err != nil {
+			p.addError("invalid integer literal")
+			return nil
+		}
+
+		return &IntLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: val}
 
-	case token.INT_TYPE:
+	case token.INT_TYPE, token.FLOAT_TYPE, token.STRING_TYPE, token.BOOL_TYPE:
+		// a type name on its own is only an expression when it converts something
 		if p.peekTok.Type == token.LPAREN {
 			return p.parseFuncCall()
 		}
+		p.addErrorAt(p.peekTok, fmt.Sprintf("expected '(' after '%s'", p.curTok.Literal))
 		return nil
 
-	case token.FLOAT:
-		return &FloatLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: atof(p.curTok.Literal)}
-
-	case token.FLOAT_TYPE:
+	case token.TYPE:
 		if p.peekTok.Type == token.LPAREN {
 			return p.parseFuncCall()
 		}
+		p.addError("unexpected 'type'")
 		return nil
 
+	case token.FLOAT:
+		val, err := lexer.ParseFloatLiteral(p.curTok.Literal)
+		if err != nil {
+			p.addError("invalid float literal")
+			return nil
+		}
+
+		return &FloatLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: val}
+
 	case token.STRING:
 		return p.parseStringLiteral()
 
-	case token.STRING_TYPE:
-		if p.peekTok.Type == token.LPAREN {
-			return p.parseFuncCall()
-		}
-		return nil
+	case token.CHAR:
+		// a char is just a one character string
+		return &StringLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: p.curTok.Literal}
 
 	case token.TRUE:
 		return &BoolLiteral{NodeBase: NodeBase{Token: p.curTok}, Value: true}
@@ -2833,12 +3371,6 @@ func (p *Parser) parsePrimary() Expression {
 	case token.FUNC:
 		return p.parseFuncLiteral()
 
-	case token.BOOL_TYPE:
-		if p.peekTok.Type == token.LPAREN {
-			return p.parseFuncCall()
-		}
-		return nil
-
 	case token.STRUCT:
 		typ := p.parseType()
 
@@ -2879,25 +3411,35 @@ func (p *Parser) parsePrimary() Expression {
 	case token.MAP:
 		typ := p.parseType()
 
+		// without braces the type itself is the value, like in make(map[string]int)
 		if p.peekTok.Type != token.LBRACE {
-			p.addError("expected '{'")
-			return nil
+			return typ
 		}
 
 		p.nextToken()
 		return p.parseCompositeLiteral(typ)
 
 	case token.LPAREN:
+		open := p.curTok
 		p.nextToken()
 		exp := p.parseExpression(LOWEST)
 
-		if p.peekTok.Type != token.RPAREN {
-			p.addError("expected ')'")
+		if p.peekTok.Type == token.COMMA {
+			return p.parseTupleLiteral(open, exp)
+		}
+
+		if !p.expect(token.RPAREN, "after grouped expression") {
 			return nil
 		}
 
-		p.nextToken()
-		return &GroupedExpression{NodeBase: NodeBase{Token: p.curTok}, Expression: exp}
+		grouped := &GroupedExpression{NodeBase: NodeBase{Token: open}, Expression: exp}
+		p.markEnd(grouped)
+
+		return grouped
+
+	case token.ILLEGAL:
+		// the lexer has already reported why
+		return nil
 
 	default:
 		return nil
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestDumpPositions checks what ayla run --debug-positions prints for every program in testdata
+// against its .golden file, so a node that starts pointing at a different token shows up in the diff
+func TestDumpPositions(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no programs in testdata")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := New(lexer.New(string(src)))
+			program := p.ParseProgram()
+			if errs := p.Errors(); len(errs) > 0 {
+				t.Fatalf("parse error: %v", errs[0])
+			}
+
+			got := DumpPositions(program.Statements)
+
+			golden := strings.TrimSuffix(file, ".ayla") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run go test ./parser -update to create it", err)
+			}
+
+			if got != string(want) {
+				t.Errorf("positions differ from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
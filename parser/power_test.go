@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+// grouping writes e back out with every infix expression in parentheses, so the test can see how
+// the parser grouped it
+func grouping(e Expression) string {
+	switch e := e.(type) {
+	case *InfixExpression:
+		return "(" + grouping(e.Left) + " " + e.Operator + " " + grouping(e.Right) + ")"
+	case *PrefixExpression:
+		return e.Operator + grouping(e.Right)
+	case *GroupedExpression:
+		return grouping(e.Expression)
+	case *IntLiteral:
+		return e.Token.Literal
+	case *Identifier:
+		return e.Value
+	}
+
+	return "?"
+}
+
+func TestPowerPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"2 * 3 ** 2", "(2 * (3 ** 2))"},
+		{"3 ** 2 * 2", "((3 ** 2) * 2)"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"(2 ** 3) ** 2", "((2 ** 3) ** 2)"},
+		{"1 + 2 ** 3 / 4", "(1 + ((2 ** 3) / 4))"},
+		{"a**b*c", "((a ** b) * c)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.src))
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("%q: %v", tt.src, errs[0])
+		}
+
+		stmt, ok := program.Statements[0].(*ExpressionStatement)
+		if !ok {
+			t.Fatalf("%q: expected an expression, got %T", tt.src, program.Statements[0])
+		}
+
+		if got := grouping(stmt.Expression); got != tt.want {
+			t.Errorf("%q: grouped as %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/z-sk1/ayla-lang/lexer"
+)
+
+// parseErrors parses src and gives back the text of every syntax error
+func parseErrors(src string) []string {
+	p := New(lexer.New(src))
+	p.ParseProgram()
+
+	var msgs []string
+	for _, err := range p.Errors() {
+		msgs = append(msgs, err.Error())
+	}
+
+	return msgs
+}
+
+func TestOneErrorPerTypo(t *testing.T) {
+	src := `say x = = 1
+
+fun f() {
+    putln(1 +)
+}
+
+say y int = ) fun g() {}
+
+putln(x y)
+`
+
+	want := []string{
+		"syntax error at 1:9: expected expression after '=' (got =)",
+		"syntax error at 4:14: expected expression after '+' (got ))",
+		"syntax error at 7:13: expected expression after '=' (got ))",
+		"syntax error at 9:9: expected ',' or ')' (got y)",
+	}
+
+	got := parseErrors(src)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %q", len(want), len(got), got)
+	}
+
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("error %d: got %q, want %q", idx+1, got[idx], want[idx])
+		}
+	}
+}
+
+func TestErrorsPointAtUnexpectedToken(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"putln(a b)", "syntax error at 1:9: expected ',' or ')' (got b)"},
+		{"say a = []int{1 2}", "syntax error at 1:17: expected ',' or '}' in composite literal (got 2)"},
+	}
+
+	for _, tt := range tests {
+		got := parseErrors(tt.src)
+		if len(got) == 0 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRecoveryKeepsFunctionOnSameLine(t *testing.T) {
+	p := New(lexer.New("say y int = ) fun g() {\n    putln(1)\n}\n"))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got %v", p.Errors())
+	}
+
+	for _, stmt := range program.Statements {
+		if fn, ok := stmt.(*FuncStatement); ok && fn.Name.Value == "g" {
+			return
+		}
+	}
+
+	t.Errorf("expected fun g to be parsed after the error, got %d statements", len(program.Statements))
+}
+
+func TestRecoveryKeepsBlockClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"function", "fun f() {\n    say x = = 1\n    putln(x)\n}\n\nfun g() {}\n"},
+		{"if", "fun f() {\n    ayla yes {\n        putln(1 +)\n    }\n    putln(2)\n}\n\nfun g() {}\n"},
+		{"case", "choose 1 {\n    when 1 {\n        putln(2 *)\n    }\n    otherwise {\n        putln(3)\n    }\n}\n\nfun g() {}\n"},
+		{"loop", "while yes {\n    putln(a b)\n}\n\nfun g() {}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.src))
+			program := p.ParseProgram()
+
+			if len(p.Errors()) != 1 {
+				t.Fatalf("expected 1 error, got %v", p.Errors())
+			}
+
+			// the broken statement's block ends at its own '}', so g is at top level and comes last
+			if len(program.Statements) != 2 {
+				t.Fatalf("expected 2 top-level statements, got %d", len(program.Statements))
+			}
+
+			fn, ok := program.Statements[1].(*FuncStatement)
+			if !ok || fn.Name.Value != "g" {
+				t.Errorf("expected fun g at top level, got %T", program.Statements[1])
+			}
+		})
+	}
+}
+
+// TestEveryPrefixFinishes parses each program in the corpus cut off at every character, the way an
+// editor sees it while it's being typed, so a half written construct like `fun (p Point` at the end
+// of the file can't leave the parser looping again
+func TestEveryPrefixFinishes(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ayla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for end := 0; end <= len(src); end++ {
+			if end < len(src) && !utf8.RuneStart(src[end]) {
+				continue
+			}
+
+			prefix := string(src[:end])
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				New(lexer.New(prefix)).ParseProgram()
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: parsing the first %d bytes never finished:\n%s", file, end, prefix)
+			}
+		}
+	}
+}
+
+func TestUnclosedReceiverFinishes(t *testing.T) {
+	for _, src := range []string{"fun (p Point", "fun (p", "fun (", "fun (p Point) area("} {
+		done := make(chan []string)
+		go func() {
+			done <- parseErrors(src)
+		}()
+
+		select {
+		case errs := <-done:
+			if len(errs) == 0 {
+				t.Errorf("%q: expected a syntax error", src)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q: parsing never finished", src)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+import "strings"
+
+// the String methods print a node back as ayla source with every infix expression in parentheses,
+// so printing a tree shows exactly how it was parsed
+
+func nodeString(n interface{ Format(*Formatter) string }) string {
+	return n.Format(&Formatter{Parens: true})
+}
+
+// String prints a whole program the same way, one top level statement per line
+func (p *Program) String() string {
+	lines := make([]string, len(p.Statements))
+	for i, stmt := range p.Statements {
+		lines[i] = nodeString(stmt)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (v *VarStatement) String() string               { return nodeString(v) }
+func (v *VarStatementBlock) String() string          { return nodeString(v) }
+func (v *VarStatementNoKeyword) String() string      { return nodeString(v) }
+func (m *MultiVarStatement) String() string          { return nodeString(m) }
+func (m *MultiVarStatementNoKeyword) String() string { return nodeString(m) }
+func (v *ConstStatement) String() string             { return nodeString(v) }
+func (c *ConstStatementBlock) String() string        { return nodeString(c) }
+func (m *MultiConstStatement) String() string        { return nodeString(m) }
+func (a *AssignmentStatement) String() string        { return nodeString(a) }
+func (v *Variant) String() string                    { return nodeString(v) }
+func (e *EnumStatement) String() string              { return nodeString(e) }
+func (t *TypeStatement) String() string              { return nodeString(t) }
+func (s *StructType) String() string                 { return nodeString(s) }
+func (t *IdentType) String() string                  { return nodeString(t) }
+func (r *RangeType) String() string                  { return nodeString(r) }
+func (q *QualifiedType) String() string              { return nodeString(q) }
+func (a *ArrayType) String() string                  { return nodeString(a) }
+func (m *MapType) String() string                    { return nodeString(m) }
+func (i *InterfaceType) String() string              { return nodeString(i) }
+func (ft *FuncType) String() string                  { return nodeString(ft) }
+func (p *PointerType) String() string                { return nodeString(p) }
+func (c *ChanType) String() string                   { return nodeString(c) }
+func (s *StartStatement) String() string             { return nodeString(s) }
+func (i *IfStatement) String() string                { return nodeString(i) }
+func (fn *FuncStatement) String() string             { return nodeString(fn) }
+func (c *FuncCall) String() string                   { return nodeString(c) }
+func (fl *FuncLiteral) String() string               { return nodeString(fl) }
+func (m *MethodStatement) String() string            { return nodeString(m) }
+func (fs *ForStatement) String() string              { return nodeString(fs) }
+func (fr *ForRangeStatement) String() string         { return nodeString(fr) }
+func (w *WhileStatement) String() string             { return nodeString(w) }
+func (s *SwitchStatement) String() string            { return nodeString(s) }
+func (c *CaseClause) String() string                 { return nodeString(c) }
+func (d *DefaultClause) String() string              { return nodeString(d) }
+func (m *MatchStatement) String() string             { return nodeString(m) }
+func (m *MatchArm) String() string                   { return nodeString(m) }
+func (s *SelectStatement) String() string            { return nodeString(s) }
+func (s *SelectCaseClause) String() string           { return nodeString(s) }
+func (w *WithStatement) String() string              { return nodeString(w) }
+func (b *BreakStatement) String() string             { return nodeString(b) }
+func (c *ContinueStatement) String() string          { return nodeString(c) }
+func (r *ReturnStatement) String() string            { return nodeString(r) }
+func (i *ImportStatement) String() string            { return nodeString(i) }
+func (d *DeferStatement) String() string             { return nodeString(d) }
+func (c *CompositeLiteral) String() string           { return nodeString(c) }
+func (s *SliceExpression) String() string            { return nodeString(s) }
+func (i *IndexExpression) String() string            { return nodeString(i) }
+func (s *SendExpression) String() string             { return nodeString(s) }
+func (r *ReceiveExpression) String() string          { return nodeString(r) }
+func (t *TypeAssertExpression) String() string       { return nodeString(t) }
+func (i IntLiteral) String() string                  { return nodeString(i) }
+func (fl FloatLiteral) String() string               { return nodeString(fl) }
+func (s StringLiteral) String() string               { return nodeString(s) }
+func (i *InterpolatedString) String() string         { return nodeString(i) }
+func (b BoolLiteral) String() string                 { return nodeString(b) }
+func (n NilLiteral) String() string                  { return nodeString(n) }
+func (m *MemberExpression) String() string           { return nodeString(m) }
+func (i *Identifier) String() string                 { return nodeString(i) }
+func (e *ExpressionStatement) String() string        { return nodeString(e) }
+func (i *InfixExpression) String() string            { return nodeString(i) }
+func (c *ConditionalExpression) String() string      { return nodeString(c) }
+func (p *PrefixExpression) String() string           { return nodeString(p) }
+func (g *GroupedExpression) String() string          { return nodeString(g) }
+func (t *TupleLiteral) String() string               { return nodeString(t) }
+func (p *PostfixExpression) String() string          { return nodeString(p) }
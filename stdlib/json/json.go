@@ -306,9 +306,9 @@ func aylaToJSON(i *interpreter.Interpreter, v interpreter.Value, name string) (a
 			if err != nil {
 				return nil, err
 			}
-			// strip the "s:" prefix from map keys
+			// strip the "s:" prefix from map keys, a key written as a literal is still untyped
 			key := val.Keys[k]
-			keyStr, ok := key.(interpreter.StringValue)
+			keyStr, ok := interpreter.UnwrapFully(key).(interpreter.StringValue)
 			if !ok {
 				return nil, fmt.Errorf("%s: map keys must be strings", name)
 			}
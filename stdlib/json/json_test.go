@@ -0,0 +1,50 @@
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z-sk1/ayla-lang/interpreter"
+	"github.com/z-sk1/ayla-lang/lexer"
+	"github.com/z-sk1/ayla-lang/parser"
+)
+
+func TestStringifyIgnoresBoolStyle(t *testing.T) {
+	src := `import json
+
+say v, err = json.Stringify(map[string]thing{"ok": yes, "list": []bool{no}})
+putln(v, err == nil)
+`
+
+	for _, style := range []interpreter.BoolStyle{interpreter.BoolYesNo, interpreter.BoolTrueFalse} {
+		p := parser.New(lexer.New(src))
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatal(errs[0])
+		}
+
+		var out strings.Builder
+
+		i := interpreter.New("test.ayla")
+		i.Stdout = &out
+		i.BoolStyle = style
+
+		if err := i.RegisterForward(program.Statements); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := i.EvalStatements(program.Statements); err != nil {
+			t.Fatal(err)
+		}
+
+		// only err == nil follows the style, the json always has true and false
+		want := `{"list":[false],"ok":true} yes` + "\n"
+		if style == interpreter.BoolTrueFalse {
+			want = `{"list":[false],"ok":true} true` + "\n"
+		}
+
+		if out.String() != want {
+			t.Errorf("style %d: got %q, want %q", style, out.String(), want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
@@ -8,6 +10,37 @@ type Token struct {
 	Line                int
 	Column              int
 	HadWhitespaceBefore bool
+
+	// Offset is the byte offset of the first character, Length is how many bytes of source it covers
+	Offset int
+	Length int
+
+	// EndLine and EndColumn are just past the last character
+	EndLine   int
+	EndColumn int
+
+	// EscapedDollars holds where in a string's Literal each '$' written as \$ is, those never start a ${}
+	EscapedDollars []int
+}
+
+// Position is a place in the source, lines and columns start at 1 and Offset counts bytes
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// Range runs from Start up to, but not including, End
+type Range struct {
+	Start Position
+	End   Position
+}
+
+func (t Token) Range() Range {
+	return Range{
+		Start: Position{Line: t.Line, Column: t.Column, Offset: t.Offset},
+		End:   Position{Line: t.EndLine, Column: t.EndColumn, Offset: t.Offset + t.Length},
+	}
 }
 
 const (
@@ -20,6 +53,7 @@ const (
 	INT    = "INT"
 	STRING = "STRING"
 	FLOAT  = "FLOAT"
+	CHAR   = "CHAR"
 	// operators
 	ASSIGN = "="
 	ARROW  = "<-"
@@ -30,12 +64,14 @@ const (
 	SLASH = "/"
 	MUL   = "*"
 	MOD   = "%"
+	POW   = "**"
 
 	PLUS_ASSIGN  = "+="
 	SUB_ASSIGN   = "-="
 	SLASH_ASSIGN = "/="
 	MUL_ASSIGN   = "*="
 	MOD_ASSIGN   = "%="
+	POW_ASSIGN   = "**="
 
 	INC = "++"
 	DEC = "--"
@@ -66,6 +102,7 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	QUESTION  = "?"
 	DOT       = "."
 	ELLIPSIS  = "..."
 	DUODOT    = ".."
@@ -89,6 +126,7 @@ const (
 	ELSE      = "ELSE"
 	SWITCH    = "SWITCH"
 	SELECT    = "SELECT"
+	MATCH     = "MATCH"
 	CASE      = "CASE"
 	DEFAULT   = "DEFAULT"
 	WITH      = "WITH"
@@ -125,6 +163,7 @@ var keywords = map[string]TokenType{
 	"elen":      ELSE,
 	"choose":    SWITCH,
 	"select":    SELECT,
+	"match":     MATCH,
 	"when":      CASE,
 	"otherwise": DEFAULT,
 	"with":      WITH,
@@ -148,6 +187,89 @@ var keywords = map[string]TokenType{
 	"nil":       NIL,
 }
 
+var controlKeywords = map[TokenType]bool{
+	IF:       true,
+	ELSE:     true,
+	SWITCH:   true,
+	SELECT:   true,
+	MATCH:    true,
+	CASE:     true,
+	DEFAULT:  true,
+	WITH:     true,
+	FOR:      true,
+	RANGE:    true,
+	WHILE:    true,
+	BREAK:    true,
+	CONTINUE: true,
+	RETURN:   true,
+	DEFER:    true,
+	START:    true,
+}
+
+var declarationKeywords = map[TokenType]bool{
+	VAR:       true,
+	CONST:     true,
+	IMPORT:    true,
+	TYPE:      true,
+	STRUCT:    true,
+	ENUM:      true,
+	INTERFACE: true,
+	FUNC:      true,
+	MAP:       true,
+	CHAN:      true,
+}
+
+var typeKeywords = map[TokenType]bool{
+	INT_TYPE:    true,
+	FLOAT_TYPE:  true,
+	STRING_TYPE: true,
+	BOOL_TYPE:   true,
+}
+
+var literalKeywords = map[TokenType]bool{
+	TRUE:  true,
+	FALSE: true,
+	NIL:   true,
+}
+
+// Keywords returns a copy of the keyword table, mapping each keyword to its token type
+func Keywords() map[string]TokenType {
+	out := make(map[string]TokenType, len(keywords))
+	for k, v := range keywords {
+		out[k] = v
+	}
+
+	return out
+}
+
+func ControlKeywords() []string {
+	return keywordsIn(controlKeywords)
+}
+
+func DeclarationKeywords() []string {
+	return keywordsIn(declarationKeywords)
+}
+
+func TypeKeywords() []string {
+	return keywordsIn(typeKeywords)
+}
+
+func LiteralKeywords() []string {
+	return keywordsIn(literalKeywords)
+}
+
+func keywordsIn(set map[TokenType]bool) []string {
+	var out []string
+	for k, v := range keywords {
+		if set[v] {
+			out = append(out, k)
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
+
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
@@ -0,0 +1,47 @@
+package token
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestKeywordGroupsCoverTable checks every keyword the lexer knows is in exactly one of the
+// groups ayla doc --keywords prints, so a keyword added to the table can't be left out of it
+func TestKeywordGroupsCoverTable(t *testing.T) {
+	groups := [][]string{ControlKeywords(), DeclarationKeywords(), TypeKeywords(), LiteralKeywords()}
+
+	seen := map[string]int{}
+	for _, group := range groups {
+		if !slices.IsSorted(group) {
+			t.Errorf("group is not sorted: %v", group)
+		}
+
+		for _, kw := range group {
+			seen[kw]++
+		}
+	}
+
+	for kw, typ := range Keywords() {
+		if seen[kw] != 1 {
+			t.Errorf("%s is in %d groups, want 1", kw, seen[kw])
+		}
+
+		if got := LookupIdent(kw); got != typ {
+			t.Errorf("LookupIdent(%q) = %s, the table says %s", kw, got, typ)
+		}
+	}
+
+	if len(seen) != len(Keywords()) {
+		t.Errorf("groups name %d keywords, the table has %d", len(seen), len(Keywords()))
+	}
+}
+
+func TestKeywordsIsACopy(t *testing.T) {
+	kws := Keywords()
+	kws["say"] = IDENT
+	delete(kws, "give")
+
+	if LookupIdent("say") != VAR || LookupIdent("give") != RETURN {
+		t.Error("changing the map Keywords gave back changed the lexer's table")
+	}
+}